@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/itnxs/httpgo/internal/pkg"
+	"github.com/spf13/cobra"
+)
+
+var printCurl bool
+
+func init() {
+	fromCurlCmd.Flags().BoolVar(&printCurl, "print", false, "只输出等价的 httpgo 命令，不执行压测")
+	rootCmd.AddCommand(fromCurlCmd)
+}
+
+var fromCurlCmd = &cobra.Command{
+	Use:     "from-curl ['curl ...' | -- curl ...]",
+	Example: fromCurlExample,
+	Short:   "将 curl 命令转换为压测请求",
+	Long: `将 curl 命令（例如从浏览器开发者工具复制的 "Copy as cURL"）转换为压测请求并执行压测，
+使用 --print 时只输出等价的 httpgo 命令。curl 命令可以作为一个带引号的参数、在 -- 之后
+按参数传入，或者省略时从标准输入读取。其余参数（例如 -c、-n、-d）与根命令一致。`,
+	Run: fromCurlRun,
+}
+
+func fromCurlRun(cmd *cobra.Command, args []string) {
+	if err := parseCurl(cmd.InOrStdin(), args); err != nil {
+		cmd.PrintErrln(err)
+		exitCode = exitFailure
+		return
+	}
+
+	if printCurl {
+		cmd.Println(config.Command())
+		return
+	}
+
+	runBenchmark(cmd)
+}
+
+// parseCurl 将 curl 命令解析到 config，只有一个参数时按 shell 规则拆分，
+// 没有参数时从 stdin 读取
+func parseCurl(stdin io.Reader, args []string) error {
+	if len(args) <= 1 {
+		line := ""
+		if len(args) == 1 {
+			line = args[0]
+		} else {
+			b, err := io.ReadAll(stdin)
+			if err != nil {
+				return err
+			}
+			line = string(b)
+		}
+
+		var err error
+		if args, err = pkg.SplitCommand(line); err != nil {
+			return err
+		}
+	}
+
+	if len(args) == 0 {
+		return errors.New("缺少 curl 命令")
+	}
+
+	return config.ParseCurl(args)
+}
+
+const fromCurlExample = `	httpgo from-curl 'curl https://example.com/api -H "Authorization: Bearer xxx" --data-raw "{\"a\":1}"' -c 50 -d 30s
+	httpgo from-curl -c 50 -d 30s -- curl -X PUT https://example.com/api -d a=1
+	pbpaste | httpgo from-curl --print`
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseCurl(t *testing.T) {
+	saved := config
+	defer func() { config = saved }()
+
+	assert.Nil(t, parseCurl(nil, []string{"curl -X PUT example.com -d a=1"}))
+	assert.Equal(t, "http://example.com", config.Url)
+	assert.Equal(t, "PUT", config.Method)
+
+	config = saved
+	assert.Nil(t, parseCurl(strings.NewReader("curl example.com \\\n  -k"), nil))
+	assert.True(t, config.Insecure)
+
+	config = saved
+	assert.Nil(t, parseCurl(nil, []string{"curl", "example.com", "-H", "a: b"}))
+	assert.Equal(t, []string{"a: b"}, config.Headers)
+
+	assert.NotNil(t, parseCurl(strings.NewReader(" "), nil))
+	assert.NotNil(t, parseCurl(nil, []string{"curl 'example.com"}))
+}
+
+func Test_fromCurlRun(t *testing.T) {
+	saved := config
+	defer func() { config, printCurl = saved, false }()
+
+	var buf bytes.Buffer
+	fromCurlCmd.SetOut(&buf)
+	fromCurlCmd.SetErr(&buf)
+
+	printCurl = true
+	exitCode = 0
+	fromCurlRun(fromCurlCmd, []string{"curl example.com --data-raw '{}' -H 'Content-Type: application/json'"})
+	assert.Equal(t, "httpgo -X POST http://example.com -H 'Content-Type: application/json' -b '{}'\n", buf.String())
+	assert.Equal(t, 0, exitCode)
+
+	buf.Reset()
+	fromCurlRun(fromCurlCmd, []string{"curl example.com --bogus"})
+	assert.Equal(t, "unsupported curl option \"--bogus\"\n", buf.String())
+	assert.Equal(t, exitFailure, exitCode)
+}
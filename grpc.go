@@ -0,0 +1,35 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	grpcCmd.Flags().SortFlags = false
+	grpcCmd.Flags().StringVar(&config.ProtoSet, "proto-set", "", "protoc --descriptor_set_out 生成的描述文件路径（需包含 --include_imports），为空时通过服务端反射获取")
+	rootCmd.AddCommand(grpcCmd)
+}
+
+var grpcCmd = &cobra.Command{
+	Use:     "grpc <address> <package.Service/Method>",
+	Example: grpcExample,
+	Short:   "压测 gRPC 服务，支持一元调用与流式调用，按 grpc-status 统计状态码",
+	Long: `调用 gRPC 服务的一个方法，方法的描述来自 --proto-set 指定的描述文件或服务端反射（grpc.reflection.v1）。
+请求消息以 JSON 格式通过 --body 或 --file 指定，启动时编码一次，客户端流式方法可以使用 JSON 数组发送多条消息；
+流式调用发送全部消息后关闭发送方向，读取响应直到服务端结束调用，每次调用计为一次请求。
+地址为 https 时使用 TLS，-H 作为元数据发送，--host 覆盖 :authority；
+--connections、--streams-per-conn、--qps、--rate、--requests、--duration 与 --timeout 的含义与 HTTP 压测相同。`,
+	Args: cobra.ExactArgs(2),
+	Run:  grpcRun,
+}
+
+func grpcRun(cmd *cobra.Command, args []string) {
+	config.GRPC = true
+	config.Url = args[0]
+	config.GrpcMethod = args[1]
+	runBenchmark(cmd)
+}
+
+const grpcExample = `	httpgo grpc localhost:50051 helloworld.Greeter/SayHello -b '{"name":"httpgo"}' -c 50 -d 30s
+	httpgo grpc https://api.example.com helloworld.Greeter/SayHello --proto-set greeter.pb -H "authorization: Bearer token"
+	httpgo grpc localhost:50051 chat.Chat/Send -b '[{"text":"a"},{"text":"b"}]' --qps 100`
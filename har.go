@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	harCmd.Flags().SortFlags = false
+	harCmd.Flags().StringVar(&config.HarMode, "mode", "paced", "回放方式，paced 按记录的相对时间回放一遍，loop 以 -c 个并发循环回放")
+	harCmd.Flags().Float64Var(&config.Speed, "speed", 1, "paced 回放的速度倍数，例如 2 表示按原始时间间隔的一半回放")
+	harCmd.Flags().BoolVar(&config.HarTemplate, "template", false, "渲染请求中的 {{变量}}，默认按记录的内容原样回放")
+	rootCmd.AddCommand(harCmd)
+}
+
+var harCmd = &cobra.Command{
+	Use:     "har <file.har>",
+	Example: harExample,
+	Short:   "回放 HAR 文件中的请求，结果按 URL 路径统计",
+	Long: `读取 HAR 1.2 文件中的所有请求作为压测负载回放，结果按 URL 路径分别统计。
+paced 模式按记录的相对时间回放一遍（可用 --speed 缩放），不受 --duration 限制；
+loop 模式以 --connections 个并发循环回放所有请求，直到达到 --requests 或 --duration。`,
+	Args: cobra.ExactArgs(1),
+	Run:  harRun,
+}
+
+func harRun(cmd *cobra.Command, args []string) {
+	config.Har = args[0]
+	runBenchmark(cmd)
+}
+
+const harExample = `	httpgo har session.har
+	httpgo har session.har --speed 2
+	httpgo har session.har --mode loop -c 64 -d 30s
+	httpgo har session.har --mode loop --template --data users.csv`
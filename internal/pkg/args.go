@@ -0,0 +1,380 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// httpie 风格请求项的分隔符
+const (
+	sepQuery    = "=="
+	sepHeader   = ":"
+	sepString   = "="
+	sepRawJSON  = ":="
+	sepFile     = "=@"
+	sepJSONFile = ":=@"
+)
+
+// itemSeparators 按长度排列，同一位置匹配多个分隔符时使用最长的一个
+var itemSeparators = []string{sepJSONFile, sepRawJSON, sepQuery, sepFile, sepString, sepHeader}
+
+// requestItem 为命令行中 URL 之后的一个请求项
+type requestItem struct {
+	key   string
+	sep   string
+	value string
+}
+
+// parseRequestItem 在第一个未被反斜杠转义的分隔符处拆分请求项
+func parseRequestItem(s string) (item requestItem, err error) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		for _, sep := range itemSeparators {
+			if strings.HasPrefix(s[i:], sep) {
+				item = requestItem{key: unescapeItemKey(strings.TrimSpace(s[:i])), sep: sep, value: s[i+len(sep):]}
+				return
+			}
+		}
+	}
+	return item, fmt.Errorf("invalid request item %q", s)
+}
+
+// unescapeItemKey 去掉分隔符字符前的反斜杠，其余转义留给嵌套路径解析
+func unescapeItemKey(k string) string {
+	if !strings.Contains(k, `\`) {
+		return k
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(k); i++ {
+		if k[i] == '\\' && i+1 < len(k) && strings.IndexByte("=:@", k[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(k[i])
+	}
+	return b.String()
+}
+
+// parseArgs 解析 URL 之后的请求项：k==v 为查询参数，Header:value 为请求头，
+// k=v 与 k:=raw 为 JSON 字段（--form 时 k=v 为表单字段），=@ 与 :=@ 从文件读取值，
+// --form 时 k=@path 为上传的文件，请求体使用 multipart/form-data，
+// JSON 字段的键支持 a[b][0] 形式的嵌套路径
+func (c *Config) parseArgs() (err error) {
+	if len(c.Args) == 0 {
+		return
+	}
+
+	query := fasthttp.AcquireArgs()
+	defer fasthttp.ReleaseArgs(query)
+
+	var (
+		root    any
+		form    []formPart
+		hasData bool
+	)
+	for _, arg := range c.Args {
+		if arg == "" {
+			continue
+		}
+
+		var item requestItem
+		if item, err = parseRequestItem(arg); err != nil {
+			return
+		}
+
+		switch item.sep {
+		case sepQuery:
+			query.Add(item.key, item.value)
+			continue
+		case sepHeader:
+			if item.key == "" {
+				return fmt.Errorf("missing header name in request item %q", arg)
+			}
+			c.Headers = append(c.Headers, item.key+": "+strings.TrimSpace(item.value))
+			continue
+		}
+
+		hasData = true
+		// 表单中的 k=@path;type=mime 为上传的文件，不读入字段值
+		if c.Form && item.sep == sepFile {
+			form = append(form, newFilePart(item.key, item.value))
+			continue
+		}
+
+		var v any
+		if v, err = item.data(); err != nil {
+			return fmt.Errorf("invalid request item %q: %w", arg, err)
+		}
+
+		if c.Form {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("raw JSON item %q cannot be used with --form", arg)
+			}
+			form = append(form, formPart{name: item.key, value: s})
+			continue
+		}
+
+		var path []jsonKey
+		if path, err = parseJSONPath(item.key); err != nil {
+			return fmt.Errorf("invalid request item %q: %w", arg, err)
+		}
+		if root, err = jsonSet(root, path, v); err != nil {
+			return fmt.Errorf("invalid request item %q: %w", arg, err)
+		}
+	}
+
+	if query.Len() > 0 {
+		c.Url = appendQuery(c.Url, query.QueryString())
+	}
+	if !hasData {
+		return
+	}
+	if c.Body != "" || c.File != "" {
+		return errors.New("request data items cannot be used with --body or --file")
+	}
+
+	if c.Form {
+		c.Method = fasthttp.MethodPost
+		if c.body, c.multipart, err = buildFormBody(c.body[:0], form); err != nil {
+			return
+		}
+		// multipart 请求体只构建一次，通过流式请求体发送，避免每个请求复制一份
+		if c.multipart != "" {
+			c.Stream = true
+		}
+	} else {
+		c.JSON = true
+		c.body = appendJSON(c.body[:0], root)
+	}
+
+	return
+}
+
+// data 返回字段的值，字符串字段为 string，原始 JSON 字段为 json.RawMessage
+func (item requestItem) data() (any, error) {
+	value := item.value
+	if item.sep == sepFile || item.sep == sepJSONFile {
+		b, err := os.ReadFile(filepath.Clean(value))
+		if err != nil {
+			return nil, err
+		}
+		value = string(b)
+	}
+
+	if item.sep == sepString || item.sep == sepFile {
+		return value, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(value)); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+// appendQuery 将查询参数追加到 URL 中已有的查询参数之后
+func appendQuery(u string, query []byte) string {
+	switch {
+	case !strings.Contains(u, "?"):
+		return u + "?" + string(query)
+	case strings.HasSuffix(u, "?") || strings.HasSuffix(u, "&"):
+		return u + string(query)
+	default:
+		return u + "&" + string(query)
+	}
+}
+
+// maxJSONIndex 为嵌套路径中数组下标的最大值，中间缺少的元素补为 null，
+// 限制下标以免过大的下标分配大量内存
+const maxJSONIndex = 1024
+
+// jsonKey 为嵌套路径中的一段，array 为 true 时表示数组下标，下标为 -1 时追加到数组末尾
+type jsonKey struct {
+	name  string
+	index int
+	array bool
+}
+
+// parseJSONPath 解析 a[b][0][] 形式的键，第一段为空时顶层为数组，
+// 方括号可以用反斜杠转义，转义的数字作为对象的键
+func parseJSONPath(s string) (path []jsonKey, err error) {
+	var (
+		b strings.Builder
+		i int
+	)
+	for ; i < len(s) && s[i] != '['; i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		} else if s[i] == ']' {
+			return nil, fmt.Errorf("unexpected ']' in key %q", s)
+		}
+		b.WriteByte(s[i])
+	}
+	if b.Len() > 0 {
+		path = append(path, jsonKey{name: b.String()})
+	}
+
+	for i < len(s) {
+		if s[i] != '[' {
+			return nil, fmt.Errorf("expected '[' at offset %d in key %q", i, s)
+		}
+
+		b.Reset()
+		j := i + 1
+		for ; j < len(s) && s[j] != ']'; j++ {
+			if s[j] == '\\' && j+1 < len(s) {
+				j++
+			}
+			b.WriteByte(s[j])
+		}
+		if j == len(s) {
+			return nil, fmt.Errorf("missing ']' in key %q", s)
+		}
+
+		switch seg := s[i+1 : j]; {
+		case seg == "":
+			path = append(path, jsonKey{index: -1, array: true})
+		case isDigits(seg):
+			n, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in key %q", seg, s)
+			}
+			if n > maxJSONIndex {
+				return nil, fmt.Errorf("index %d in key %q exceeds the maximum of %d", n, s, maxJSONIndex)
+			}
+			path = append(path, jsonKey{index: n, array: true})
+		default:
+			path = append(path, jsonKey{name: b.String()})
+		}
+		i = j + 1
+	}
+
+	if len(path) == 0 {
+		return nil, errors.New("empty key")
+	}
+
+	return
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// jsonObject 为保留键顺序的 JSON 对象，键按首次出现的顺序输出
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+type jsonArray struct {
+	items []any
+}
+
+// jsonSet 将 v 写入 cur 中 path 指向的位置并返回新的 cur，
+// 路径上已有值的类型与路径不符时返回错误
+func jsonSet(cur any, path []jsonKey, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+
+	k := path[0]
+	if !k.array {
+		obj, ok := cur.(*jsonObject)
+		if cur == nil {
+			obj = &jsonObject{values: make(map[string]any)}
+		} else if !ok {
+			return nil, fmt.Errorf("key %q conflicts with an earlier non-object value", k.name)
+		}
+
+		old, exists := obj.values[k.name]
+		child, err := jsonSet(old, path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			obj.keys = append(obj.keys, k.name)
+		}
+		obj.values[k.name] = child
+		return obj, nil
+	}
+
+	arr, ok := cur.(*jsonArray)
+	if cur == nil {
+		arr = &jsonArray{}
+	} else if !ok {
+		return nil, errors.New("index conflicts with an earlier non-array value")
+	}
+
+	i := k.index
+	if i < 0 {
+		i = len(arr.items)
+	}
+	for len(arr.items) <= i {
+		arr.items = append(arr.items, nil)
+	}
+
+	child, err := jsonSet(arr.items[i], path[1:], v)
+	if err != nil {
+		return nil, err
+	}
+	arr.items[i] = child
+	return arr, nil
+}
+
+// appendJSON 将 jsonSet 构建的值编码为 JSON，未赋值的数组元素为 null
+func appendJSON(dst []byte, v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendJSONString(dst, v)
+	case json.RawMessage:
+		return append(dst, v...)
+	case *jsonObject:
+		dst = append(dst, '{')
+		for i, k := range v.keys {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSONString(dst, k)
+			dst = append(dst, ':')
+			dst = appendJSON(dst, v.values[k])
+		}
+		return append(dst, '}')
+	case *jsonArray:
+		dst = append(dst, '[')
+		for i, item := range v.items {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendJSON(dst, item)
+		}
+		return append(dst, ']')
+	default:
+		return append(dst, "null"...)
+	}
+}
+
+// appendJSONString 编码字符串，不转义 HTML 字符以保持请求体可读
+func appendJSONString(dst []byte, s string) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return append(dst, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))...)
+}
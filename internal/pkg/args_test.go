@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRequestItem(t *testing.T) {
+	t.Parallel()
+
+	for s, expected := range map[string]requestItem{
+		"a==b":           {key: "a", sep: sepQuery, value: "b"},
+		"a===b":          {key: "a", sep: sepQuery, value: "=b"},
+		"a:=1":           {key: "a", sep: sepRawJSON, value: "1"},
+		"a:=@f.json":     {key: "a", sep: sepJSONFile, value: "f.json"},
+		"a=@f.txt":       {key: "a", sep: sepFile, value: "f.txt"},
+		"a=b:c":          {key: "a", sep: sepString, value: "b:c"},
+		"Host:a=b":       {key: "Host", sep: sepHeader, value: "a=b"},
+		"url:http://x/":  {key: "url", sep: sepHeader, value: "http://x/"},
+		`a\==b`:          {key: "a=", sep: sepString, value: "b"},
+		`a\:b:=c`:        {key: "a:b", sep: sepRawJSON, value: "c"},
+		`a\[0\]\\x=y`:    {key: `a\[0\]\\x`, sep: sepString, value: "y"},
+		"email\\@x.com=": {key: "email@x.com", sep: sepString, value: ""},
+	} {
+		item, err := parseRequestItem(s)
+		assert.Nil(t, err, s)
+		assert.Equal(t, expected, item, s)
+	}
+
+	_, err := parseRequestItem(`a\=b`)
+	assert.NotNil(t, err)
+}
+
+func Test_parseJSONPath(t *testing.T) {
+	t.Parallel()
+
+	path, err := parseJSONPath(`a[b][0][]\[x\][\1]`)
+	assert.NotNil(t, err)
+	assert.Nil(t, path)
+
+	path, err = parseJSONPath(`a\[x\][b][0][][\1]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []jsonKey{
+		{name: "a[x]"},
+		{name: "b"},
+		{index: 0, array: true},
+		{index: -1, array: true},
+		{name: "1"},
+	}, path)
+
+	path, err = parseJSONPath("[3]")
+	assert.Nil(t, err)
+	assert.Equal(t, []jsonKey{{index: 3, array: true}}, path)
+
+	path, err = parseJSONPath("a[1024]")
+	assert.Nil(t, err)
+	assert.Equal(t, []jsonKey{{name: "a"}, {index: maxJSONIndex, array: true}}, path)
+
+	for _, s := range []string{"", "a]", "a[", "a[0]b", "[99999999999999999999]", "a[1025]", "a[999999999]"} {
+		_, err = parseJSONPath(s)
+		assert.NotNil(t, err, s)
+	}
+}
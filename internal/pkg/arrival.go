@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"sync"
+	"time"
+)
+
+// arrival 是开放模型的执行器，按固定到达速率发起请求，不等待之前的响应。
+// 没有空闲 worker 时按需扩容，in-flight 请求达到上限时丢弃新到达的请求
+type arrival struct {
+	p       *HttpGo
+	offset  func(n float64) (time.Duration, bool)
+	request func(intended time.Time, n int64)
+	max     int
+	workers int
+	jobs    chan arrivalJob
+	wg      sync.WaitGroup
+}
+
+// arrivalJob 表示第 n 个请求及其计划发送时间
+type arrivalJob struct {
+	intended time.Time
+	n        int64
+}
+
+func newArrival(p *HttpGo) *arrival {
+	a := &arrival{
+		p:    p,
+		max:  p.c.MaxInFlight,
+		jobs: make(chan arrivalJob),
+		request: func(intended time.Time, _ int64) {
+			p.request(intended)
+		},
+	}
+
+	if p.replay != nil {
+		a.offset = p.replay.offset
+		a.request = func(intended time.Time, n int64) {
+			p.replay.request(p, intended, n)
+		}
+	} else if p.stages != nil {
+		a.offset = p.stages.offset
+	} else {
+		rate := float64(p.c.Rate)
+		a.offset = func(n float64) (time.Duration, bool) {
+			return time.Duration(n / rate * float64(time.Second)), true
+		}
+	}
+
+	return a
+}
+
+// run 按计划时间依次派发请求，直到达到请求总数、持续时间或被停止，
+// 然后等待所有 in-flight 请求结束
+func (a *arrival) run() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	start := a.p.beginTime
+loop:
+	for issued := int64(0); ; issued++ {
+		if a.p.c.Count > 0 && issued >= int64(a.p.c.Count) {
+			break
+		}
+
+		off, ok := a.offset(float64(issued))
+		if !ok || a.p.exhausted.Load() {
+			break
+		}
+
+		intended := start.Add(off)
+		if a.p.c.Count <= 0 && off >= a.p.c.Duration {
+			a.p.stop()
+			break
+		}
+
+		if d := time.Until(intended); d > 0 {
+			timer.Reset(d)
+			select {
+			case <-a.p.doneChan:
+				timer.Stop()
+				break loop
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-a.p.doneChan:
+				break loop
+			default:
+			}
+		}
+
+		a.dispatch(arrivalJob{intended: intended, n: issued})
+	}
+
+	close(a.jobs)
+	a.wg.Wait()
+	a.p.stop()
+}
+
+// dispatch 将请求交给空闲 worker，没有空闲 worker 时新建一个，
+// worker 数量已达上限时记为丢弃
+func (a *arrival) dispatch(job arrivalJob) {
+	select {
+	case a.jobs <- job:
+		return
+	default:
+	}
+
+	if a.workers < a.max {
+		a.workers++
+		a.wg.Add(1)
+		go a.worker(job)
+		return
+	}
+
+	a.p.appendDropped()
+}
+
+func (a *arrival) worker(job arrivalJob) {
+	defer a.wg.Done()
+
+	for ok := true; ok; {
+		a.request(job.intended, job.n)
+		if a.p.exhausted.Load() {
+			return
+		}
+
+		select {
+		case <-a.p.doneChan:
+			return
+		case job, ok = <-a.jobs:
+		}
+	}
+}
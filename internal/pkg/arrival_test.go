@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_arrival_run_count(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Rate: 1000, Count: 20})
+	assert.Nil(t, p.init())
+	fc := newFakeClient()
+	p.client = fc
+
+	assert.Equal(t, done, p.run().(int))
+	assert.Equal(t, int64(20), atomic.LoadInt64(&fc.count))
+	assert.Equal(t, int64(20), p.stat.corrected.count())
+	assert.Equal(t, int64(0), atomic.LoadInt64(&p.stat.dropped))
+	assert.True(t, p.done)
+}
+
+func Test_arrival_run_dropped(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Rate: 1000, MaxInFlight: 2, Count: 10})
+	assert.Nil(t, p.init())
+	sc := &slowClient{release: make(chan struct{})}
+	p.client = sc
+
+	finished := make(chan struct{})
+	go func() {
+		p.run()
+		close(finished)
+	}()
+
+	time.Sleep(time.Millisecond * 100)
+	close(sc.release)
+	<-finished
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&sc.count))
+	assert.Equal(t, int64(8), atomic.LoadInt64(&p.stat.dropped))
+	assert.Equal(t, int64(8), p.report().Totals.Dropped)
+}
+
+func Test_arrival_run_duration(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Rate: 100, Duration: time.Millisecond * 100})
+	assert.Nil(t, p.init())
+	fc := newFakeClient()
+	p.client = fc
+
+	assert.Equal(t, done, p.run().(int))
+	assert.InDelta(t, 10, atomic.LoadInt64(&fc.count), 1)
+}
+
+func Test_arrival_run_stopped(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Rate: 10, Duration: time.Hour})
+	assert.Nil(t, p.init())
+	p.client = newFakeClient()
+
+	finished := make(chan struct{})
+	go func() {
+		p.run()
+		close(finished)
+	}()
+
+	time.Sleep(time.Millisecond * 50)
+	p.stop()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("arrival executor did not stop")
+	}
+}
+
+func Test_HttpGo_init_rate_with_qps(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Rate: 10, Qps: 10})
+	assert.NotNil(t, p.init())
+	assert.Equal(t, defaultMaxInFlight, p.c.MaxInFlight)
+	assert.Equal(t, defaultMaxInFlight, p.c.maxConns())
+}
+
+type slowClient struct {
+	release chan struct{}
+	count   int64
+}
+
+func (sc *slowClient) do() (*endpoint, int, time.Duration, error) {
+	atomic.AddInt64(&sc.count, 1)
+	<-sc.release
+	return nil, 200, time.Millisecond, nil
+}
+
+func (sc *slowClient) doOnce() error {
+	return nil
+}
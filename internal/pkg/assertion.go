@@ -0,0 +1,250 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AssertionError 表示压测结果未满足一条或多条断言
+type AssertionError struct {
+	Failed []string
+}
+
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + strings.Join(e.Failed, "; ")
+}
+
+type metricKind int
+
+const (
+	metricLatency metricKind = iota
+	metricRatio
+	metricNumber
+)
+
+type assertion struct {
+	expr   string
+	metric string
+	op     string
+	want   float64
+	kind   metricKind
+}
+
+var assertionOps = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// parseAssertion 解析形如 p99<200ms、error_rate<0.1%、rps>5000 的断言
+func parseAssertion(expr string) (a assertion, err error) {
+	a.expr = strings.TrimSpace(expr)
+
+	i := strings.IndexAny(a.expr, "<>=!")
+	if i <= 0 {
+		return a, fmt.Errorf("invalid assertion %q", expr)
+	}
+	for _, op := range assertionOps {
+		if strings.HasPrefix(a.expr[i:], op) {
+			a.op = op
+			break
+		}
+	}
+	if a.op == "" {
+		return a, fmt.Errorf("invalid operator in assertion %q", expr)
+	}
+
+	a.metric = strings.ToLower(strings.TrimSpace(a.expr[:i]))
+	value := strings.TrimSpace(a.expr[i+len(a.op):])
+	if value == "" {
+		return a, fmt.Errorf("missing value in assertion %q", expr)
+	}
+
+	if a.kind, err = assertionMetricKind(a.metric); err != nil {
+		return
+	}
+
+	switch a.kind {
+	case metricLatency:
+		a.want, err = parseLatencyValue(value)
+	case metricRatio:
+		a.want, err = parseRatioValue(value)
+	default:
+		a.want, err = strconv.ParseFloat(value, 64)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid value in assertion %q: %w", expr, err)
+	}
+
+	return
+}
+
+// correctedPrefix 表示断言针对修正协调遗漏后的延迟，例如 corrected_p99<200ms
+const correctedPrefix = "corrected_"
+
+func assertionMetricKind(metric string) (metricKind, error) {
+	if m := strings.TrimPrefix(metric, correctedPrefix); m != metric {
+		if kind, err := assertionMetricKind(m); err == nil && kind == metricLatency {
+			return kind, nil
+		}
+		return 0, fmt.Errorf("unsupported assertion metric %q", metric)
+	}
+
+	switch metric {
+	case "avg", "stdev", "min", "max":
+		return metricLatency, nil
+	case "error_rate", "1xx_ratio", "2xx_ratio", "3xx_ratio", "4xx_ratio", "5xx_ratio", "ok_ratio":
+		return metricRatio, nil
+	case "rps", "requests", "errors", "dropped":
+		return metricNumber, nil
+	}
+	if len(metric) > 1 && metric[0] == 'p' {
+		if q, err := strconv.ParseFloat(metric[1:], 64); err == nil && q > 0 && q <= 100 {
+			return metricLatency, nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported assertion metric %q", metric)
+}
+
+// parseLatencyValue 将延迟转换为毫秒，未带单位时按毫秒处理
+func parseLatencyValue(v string) (float64, error) {
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, err
+	}
+	return float64(d) / float64(time.Millisecond), nil
+}
+
+// parseRatioValue 解析比例，支持 0.1% 与 0.001 两种写法
+func parseRatioValue(v string) (float64, error) {
+	if strings.HasSuffix(v, "%") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v[:len(v)-1]), 64)
+		return f / 100, err
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func (a assertion) check(r *report) (actual float64, ok bool) {
+	actual = a.actual(r)
+	switch a.op {
+	case "<":
+		ok = actual < a.want
+	case "<=":
+		ok = actual <= a.want
+	case ">":
+		ok = actual > a.want
+	case ">=":
+		ok = actual >= a.want
+	case "==":
+		ok = actual == a.want
+	case "!=":
+		ok = actual != a.want
+	}
+	return
+}
+
+func (a assertion) actual(r *report) float64 {
+	total := float64(r.Totals.Requests + r.Totals.Errors)
+	ratio := func(n int64) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n) / total
+	}
+
+	metric, lat, h := a.metric, r.Latency, r.latency
+	if m := strings.TrimPrefix(metric, correctedPrefix); m != metric {
+		metric = m
+		lat, h = r.correctedLatency()
+	}
+
+	switch metric {
+	case "avg":
+		return lat.AvgMs
+	case "stdev":
+		return lat.StdevMs
+	case "min":
+		return lat.MinMs
+	case "max":
+		return lat.MaxMs
+	case "error_rate":
+		return ratio(r.Totals.Errors)
+	case "1xx_ratio":
+		return ratio(r.Codes.Code1xx)
+	case "2xx_ratio":
+		return ratio(r.Codes.Code2xx)
+	case "3xx_ratio":
+		return ratio(r.Codes.Code3xx)
+	case "4xx_ratio":
+		return ratio(r.Codes.Code4xx)
+	case "5xx_ratio":
+		return ratio(r.Codes.Code5xx)
+	case "ok_ratio":
+		if r.GRPC == nil {
+			return 0
+		}
+		return ratio(r.GRPC.Codes[grpcCodeName(0)])
+	case "rps":
+		if r.Totals.ElapsedSec == 0 {
+			return 0
+		}
+		return float64(r.Totals.Requests) / r.Totals.ElapsedSec
+	case "requests":
+		return float64(r.Totals.Requests)
+	case "errors":
+		return float64(r.Totals.Errors)
+	case "dropped":
+		return float64(r.Totals.Dropped)
+	}
+
+	q, _ := strconv.ParseFloat(metric[1:], 64)
+	if h == nil {
+		return lat.PercentilesMs[percentileKey(q)]
+	}
+	return float64(h.percentile(q)) / 1000
+}
+
+func (a assertion) format(v float64) string {
+	switch a.kind {
+	case metricLatency:
+		return strconv.FormatFloat(v, 'f', 2, 64) + "ms"
+	case metricRatio:
+		return strconv.FormatFloat(v*100, 'f', 3, 64) + "%"
+	default:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+}
+
+// evaluateAssertions 计算所有断言的结果
+func evaluateAssertions(asserts []assertion, r *report) []reportAssertion {
+	results := make([]reportAssertion, 0, len(asserts))
+	for _, a := range asserts {
+		actual, ok := a.check(r)
+		results = append(results, reportAssertion{
+			Expr:   a.expr,
+			Actual: a.format(actual),
+			Passed: ok,
+		})
+	}
+	return results
+}
+
+// checkAssertions 在压测结束后校验断言，存在失败项时返回 *AssertionError
+func (p *HttpGo) checkAssertions() error {
+	if len(p.asserts) == 0 {
+		return nil
+	}
+
+	var failed []string
+	for _, res := range p.report().Assertions {
+		if !res.Passed {
+			failed = append(failed, res.Expr+" (actual: "+res.Actual+")")
+		}
+	}
+	if len(failed) > 0 {
+		return &AssertionError{Failed: failed}
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseAssertion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expr   string
+		metric string
+		op     string
+		want   float64
+	}{
+		{"p99<200ms", "p99", "<", 200},
+		{" P99.9 <= 1s ", "p99.9", "<=", 1000},
+		{"avg<1500us", "avg", "<", 1.5},
+		{"max<30", "max", "<", 30},
+		{"error_rate<0.1%", "error_rate", "<", 0.001},
+		{"2xx_ratio>=99.9%", "2xx_ratio", ">=", 0.999},
+		{"5xx_ratio==0", "5xx_ratio", "==", 0},
+		{"rps>5000", "rps", ">", 5000},
+		{"errors!=3", "errors", "!=", 3},
+		{"corrected_p99<1s", "corrected_p99", "<", 1000},
+		{"dropped==0", "dropped", "==", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			a, err := parseAssertion(tc.expr)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.metric, a.metric)
+			assert.Equal(t, tc.op, a.op)
+			assert.InDelta(t, tc.want, a.want, 1e-9)
+		})
+	}
+}
+
+func Test_parseAssertion_invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "p99", "<200ms", "p99=>1", "p99<", "p99<abc", "foo<1", "p0<1", "p101<1", "error_rate<x%", "rps>fast"} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseAssertion(expr)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func Test_assertion_check(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url"})
+	p.stat.appendLatency(time.Millisecond * 100)
+	p.stat.appendLatency(time.Millisecond * 300)
+	p.stat.reqs = 2
+	p.stat.elapsed = int64(time.Second)
+	p.stat.code2xx = 1
+	p.stat.code5xx = 1
+	p.stat.errs["boom"] = 2
+	r := p.report()
+
+	testCases := []struct {
+		expr string
+		ok   bool
+	}{
+		{"p50<200ms", true},
+		{"p99<200ms", false},
+		{"p99.99<=300ms", true},
+		{"avg==200ms", true},
+		{"min>=100ms", true},
+		{"max>1s", false},
+		{"stdev>0", true},
+		{"error_rate<=50%", true},
+		{"error_rate<0.1%", false},
+		{"2xx_ratio>=25%", true},
+		{"5xx_ratio!=0.25", false},
+		{"1xx_ratio==0", true},
+		{"3xx_ratio==0", true},
+		{"4xx_ratio==0", true},
+		{"rps>1", true},
+		{"requests==2", true},
+		{"errors<1", false},
+		{"corrected_p99<=300ms", true},
+		{"corrected_avg==200ms", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			a, err := parseAssertion(tc.expr)
+			assert.Nil(t, err)
+			_, ok := a.check(r)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func Test_assertion_corrected(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url"})
+	p.stat.appendLatency(time.Millisecond * 10)
+	p.stat.appendCorrectedLatency(time.Millisecond*500, time.Millisecond*10)
+	r := p.report()
+
+	a, err := parseAssertion("p99<100ms")
+	assert.Nil(t, err)
+	_, ok := a.check(r)
+	assert.True(t, ok)
+
+	a, err = parseAssertion("corrected_p99<100ms")
+	assert.Nil(t, err)
+	_, ok = a.check(r)
+	assert.False(t, ok)
+
+	a, err = parseAssertion("corrected_p50>=500ms")
+	assert.Nil(t, err)
+	_, ok = a.check(&report{Corrected: &reportLatency{PercentilesMs: map[string]float64{"p50": 500}}})
+	assert.True(t, ok)
+}
+
+func Test_assertion_ok_ratio(t *testing.T) {
+	t.Parallel()
+
+	a, err := parseAssertion("ok_ratio>=90%")
+	assert.Nil(t, err)
+	assert.Equal(t, metricRatio, a.kind)
+
+	r := &report{
+		Totals: reportTotals{Requests: 10},
+		GRPC:   &reportGRPC{Codes: map[string]int64{"OK": 9, "Unavailable": 1}},
+	}
+	_, ok := a.check(r)
+	assert.True(t, ok)
+
+	r.GRPC.Codes["OK"] = 8
+	r.GRPC.Codes["Unavailable"] = 2
+	_, ok = a.check(r)
+	assert.False(t, ok)
+}
+
+func Test_assertion_rps_without_elapsed(t *testing.T) {
+	t.Parallel()
+
+	a, err := parseAssertion("rps>0")
+	assert.Nil(t, err)
+	_, ok := a.check(&report{})
+	assert.False(t, ok)
+}
+
+func Test_HttpGo_checkAssertions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("invalid assertion", func(t *testing.T) {
+		p := New(Config{Url: "url", Asserts: []string{"p99"}})
+		assert.NotNil(t, p.init())
+	})
+
+	t.Run("ok_ratio without grpc", func(t *testing.T) {
+		p := New(Config{Url: "url", Asserts: []string{"ok_ratio>=99%"}})
+		assert.NotNil(t, p.init())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:         "url",
+			NoTUI:       true,
+			Count:       2,
+			Connections: 1,
+			Asserts:     []string{"requests==2", "error_rate<0.1%", "rps<0"},
+		})
+		p.stat.w = &buf
+		p.stat.ew = &buf
+		p.client = newFakeClient()
+
+		err := p.Run()
+		var ae *AssertionError
+		assert.True(t, errors.As(err, &ae))
+		assert.Len(t, ae.Failed, 1)
+		assert.True(t, strings.HasPrefix(ae.Failed[0], "rps<0 (actual: "))
+		assert.Contains(t, err.Error(), "assertion failed: rps<0")
+	})
+
+	t.Run("passed and reported", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:         "url",
+			Output:      outputJSON,
+			Count:       2,
+			Connections: 1,
+			// 按请求数结束的短时间压测也需要计入最后一个窗口的耗时
+			Asserts: []string{"requests==2", "error_rate<0.1%", "rps>0"},
+		})
+		p.stat.w = &buf
+		p.stat.ew = &bytes.Buffer{}
+		p.client = newFakeClient()
+
+		assert.Nil(t, p.Run())
+		assert.Contains(t, buf.String(), `"expr": "error_rate<0.1%"`)
+		assert.Contains(t, buf.String(), `"actual": "0.000%"`)
+		assert.Contains(t, buf.String(), `"passed": true`)
+	})
+}
@@ -2,17 +2,21 @@ package pkg
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/valyala/fasthttp"
 )
 
+// client 发送请求，do 返回本次请求所属的端点，未使用场景时为 nil
 type client interface {
-	do() (int, time.Duration, error)
+	do() (*endpoint, int, time.Duration, error)
 	doOnce() error
 }
 
@@ -25,27 +29,71 @@ type onceClientDoer interface {
 	DoRedirects(*fasthttp.Request, *fasthttp.Response, int) error
 }
 
+// target 表示一个请求模板及发送它的客户端
+type target struct {
+	*endpoint
+	tmpl        *requestTemplate
+	doer        clientDoer
+	requestPool sync.Pool
+	streamPool  sync.Pool
+	request     *fasthttp.Request
+	body        []byte
+}
+
 type httpClient struct {
-	doer         clientDoer
+	*target
 	onceDoer     onceClientDoer
-	requestPool  sync.Pool
-	streamPool   sync.Pool
-	request      *fasthttp.Request
 	writeCloser  io.WriteCloser
-	body         []byte
 	stream       bool
 	maxRedirects int
+	// streamSize 为流式请求体的长度，为 -1 时使用分块传输
+	streamSize int
+	// bodies 统计响应体解压前后的字节数，未指定 Accept-Encoding 时为 nil
+	bodies *bodyCounter
+
+	// targets 为场景中的请求，weights 为对应的累计权重
+	targets []*target
+	weights []int
+	seq     uint64
+	// offsets 为 HAR 中各请求相对第一个请求的时间
+	offsets []time.Duration
+	// raw 为原样发送的原始请求，调试模式下输出
+	raw []byte
 }
 
 func newHttpClient(c *Config) (fc *httpClient, err error) {
 	fc = &httpClient{
+		target:       &target{request: fasthttp.AcquireRequest()},
 		maxRedirects: c.getMaxRedirects(),
-		request:      fasthttp.AcquireRequest(),
 		stream:       c.Stream,
+		streamSize:   -1,
+		bodies:       c.bodies,
 		writeCloser:  defaultWriteCloser{Writer: os.Stdout},
 	}
 
-	c.parseArgs()
+	// 数据文件在构建请求模板前读取，场景中的所有请求共用
+	if c.Data != "" {
+		if c.feeder, err = newFeeder(c.Data, c.DataMode); err != nil {
+			return
+		}
+	}
+
+	if c.Scenario != "" {
+		return fc, fc.initScenario(c)
+	}
+	if c.Har != "" {
+		return fc, fc.initHar(c)
+	}
+	if c.HttpFile != "" {
+		return fc, fc.initHttpFile(c)
+	}
+	if c.Raw != "" {
+		return fc, fc.initRaw(c)
+	}
+
+	if err = c.parseArgs(); err != nil {
+		return
+	}
 	if err = c.setReqBasic(fc.request); err != nil {
 		return
 	}
@@ -53,14 +101,28 @@ func newHttpClient(c *Config) (fc *httpClient, err error) {
 		return
 	}
 	fc.body = c.body
+	// multipart 请求体的长度已知，使用 Content-Length 而不是分块传输
+	if c.multipart != "" {
+		fc.stream, fc.streamSize = true, len(c.body)
+	}
 	if err = c.setReqHeader(fc.request); err != nil {
 		return
 	}
+	if fc.tmpl, err = c.requestTemplate(); err != nil {
+		return
+	}
+	if c.feeder != nil && !fc.tmpl.usesData() {
+		return fc, errDataUnused
+	}
 	if c.tlsConf, err = c.getTlsConfig(); err != nil {
 		return
 	}
 
-	if c.Debug {
+	if c.Debug && c.HTTP2 {
+		fc.onceDoer = c.newHttp2Doer(c.addr, c.isTLS, 1)
+	} else if c.Debug && c.HTTP3 {
+		fc.onceDoer, err = c.newHttp3Doer(c.addr, c.isTLS, 1)
+	} else if c.Debug {
 		fc.request.SetConnectionClose()
 		fc.onceDoer, err = c.hostClient()
 	} else {
@@ -70,35 +132,133 @@ func newHttpClient(c *Config) (fc *httpClient, err error) {
 	return
 }
 
-func (c *httpClient) do() (code int, latency time.Duration, err error) {
+// initScenario 读取场景文件并为每个请求构建请求模板
+func (c *httpClient) initScenario(conf *Config) (err error) {
+	if conf.Debug {
+		return errors.New("debug mode does not support scenario files")
+	}
+
+	var list []scenario
+	if list, err = readScenarios(conf.Scenario); err != nil {
+		return
+	}
+	if conf.tlsConf, err = conf.getTlsConfig(); err != nil {
+		return
+	}
+	c.targets, c.weights, err = conf.scenarioTargets(list)
+
+	return
+}
+
+// endpoints 返回场景中各请求对应的端点
+func (c *httpClient) endpoints() []*endpoint {
+	if c == nil || len(c.targets) == 0 {
+		return nil
+	}
+
+	eps := make([]*endpoint, 0, len(c.targets))
+	seen := make(map[*endpoint]bool, len(c.targets))
+	for _, t := range c.targets {
+		if !seen[t.endpoint] {
+			seen[t.endpoint] = true
+			eps = append(eps, t.endpoint)
+		}
+	}
+	return eps
+}
+
+// pick 按权重轮流选择场景中的请求，未使用场景时返回默认请求
+func (c *httpClient) pick() *target {
+	if len(c.targets) == 0 {
+		return c.target
+	}
+
+	n := atomic.AddUint64(&c.seq, 1) - 1
+	w := int(n % uint64(c.weights[len(c.weights)-1]))
+	return c.targets[sort.SearchInts(c.weights, w+1)]
+}
+
+func (c *httpClient) do() (*endpoint, int, time.Duration, error) {
+	return c.doTarget(c.pick())
+}
+
+// doTarget 发送 t 对应的请求，返回 t 所属的端点
+func (c *httpClient) doTarget(t *target) (ep *endpoint, code int, latency time.Duration, err error) {
+	ep = t.endpoint
+
 	var (
-		req  = c.acquireReq()
+		req  = t.acquireReq()
 		resp = fasthttp.AcquireResponse()
 	)
 
 	defer func() {
-		c.requestPool.Put(req)
+		t.requestPool.Put(req)
 		fasthttp.ReleaseResponse(resp)
 	}()
 
+	if t.tmpl != nil {
+		if err = t.tmpl.render(req); err != nil {
+			return
+		}
+	}
+
 	if c.stream {
-		bodyStream := c.acquireBodyStream()
-		req.SetBodyStream(bodyStream, -1)
-		c.streamPool.Put(bodyStream)
+		// 请求发送完成前读取位置仍在使用，结束后才能放回池中
+		bodyStream := t.acquireBodyStream()
+		req.SetBodyStream(bodyStream, c.streamSize)
+		defer t.streamPool.Put(bodyStream)
 	}
 
 	start := time.Now()
-	if err = c.doer.Do(req, resp); err != nil {
+	if err = t.doer.Do(req, resp); err != nil {
 		return
 	}
 
 	code = resp.StatusCode()
 	latency = time.Since(start)
+	if c.bodies != nil {
+		c.bodies.add(resp)
+	}
 
 	return
 }
 
-func (c *httpClient) acquireReq() *fasthttp.Request {
+// doStream 发送 t 对应的请求，收到响应头后由 read 流式读取响应体，start 为请求的发送时间。
+// 客户端需开启 StreamResponseBody，响应体未读取完毕时连接不能复用，请求总是带有 Connection: close
+func (c *httpClient) doStream(t *target, read func(resp *fasthttp.Response, start time.Time) error) error {
+	var (
+		req  = t.acquireReq()
+		resp = fasthttp.AcquireResponse()
+	)
+
+	defer func() {
+		_ = resp.CloseBodyStream()
+		t.requestPool.Put(req)
+		fasthttp.ReleaseResponse(resp)
+	}()
+
+	if t.tmpl != nil {
+		if err := t.tmpl.render(req); err != nil {
+			return err
+		}
+	}
+
+	if c.stream {
+		bodyStream := t.acquireBodyStream()
+		req.SetBodyStream(bodyStream, c.streamSize)
+		defer t.streamPool.Put(bodyStream)
+	}
+	req.Header.SetConnectionClose()
+
+	start := time.Now()
+	if err := t.doer.Do(req, resp); err != nil {
+		return err
+	}
+
+	return read(resp, start)
+}
+
+func (c *target) acquireReq() *fasthttp.Request {
 	v := c.requestPool.Get()
 	if v == nil {
 		req := fasthttp.AcquireRequest()
@@ -109,7 +269,7 @@ func (c *httpClient) acquireReq() *fasthttp.Request {
 	return v.(*fasthttp.Request)
 }
 
-func (c *httpClient) acquireBodyStream() *bytes.Reader {
+func (c *target) acquireBodyStream() *bytes.Reader {
 	v := c.streamPool.Get()
 	if v == nil {
 		return bytes.NewReader(c.body)
@@ -125,15 +285,25 @@ func (c *httpClient) doOnce() (err error) {
 		resp = fasthttp.AcquireResponse()
 	)
 
+	if c.tmpl != nil {
+		if err = c.tmpl.render(req); err != nil {
+			return
+		}
+	}
+
 	if c.stream {
-		req.SetBodyStream(bytes.NewReader(c.body), -1)
+		req.SetBodyStream(bytes.NewReader(c.body), c.streamSize)
 	}
 
 	defer func() {
 		if err == nil {
 			msg := fmt.Sprintf("Connected to %s(%v)\r\n\r\n", req.URI().Host(), resp.RemoteAddr())
 			_, _ = c.writeCloser.Write([]byte(msg))
-			_, _ = req.WriteTo(c.writeCloser)
+			if c.raw != nil {
+				_, _ = c.writeCloser.Write(c.raw)
+			} else {
+				_, _ = req.WriteTo(c.writeCloser)
+			}
 			_, _ = c.writeCloser.Write([]byte("\n\n"))
 			_, _ = resp.WriteTo(c.writeCloser)
 			_ = c.writeCloser.Close()
@@ -62,7 +62,7 @@ func Test_Fastclient_Do(t *testing.T) {
     t.Parallel()
 
     f := &httpClient{
-        body:   []byte("body"),
+        target: &target{body: []byte("body")},
         stream: true,
     }
 
@@ -73,14 +73,14 @@ func Test_Fastclient_Do(t *testing.T) {
     t.Run("error", func(t *testing.T) {
         fakeErr := errors.New("fake error")
         f.doer = errorFakeDoer(fakeErr, nil)
-        _, _, err := f.do()
+        _, _, _, err := f.do()
         assert.NotNil(t, err)
     })
 
     t.Run("success", func(t *testing.T) {
         f.doer = getFakeDoer(400, t)
         for i := 0; i < 5; i++ {
-            code, latency, err := f.do()
+            _, code, latency, err := f.do()
             assert.Nil(t, err)
             assert.True(t, latency > 0)
             assert.Equal(t, code, 400)
@@ -88,11 +88,170 @@ func Test_Fastclient_Do(t *testing.T) {
     })
 }
 
+func Test_Client_Scenario(t *testing.T) {
+    t.Parallel()
+
+    path := writeScenario(t, "s.yaml", `
+requests:
+  - {name: a, url: /a, weight: 3}
+  - {name: b, url: /b}
+`)
+
+    t.Run("success", func(t *testing.T) {
+        fc, err := newHttpClient(&Config{Url: "http://example.com", Scenario: path})
+        assert.Nil(t, err)
+        assert.Len(t, fc.targets, 2)
+        assert.Equal(t, "a", fc.endpoints()[0].name)
+        assert.Equal(t, "b", fc.endpoints()[1].name)
+
+        counts := map[string]int{}
+        for i := 0; i < 8; i++ {
+            counts[fc.pick().name]++
+        }
+        assert.Equal(t, map[string]int{"a": 6, "b": 2}, counts)
+    })
+
+    t.Run("debug mode", func(t *testing.T) {
+        _, err := newHttpClient(&Config{Scenario: path, Debug: true})
+        assert.NotNil(t, err)
+    })
+
+    t.Run("no cert", func(t *testing.T) {
+        _, err := newHttpClient(&Config{Scenario: path, Cert: "not-cert"})
+        assert.NotNil(t, err)
+    })
+
+    t.Run("not exist", func(t *testing.T) {
+        _, err := newHttpClient(&Config{Scenario: "not-exist"})
+        assert.NotNil(t, err)
+    })
+
+    t.Run("do", func(t *testing.T) {
+        fc := &httpClient{}
+        fc.targets = []*target{
+            {endpoint: newEndpoint("a"), body: []byte("body"), request: fasthttp.AcquireRequest(), doer: getFakeDoer(200, t)},
+        }
+        fc.targets[0].request.SetBodyString("body")
+        fc.weights = []int{1}
+
+        ep, code, _, err := fc.do()
+        assert.Nil(t, err)
+        assert.Equal(t, 200, code)
+        assert.Equal(t, "a", ep.name)
+    })
+}
+
+func Test_Client_Template(t *testing.T) {
+    t.Parallel()
+
+    t.Run("error", func(t *testing.T) {
+        _, err := newHttpClient(&Config{Url: "http://example.com/{{randInt 2 1}}"})
+        assert.NotNil(t, err)
+    })
+
+    // 请求体中未知的变量不作为模板，按原样发送
+    t.Run("unknown variable", func(t *testing.T) {
+        body := `{"greeting":"Hello {{foo}}","list":"{{#each items}}{{name}}{{/each}}"}`
+        fc, err := newHttpClient(&Config{Url: "http://example.com/a", Body: body})
+        assert.Nil(t, err)
+        assert.Nil(t, fc.tmpl)
+
+        var sent string
+        fc.doer = doerFunc(func(req *fasthttp.Request, resp *fasthttp.Response) error {
+            sent = string(req.Body())
+            return nil
+        })
+        _, _, _, err = fc.do()
+        assert.Nil(t, err)
+        assert.Equal(t, body, sent)
+    })
+
+    t.Run("render", func(t *testing.T) {
+        fc, err := newHttpClient(&Config{Url: "http://example.com/{{seq}}", Body: "body"})
+        assert.Nil(t, err)
+        assert.NotNil(t, fc.tmpl)
+
+        var paths []string
+        fc.doer = doerFunc(func(req *fasthttp.Request, resp *fasthttp.Response) error {
+            paths = append(paths, string(req.URI().Path()))
+            return nil
+        })
+        for i := 0; i < 2; i++ {
+            _, _, _, err = fc.do()
+            assert.Nil(t, err)
+        }
+        assert.Equal(t, []string{"/1", "/2"}, paths)
+    })
+
+    t.Run("debug", func(t *testing.T) {
+        var buf bytes.Buffer
+        fc, err := newHttpClient(&Config{Url: "http://example.com/{{seq}}", Body: "body", Debug: true})
+        assert.Nil(t, err)
+        fc.writeCloser = defaultWriteCloser{&buf}
+        fc.onceDoer = getFakeOnceDoer(0, t)
+        assert.Nil(t, fc.doOnce())
+        assert.Contains(t, buf.String(), "GET /1 HTTP/1.1")
+    })
+
+    t.Run("scenario", func(t *testing.T) {
+        path := writeScenario(t, "s.yaml", "requests: [{url: '/a/{{seq}}'}]")
+        fc, err := newHttpClient(&Config{Url: "http://example.com", Scenario: path})
+        assert.Nil(t, err)
+        assert.NotNil(t, fc.targets[0].tmpl)
+
+        path = writeScenario(t, "s.yaml", "requests: [{url: '/a/{{randString 0}}'}]")
+        _, err = newHttpClient(&Config{Url: "http://example.com", Scenario: path})
+        assert.NotNil(t, err)
+    })
+
+    t.Run("data not referenced", func(t *testing.T) {
+        data := writeScenario(t, "users.csv", "id\n1\n")
+        _, err := newHttpClient(&Config{Url: "http://example.com/{{seq}}", Data: data})
+        assert.Equal(t, errDataUnused, err)
+
+        // 场景中任一请求引用数据列即可
+        path := writeScenario(t, "s.yaml", "requests: [{url: '/a'}, {url: '/b/{{data.id}}'}]")
+        fc, err := newHttpClient(&Config{Url: "http://example.com", Scenario: path, Data: data})
+        assert.Nil(t, err)
+        assert.False(t, fc.targets[0].tmpl.usesData())
+        assert.True(t, fc.targets[1].tmpl.usesData())
+
+        path = writeScenario(t, "s.yaml", "requests: [{url: '/a/{{seq}}'}]")
+        _, err = newHttpClient(&Config{Url: "http://example.com", Scenario: path, Data: data})
+        assert.Equal(t, errDataUnused, err)
+
+        // HAR 默认不渲染模板，数据文件不会被使用
+        path = writeScenario(t, "s.har", `{"log":{"version":"1.2","entries":[{"request":{"method":"GET","url":"http://example.com/{{data.id}}"}}]}}`)
+        _, err = newHttpClient(&Config{Har: path, Data: data})
+        assert.Equal(t, errDataUnused, err)
+        _, err = newHttpClient(&Config{Har: path, Data: data, HarTemplate: true})
+        assert.Nil(t, err)
+
+        path = writeScenario(t, "r.txt", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+        _, err = newHttpClient(&Config{Raw: path, Data: data})
+        assert.NotNil(t, err)
+    })
+}
+
+type doerFunc func(*fasthttp.Request, *fasthttp.Response) error
+
+func (f doerFunc) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+    return f(req, resp)
+}
+
+func Test_Client_endpoints(t *testing.T) {
+    t.Parallel()
+
+    var fc *httpClient
+    assert.Nil(t, fc.endpoints())
+    assert.Nil(t, (&httpClient{}).endpoints())
+}
+
 func Test_Fastclient_DoRedirects(t *testing.T) {
     t.Parallel()
 
     f := &httpClient{
-        body:   []byte("body"),
+        target: &target{body: []byte("body")},
         stream: true,
     }
 
@@ -5,6 +5,7 @@ import (
     "crypto/tls"
     "fmt"
     "net"
+    "net/url"
     "os"
     "path/filepath"
     "strconv"
@@ -18,6 +19,8 @@ import (
 const (
     mimeApplicationJSON = "application/json"
     mimeApplicationForm = "application/x-www-form-urlencoded"
+    mimeTextEventStream = "text/event-stream"
+    mimeMultipartForm   = "multipart/form-data"
 )
 
 // Config 保存 httpgo 的配置设置
@@ -29,6 +32,19 @@ type Config struct {
     // Qps 指定固定基准测试的最高值，但实际 qps
     // 可能会低于此值
     Qps int
+    // Rate 表示开放模型下每秒发起的请求数，请求按固定到达速率发送，
+    // 与响应快慢和 Connections 无关，不能与 Qps 同时使用
+    Rate int
+    // MaxInFlight 表示开放模型下同时进行的请求上限，达到上限时新到达的
+    // 请求被丢弃并单独计数，默认为 1024（仅在 Rate 大于 0 时生效）
+    MaxInFlight int
+    // Stages 表示分阶段的负载曲线，格式为 时长:目标值，以逗号分隔，
+    // 例如 30s:100,2m:1000,30s:0，目标值在阶段内从上一阶段的目标值
+    // 线性变化（首个阶段从 0 开始），指定时忽略 Duration
+    Stages string
+    // StageBy 表示阶段目标值作用的对象，可选 qps（默认）、rate（开放模型的
+    // 到达速率）与 connections（活跃的 worker 数量）
+    StageBy string
     // Duration 表示基准测试持续时间，如果指定了 Count 则忽略此项
     Duration time.Duration
     // Timeout 表示套接字/请求超时时间
@@ -37,7 +53,7 @@ type Config struct {
     Url string
     // Method 是 HTTP 请求方法
     Method string
-    // Args 可以方便地为表单和 JSON 请求设置数据
+    // Args 为 httpie 风格的请求项，可以设置查询参数、请求头以及 JSON 或表单请求体
     Args []string
     // Headers 表示 HTTP 请求头
     Headers []string
@@ -49,12 +65,67 @@ type Config struct {
     Body string
     // File 表示从文件中读取请求体
     File string
+    // Scenario 表示场景文件的路径，文件中列出多个带权重的请求，
+    // worker 按权重选择请求，结果按端点分别统计
+    Scenario string
+    // HttpFile 表示 JetBrains / VS Code REST Client 格式的 .http 文件路径，
+    // 文件中的请求按权重混合发送，结果按请求分别统计
+    HttpFile string
+    // Names 按名称选择 .http 文件中的请求，格式为 名称[:权重]，
+    // 未指定时使用文件中的所有请求
+    Names []string
+    // Har 表示 HAR 文件的路径，文件中的所有请求作为压测负载回放，
+    // 结果按 URL 路径分别统计
+    Har string
+    // HarMode 表示 HAR 的回放方式，paced（默认）按记录的相对时间回放一遍，
+    // loop 以 Connections 个并发循环回放所有请求
+    HarMode string
+    // Speed 表示 paced 回放的速度倍数，例如 2 表示按原始时间间隔的一半回放，
+    // 默认为 1
+    Speed float64
+    // HarTemplate 为 true 时渲染 HAR 请求中的 {{变量}}，默认按记录的内容原样回放
+    HarTemplate bool
+    // Data 表示数据文件的路径，支持 CSV（首行为列名）与 JSONL，
+    // 每次请求取出一行，模板中以 {{data.列名}} 引用
+    Data string
+    // DataMode 表示数据文件的读取方式，可选 circular（默认，循环读取）、
+    // sequential（按顺序读取一遍，读完后结束压测）与 random（随机读取）
+    DataMode string
+    // Raw 表示原始 HTTP/1.1 请求文件的路径，文件内容（请求行、请求头与请求体）
+    // 原样发送，不做请求头的规范化，目标地址取自 Url 或请求中的 Host 头
+    Raw string
     // Stream 表示使用流式请求体
     Stream bool
     // JSON 表示发送 JSON 请求
     JSON bool
     // Form 表示发送表单请求
     Form bool
+    // HTTP2 表示使用 HTTP/2 发送请求，https 通过 ALPN 协商 h2，http 使用 h2c
+    HTTP2 bool
+    // HTTP3 表示通过 QUIC 使用 HTTP/3 发送请求，仅支持 https
+    HTTP3 bool
+    // StreamsPerConn 表示 HTTP/2 与 HTTP/3 每个连接上的并发流数，连接数为并发数除以该值
+    StreamsPerConn int
+    // WebSocket 表示压测 WebSocket 服务，每个连接按 WsRate 发送 WsMessage 并匹配回复
+    WebSocket bool
+    // WsMessage 表示 WebSocket 模式下发送的消息，为空时使用 Body，均为空时发送 ping
+    WsMessage string
+    // WsRate 表示 WebSocket 模式下每个连接每秒发送的消息数，为 0 时收到回复后立即发送下一条
+    WsRate float64
+    // SSE 表示压测 Server-Sent Events 服务，每个连接保持一个 text/event-stream 响应，
+    // 统计首个事件的耗时、事件间隔与每个连接每秒收到的事件数
+    SSE bool
+    // GRPC 表示压测 gRPC 服务，Url 为服务地址（https 使用 TLS），请求消息为 JSON 格式的 Body 或 File，
+    // 结果按 grpc-status 统计状态码
+    GRPC bool
+    // GrpcMethod 表示 gRPC 模式下调用的方法，格式为 package.Service/Method
+    GrpcMethod string
+    // ProtoSet 表示 protoc --descriptor_set_out 生成的描述文件路径，为空时通过服务端反射获取方法的描述
+    ProtoSet string
+    // CompressBody 表示请求体的压缩方式（gzip、br 或 zstd），请求体在初始化时压缩一次
+    CompressBody string
+    // AcceptEncoding 为请求头 Accept-Encoding 的值，指定时统计响应体解压前后的字节数
+    AcceptEncoding string
     // Insecure 跳过 TLS 验证
     Insecure bool
     // Cert 表示客户端 TLS 证书的路径
@@ -65,6 +136,9 @@ type Config struct {
     HttpProxy string
     // SocksProxy 表示 SOCKS 代理地址
     SocksProxy string
+    // UnixSocket 表示 Unix 域套接字路径，不为空时所有连接都建立到该套接字，
+    // Url 中的主机只用于 Host 头，也可以使用 http+unix://%2Frun%2Fapp.sock/path 形式的 Url
+    UnixSocket string
     // Pipeline 如果为 true，将使用 fasthttp PipelineClient
     Pipeline bool
     // Follow 如果为 true，在调试模式下跟随 30x 位置重定向
@@ -74,44 +148,136 @@ type Config struct {
     MaxRedirects int
     // Debug 如果为 true，只发送一次请求并显示请求和响应详情
     Debug bool
+    // Output 表示结果输出格式，为 json 时不启动交互界面，
+    // 压测结束后输出 JSON 格式的结果
+    Output string
+    // OutputFile 表示 JSON 结果的写入文件，为空时写入标准输出
+    OutputFile string
+    // NoTUI 如果为 true，不启动交互界面，定期向标准错误输出进度，
+    // 结束后向标准输出写入纯文本结果
+    NoTUI bool
+    // Asserts 表示压测结束后校验的断言，例如 p99<200ms、error_rate<0.1%，
+    // 任一断言失败时 Run 返回 *AssertionError
+    Asserts []string
 
     throughput int64
+    phases     *phases
+    bodies     *bodyCounter
+    h2         *http2Stats
+    h3         *http3Stats
+    ws         *wsStats
+    sse        *sseStats
+    grpc       *grpcStats
+    feeder     *feeder
     body       []byte
+    // multipart 为 multipart 请求体的 Content-Type，包含分隔符
+    multipart  string
     isTLS      bool
     addr       string
     tlsConf    *tls.Config
 }
 
 func (c *Config) doer() (clientDoer, error) {
+    return c.addrDoer(c.addr, c.isTLS)
+}
+
+// addrDoer 返回连接到 addr 的客户端，场景中每个目标地址各使用一个
+func (c *Config) addrDoer(addr string, isTLS bool) (clientDoer, error) {
+    if c.HTTP2 {
+        return c.newHttp2Doer(addr, isTLS, c.http2Conns()), nil
+    }
+    if c.HTTP3 {
+        return c.newHttp3Doer(addr, isTLS, c.http2Conns())
+    }
     if c.Pipeline {
         return &fasthttp.PipelineClient{
             Name:        "httpgo/" + Version,
-            Addr:        c.addr,
-            Dial:        c.getDialer(),
-            IsTLS:       c.isTLS,
+            Addr:        addr,
+            Dial:        c.getDialer(isTLS),
+            IsTLS:       isTLS,
             TLSConfig:   c.tlsConf,
-            MaxConns:    c.Connections,
+            MaxConns:    c.maxConns(),
             ReadTimeout: c.Timeout,
             Logger:      discardLogger{},
         }, nil
     }
-    return c.hostClient()
+    return c.addrHostClient(addr, isTLS)
 }
 
 func (c *Config) hostClient() (*fasthttp.HostClient, error) {
+    return c.addrHostClient(c.addr, c.isTLS)
+}
+
+func (c *Config) addrHostClient(addr string, isTLS bool) (*fasthttp.HostClient, error) {
     hc := &fasthttp.HostClient{
         Name:        "httpgo/" + Version,
-        Addr:        c.addr,
-        Dial:        c.getDialer(),
-        IsTLS:       c.isTLS,
+        Addr:        addr,
+        Dial:        c.getDialer(isTLS),
+        IsTLS:       isTLS,
         TLSConfig:   c.tlsConf,
-        MaxConns:    c.Connections,
+        MaxConns:    c.maxConns(),
         ReadTimeout: c.Timeout,
     }
+    // 事件流的响应体不会读取完毕，按读取间隔判断超时，见 idleDialer
+    if c.SSE {
+        hc.StreamResponseBody = true
+        hc.ReadTimeout = 0
+    }
 
     return hc, nil
 }
 
+// openModel 判断是否以固定到达速率（开放模型）发起请求
+func (c *Config) openModel() bool {
+    return c.Rate > 0 || (c.Stages != "" && c.StageBy == stageByRate) || (c.Har != "" && c.HarMode != harLoop)
+}
+
+// maxConns 返回客户端的最大连接数，开放模型下与 in-flight 上限一致
+func (c *Config) maxConns() int {
+    if c.openModel() {
+        return c.MaxInFlight
+    }
+    return c.Connections
+}
+
+// unixUrl 将 http+unix://%2Frun%2Fapp.sock/path 形式的 Url 改写为 http://localhost/path，
+// 主机部分转义后的套接字路径保存到 UnixSocket，其他 Url 保持不变
+func (c *Config) unixUrl() error {
+    i := strings.Index(c.Url, "://")
+    if i < 0 || !strings.HasSuffix(c.Url[:i], "+unix") {
+        return nil
+    }
+
+    scheme := strings.TrimSuffix(c.Url[:i], "+unix")
+    if scheme != "http" && scheme != "https" {
+        return fmt.Errorf("unsupported protocol %q. http+unix and https+unix are supported", c.Url[:i])
+    }
+    if c.UnixSocket != "" {
+        return fmt.Errorf("--unix-socket cannot be used with %s+unix urls", scheme)
+    }
+
+    host, path := c.Url[i+3:], "/"
+    if j := strings.IndexAny(host, "/?#"); j >= 0 {
+        host, path = host[:j], host[j:]
+        if path[0] != '/' {
+            path = "/" + path
+        }
+    }
+
+    sock, err := url.PathUnescape(host)
+    if err != nil {
+        return fmt.Errorf("invalid unix socket path %q: %w", host, err)
+    }
+    if sock == "" {
+        return fmt.Errorf("missing unix socket path in %q", c.Url)
+    }
+
+    c.UnixSocket = sock
+    c.Url = scheme + "://localhost" + path
+
+    return nil
+}
+
 func (c *Config) setReqBasic(req *fasthttp.Request) (err error) {
     req.Header.SetMethod(c.Method)
     req.SetRequestURI(c.Url)
@@ -155,131 +321,23 @@ func (c *Config) setReqBody(req *fasthttp.Request) (err error) {
     }
 
     if c.File != "" {
-        c.body, err = os.ReadFile(filepath.Clean(c.File))
-    }
-
-    if !c.Stream {
-        // 设置固定请求体
-        req.SetBody(c.body)
-    }
-
-    return
-}
-
-// parseArgs 从额外参数中获取请求体
-func (c *Config) parseArgs() {
-    if len(c.Args) == 0 {
-        return
-    }
-
-    isJson := true
-    for _, arg := range c.Args {
-        // 检查参数格式有效性
-        if arg == "" {
-            continue
-        }
-
-        formEqualIndex := strings.Index(arg, "=")
-        jsonEqualIndex := strings.Index(arg, ":=")
-        // 没有 "=" 或 "=" 在 ":=" 之前
-        if formEqualIndex == -1 || jsonEqualIndex == -1 || formEqualIndex < jsonEqualIndex {
-            isJson = false
-            break
+        if c.body, err = os.ReadFile(filepath.Clean(c.File)); err != nil {
+            return
         }
     }
 
-    if isJson {
-        c.buildJSONBody()
-    } else {
-        c.buildFormBody()
-    }
-}
-
-// buildJSONBody 构建 JSON 请求体
-func (c *Config) buildJSONBody() {
-    c.JSON = true
-    c.body = append(c.body, '{')
-    for ii, arg := range c.Args {
-        if arg == "" {
-            continue
-        }
-
-        i := strings.Index(arg, ":=")
-        if i == -1 {
-            continue // 跳过无效参数
-        }
-
-        k, v := strings.TrimSpace(arg[:i]), strings.TrimSpace(arg[i+2:])
-        if k == "" {
-            continue // 跳过空键名
-        }
-
-        c.body = append(c.body, '"')
-        c.body = append(c.body, k...)
-        c.body = append(c.body, '"', ':')
-
-        if needQuote(v) {
-            c.body = append(c.body, '"')
-            c.body = append(c.body, v...)
-            c.body = append(c.body, '"')
-        } else {
-            c.body = append(c.body, v...)
-        }
-
-        if ii < len(c.Args)-1 {
-            c.body = append(c.body, ',')
+    if c.CompressBody != "" {
+        if c.body, err = compressBody(c.body, c.CompressBody); err != nil {
+            return
         }
     }
-    c.body = append(c.body, '}')
-}
-
-// buildFormBody 构建表单请求体
-func (c *Config) buildFormBody() {
-    c.Form = true
-    c.Method = fasthttp.MethodPost
-    formArgs := fasthttp.AcquireArgs()
-    defer fasthttp.ReleaseArgs(formArgs)
-
-    for _, arg := range c.Args {
-        if arg == "" {
-            continue
-        }
-
-        i := strings.Index(arg, "=")
-        if i == -1 {
-            formArgs.AddNoValue(strings.TrimSpace(arg))
-        } else {
-            key := strings.TrimSpace(arg[:i])
-            value := strings.TrimSpace(arg[i+1:])
-            if key != "" {
-                formArgs.Add(key, value)
-            }
-        }
-    }
-    c.body = formArgs.AppendBytes(c.body)
-}
 
-func needQuote(v string) bool {
-    if vv := strings.ToLower(v); vv == "false" || vv == "true" {
-        return false
-    }
-    if _, err := strconv.Atoi(v); err == nil {
-        return false
-    }
-    if _, err := strconv.ParseFloat(v, 64); err == nil {
-        return false
-    }
-
-    l := len(v)
-    if l <= 1 {
-        return true
-    }
-
-    if (v[0] == '[' && v[l-1] == ']') || (v[0] == '{' && v[l-1] == '}') {
-        return false
+    if !c.Stream {
+        // 设置固定请求体
+        req.SetBody(c.body)
     }
 
-    return true
+    return
 }
 
 func (c *Config) setReqHeader(req *fasthttp.Request) (err error) {
@@ -300,19 +358,50 @@ func (c *Config) setReqHeader(req *fasthttp.Request) (err error) {
     if c.Form {
         req.Header.SetContentType(mimeApplicationForm)
     }
+    if c.multipart != "" {
+        req.Header.SetContentType(c.multipart)
+    }
+    if c.CompressBody != "" && len(c.body) > 0 {
+        req.Header.Set(fasthttp.HeaderContentEncoding, c.CompressBody)
+    }
+    if c.AcceptEncoding != "" {
+        req.Header.Set(fasthttp.HeaderAcceptEncoding, c.AcceptEncoding)
+    }
+    if c.SSE && len(req.Header.Peek(fasthttp.HeaderAccept)) == 0 {
+        req.Header.Set(fasthttp.HeaderAccept, mimeTextEventStream)
+    }
 
     return
 }
 
-func (c *Config) getDialer() fasthttp.DialFunc {
+func (c *Config) getDialer(isTLS bool) fasthttp.DialFunc {
+    dial := c.getConnDialer()
+    if isTLS {
+        dial = tlsDialer(dial, c.tlsConf, c.Timeout, c.phases)
+    }
+    // 管道模式下同一连接上的请求并发收发，无法区分各请求的 TTFB
+    if c.phases != nil && !c.Pipeline {
+        dial = traceDialer(dial, c.phases)
+    }
+    if c.SSE {
+        dial = idleDialer(dial, c.Timeout)
+    }
+
+    return dial
+}
+
+func (c *Config) getConnDialer() fasthttp.DialFunc {
+    if c.UnixSocket != "" {
+        return unixDialer(&c.throughput, c.UnixSocket, c.Timeout, c.phases)
+    }
     if c.HttpProxy != "" {
-        return httpProxyDialer(&c.throughput, c.HttpProxy, c.Timeout)
+        return httpProxyDialer(&c.throughput, c.HttpProxy, c.Timeout, c.phases)
     }
     if c.SocksProxy != "" {
-        return httpSocksProxyDialer(&c.throughput, c.SocksProxy)
+        return httpSocksProxyDialer(&c.throughput, c.SocksProxy, c.phases)
     }
 
-    return httpDialer(&c.throughput, c.Timeout)
+    return httpDialer(&c.throughput, c.Timeout, c.phases)
 }
 
 /* #nosec G402 */
@@ -1,6 +1,9 @@
 package pkg
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,42 +39,102 @@ func Test_Config_setReqBasic(t *testing.T) {
 func Test_Config_parseArgs(t *testing.T) {
 	t.Parallel()
 
+	dir := t.TempDir()
+	text := filepath.Join(dir, "bio.txt")
+	assert.Nil(t, os.WriteFile(text, []byte("line1\nline \"2\""), 0o600))
+	data := filepath.Join(dir, "tags.json")
+	assert.Nil(t, os.WriteFile(data, []byte("[\n  \"a\",\n  \"b\"\n]\n"), 0o600))
+
 	testCases := []struct {
 		name     string
 		args     []string
-		isForm   bool
+		form     bool
 		isJson   bool
 		expected string
 	}{
-		{"form no value", []string{"foo"}, true, false, "foo"},
 		{"form one kv", []string{" foo=bar"}, true, false, "foo=bar"},
-		{"form two kv", []string{"foo=bar ", "bar=baz"}, true, false, "foo=bar&bar=baz"},
+		{"form two kv", []string{"foo=bar", "bar=baz"}, true, false, "foo=bar&bar=baz"},
 		{"form escape kv", []string{"foo=bar=baz"}, true, false, "foo=bar%3Dbaz"},
-		{"json no value", []string{"foo:="}, false, true, `{"foo":""}`},
+		{"json string value", []string{"foo=bar"}, false, true, `{"foo":"bar"}`},
+		{"json empty string value", []string{"foo="}, false, true, `{"foo":""}`},
 		{"json bool true value", []string{" foo:=true"}, false, true, `{"foo":true}`},
-		{"json bool false value", []string{"foo:= False"}, false, true, `{"foo":False}`},
 		{"json int value", []string{"foo:=1 "}, false, true, `{"foo":1}`},
 		{"json float value", []string{"foo:=1.1"}, false, true, `{"foo":1.1}`},
-		{"json array value", []string{"foo :=[1]"}, false, true, `{"foo":[1]}`},
+		{"json array value", []string{"foo :=[1, 2]"}, false, true, `{"foo":[1,2]}`},
 		{"json object value", []string{`foo:={"bar":"baz"}`}, false, true, `{"foo":{"bar":"baz"}}`},
-		{"json string value", []string{`foo:= baz`}, false, true, `{"foo":"baz"}`},
-		{"json invalid bool tru value", []string{"foo:=tru"}, false, true, `{"foo":"tru"}`},
-		{"json invalid bool false value", []string{"foo:=fals"}, false, true, `{"foo":"fals"}`},
-		{"json invalid int value", []string{"foo:=1aa"}, false, true, `{"foo":"1aa"}`},
-		{"json invalid float value", []string{"foo:=1.1.1"}, false, true, `{"foo":"1.1.1"}`},
 		{"json two kv", []string{"foo:=true", "bar:=1"}, false, true, `{"foo":true,"bar":1}`},
+		{"json mixed kv", []string{"name=bob", "age:=25"}, false, true, `{"name":"bob","age":25}`},
+		{"json escape", []string{`q"uote=a"b\c<d>`, "line=a\nb"}, false, true, `{"q\"uote":"a\"b\\c<d>","line":"a\nb"}`},
+		{"json escaped separator", []string{`a\=b=c`, `x\:y=z`}, false, true, `{"a=b":"c","x:y":"z"}`},
+		{"json nested", []string{"user[name]=bob", "user[tags][]=a", "user[tags][]=b", "user[age]:=3"}, false, true,
+			`{"user":{"name":"bob","tags":["a","b"],"age":3}}`},
+		{"json nested index", []string{"a[2]=x", "a[0][b]:=null"}, false, true, `{"a":[{"b":null},null,"x"]}`},
+		{"json top level array", []string{"[]:=1", "[]=two"}, false, true, `[1,"two"]`},
+		{"json escaped bracket", []string{`a\[b\]=1`, `m[\1]=2`}, false, true, `{"a[b]":"1","m":{"1":"2"}}`},
+		{"json files", []string{"bio=@" + text, "tags:=@" + data}, false, true, `{"bio":"line1\nline \"2\"","tags":["a","b"]}`},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			c, _ := configAndReq()
+			c.Form = tc.form
 			c.Args = tc.args
-			c.parseArgs()
-			assert.Equal(t, tc.isForm, c.Form)
+			assert.Nil(t, c.parseArgs())
 			assert.Equal(t, tc.isJson, c.JSON)
 			assert.Equal(t, tc.expected, string(c.body))
+			if tc.form {
+				assert.Equal(t, fasthttp.MethodPost, c.Method)
+			}
 		})
 	}
+
+	t.Run("query and header", func(t *testing.T) {
+		c, _ := configAndReq()
+		c.Url = "http://example.com/search?a=1"
+		c.Args = []string{"q==go lang", "page==2", "X-Token:abc", "Referer: http://example.com/"}
+		assert.Nil(t, c.parseArgs())
+		assert.Equal(t, "http://example.com/search?a=1&q=go+lang&page=2", c.Url)
+		assert.Equal(t, []string{"X-Token: abc", "Referer: http://example.com/"}, c.Headers)
+		assert.False(t, c.JSON)
+		assert.Empty(t, c.body)
+	})
+
+	t.Run("form upload", func(t *testing.T) {
+		c, _ := configAndReq()
+		c.Form = true
+		c.Args = []string{"name=bob", "bio=@" + text + ";type=text/markdown"}
+		assert.Nil(t, c.parseArgs())
+		assert.True(t, strings.HasPrefix(c.multipart, "multipart/form-data; boundary="))
+		assert.True(t, c.Stream)
+		assert.Equal(t, fasthttp.MethodPost, c.Method)
+		assert.Contains(t, string(c.body), "Content-Type: text/markdown\r\n\r\nline1\nline \"2\"\r\n")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			args []string
+			form bool
+			body string
+		}{
+			{args: []string{"foo"}},
+			{args: []string{"foo:=tru"}},
+			{args: []string{"foo:="}},
+			{args: []string{":value"}},
+			{args: []string{"=value"}},
+			{args: []string{"a[b=1"}},
+			{args: []string{"a[b]c=1"}},
+			{args: []string{"a=1", "a[b]=2"}},
+			{args: []string{"a[]=1", "a[b]=2"}},
+			{args: []string{"a=@missing.txt"}},
+			{args: []string{"a=@missing.txt"}, form: true},
+			{args: []string{"a:=1"}, form: true},
+			{args: []string{"a=1"}, body: "raw"},
+		} {
+			c, _ := configAndReq()
+			c.Args, c.Form, c.Body = tc.args, tc.form, tc.body
+			assert.NotNil(t, c.parseArgs(), tc.args)
+		}
+	})
 }
 
 func Test_Config_setReqBody(t *testing.T) {
@@ -157,6 +220,33 @@ func configAndReq() (*Config, *fasthttp.Request) {
 	return &Config{}, &fasthttp.Request{}
 }
 
+func Test_Config_unixUrl(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		url, want, sock string
+	}{
+		{"http://example.com/a", "http://example.com/a", ""},
+		{"http+unix://%2Frun%2Fapp.sock", "http://localhost/", "/run/app.sock"},
+		{"http+unix://%2Frun%2Fapp.sock/a/b?x=1", "http://localhost/a/b?x=1", "/run/app.sock"},
+		{"https+unix://app.sock?x=1", "https://localhost/?x=1", "app.sock"},
+	}
+	for _, tc := range cases {
+		c := &Config{Url: tc.url}
+		assert.Nil(t, c.unixUrl())
+		assert.Equal(t, tc.want, c.Url)
+		assert.Equal(t, tc.sock, c.UnixSocket)
+	}
+
+	for _, u := range []string{"ws+unix://%2Fapp.sock", "http+unix:///path", "http+unix://%zz/"} {
+		c := &Config{Url: u}
+		assert.NotNil(t, c.unixUrl(), u)
+	}
+
+	c := &Config{Url: "http+unix://%2Fa.sock/", UnixSocket: "/b.sock"}
+	assert.NotNil(t, c.unixUrl())
+}
+
 func Test_getMaxRedirects(t *testing.T) {
 	t.Parallel()
 
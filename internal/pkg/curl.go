@@ -0,0 +1,532 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// curlOption 描述 curl 的一个选项，names 包含短选项与长选项
+type curlOption struct {
+	names  []string
+	hasArg bool
+	apply  func(p *curlParser, v string) error
+}
+
+// curlParser 保存解析 curl 命令行过程中的状态
+type curlParser struct {
+	c       *Config
+	method  string
+	url     string
+	data    []string
+	file    string
+	get     bool
+	head    bool
+	headers []string
+}
+
+var curlOptions = []curlOption{
+	{names: []string{"-X", "--request"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.method = strings.ToUpper(v)
+		return nil
+	}},
+	{names: []string{"--url"}, hasArg: true, apply: (*curlParser).setUrl},
+	{names: []string{"-H", "--header"}, hasArg: true, apply: (*curlParser).addHeader},
+	{names: []string{"-d", "--data", "--data-ascii"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		return p.addData(v, true, true)
+	}},
+	{names: []string{"--data-raw"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		return p.addData(v, false, false)
+	}},
+	{names: []string{"--data-binary"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		return p.addData(v, true, false)
+	}},
+	{names: []string{"--data-urlencode"}, hasArg: true, apply: (*curlParser).addDataUrlencode},
+	{names: []string{"--json"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.headers = append(p.headers, "Content-Type: "+mimeApplicationJSON, "Accept: "+mimeApplicationJSON)
+		return p.addData(v, true, false)
+	}},
+	{names: []string{"-G", "--get"}, apply: func(p *curlParser, _ string) error {
+		p.get = true
+		return nil
+	}},
+	{names: []string{"-I", "--head"}, apply: func(p *curlParser, _ string) error {
+		p.head = true
+		return nil
+	}},
+	{names: []string{"-u", "--user"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		if !strings.Contains(v, ":") {
+			return fmt.Errorf("curl option -u needs user:password, got %q", v)
+		}
+		p.headers = append(p.headers, "Authorization: Basic "+base64.StdEncoding.EncodeToString([]byte(v)))
+		return nil
+	}},
+	{names: []string{"-A", "--user-agent"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.headers = append(p.headers, "User-Agent: "+v)
+		return nil
+	}},
+	{names: []string{"-e", "--referer"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.headers = append(p.headers, "Referer: "+v)
+		return nil
+	}},
+	{names: []string{"-b", "--cookie"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		if !strings.Contains(v, "=") {
+			return fmt.Errorf("curl cookie file %q is not supported", v)
+		}
+		p.headers = append(p.headers, "Cookie: "+v)
+		return nil
+	}},
+	{names: []string{"--compressed"}, apply: func(p *curlParser, _ string) error {
+		p.headers = append(p.headers, "Accept-Encoding: gzip, deflate, br")
+		return nil
+	}},
+	{names: []string{"-k", "--insecure"}, apply: func(p *curlParser, _ string) error {
+		p.c.Insecure = true
+		return nil
+	}},
+	{names: []string{"-E", "--cert"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.c.Cert = v
+		return nil
+	}},
+	{names: []string{"--key"}, hasArg: true, apply: func(p *curlParser, v string) error {
+		p.c.Key = v
+		return nil
+	}},
+	{names: []string{"-x", "--proxy"}, hasArg: true, apply: (*curlParser).setProxy},
+	{names: []string{"-L", "--location"}, apply: func(p *curlParser, _ string) error {
+		p.c.Follow = true
+		return nil
+	}},
+	// 以下选项只影响 curl 自身的输出与超时，忽略即可
+	{names: []string{"-s", "--silent", "-S", "--show-error", "-v", "--verbose", "-i", "--include",
+		"-g", "--globoff", "-N", "--no-buffer", "--http1.1", "-#", "--progress-bar", "--fail"}},
+	{names: []string{"-o", "--output", "-w", "--write-out", "-m", "--max-time", "--connect-timeout"}, hasArg: true},
+}
+
+func lookupCurlOption(name string) (curlOption, bool) {
+	for _, o := range curlOptions {
+		for _, n := range o.names {
+			if n == name {
+				return o, true
+			}
+		}
+	}
+	return curlOption{}, false
+}
+
+// ParseCurl 将 curl 命令行参数（可以 curl 开头）解析为请求配置，
+// 支持 -X、-H、-d/--data-raw/--data-binary、-u、-k、--compressed、
+// --cert/--key、-x 等常用选项，不支持的选项返回错误
+func (c *Config) ParseCurl(args []string) (err error) {
+	if len(args) > 0 && isCurlCommand(args[0]) {
+		args = args[1:]
+	}
+
+	p := &curlParser{c: c}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' {
+			if err = p.setUrl(arg); err != nil {
+				return
+			}
+			continue
+		}
+
+		if arg[1] == '-' {
+			if i, _, err = p.apply(args, i, arg, ""); err != nil {
+				return
+			}
+			continue
+		}
+
+		// 多个短选项可以合并（-sSL），带值的短选项的值可以紧跟在选项后（-XPOST）
+		for j := 1; j < len(arg); j++ {
+			var hasArg bool
+			if i, hasArg, err = p.apply(args, i, "-"+arg[j:j+1], arg[j+1:]); err != nil {
+				return
+			}
+			if hasArg {
+				break
+			}
+		}
+	}
+
+	return p.finish()
+}
+
+// apply 执行名为 name 的选项，带值的选项优先使用 attached，否则取下一个参数，
+// 返回最后使用的参数位置
+func (p *curlParser) apply(args []string, i int, name, attached string) (int, bool, error) {
+	o, ok := lookupCurlOption(name)
+	if !ok {
+		return i, false, fmt.Errorf("unsupported curl option %q", name)
+	}
+
+	v := attached
+	if o.hasArg && v == "" {
+		if i+1 >= len(args) {
+			return i, true, fmt.Errorf("curl option %q needs a value", name)
+		}
+		i++
+		v = args[i]
+	}
+	if o.apply != nil {
+		if err := o.apply(p, v); err != nil {
+			return i, o.hasArg, err
+		}
+	}
+
+	return i, o.hasArg, nil
+}
+
+func isCurlCommand(s string) bool {
+	name := strings.ToLower(filepath.Base(s))
+	return name == "curl" || name == "curl.exe"
+}
+
+func (p *curlParser) setUrl(v string) error {
+	if p.url != "" {
+		return errors.New("multiple URLs in curl command are not supported")
+	}
+	if v == "" {
+		return errors.New("empty URL in curl command")
+	}
+	p.url = v
+	return nil
+}
+
+func (p *curlParser) addHeader(v string) error {
+	i := strings.Index(v, ":")
+	if i <= 0 {
+		return fmt.Errorf("invalid curl header %q", v)
+	}
+	// curl 中 "Name:" 表示去掉内置的请求头
+	if strings.TrimSpace(v[i+1:]) == "" {
+		return nil
+	}
+	p.headers = append(p.headers, v)
+	return nil
+}
+
+// addData 添加一段请求体，readFile 表示 @ 开头时读取文件，
+// stripNewlines 表示按 -d 的方式去掉文件中的换行
+func (p *curlParser) addData(v string, readFile, stripNewlines bool) error {
+	if readFile && strings.HasPrefix(v, "@") {
+		path := v[1:]
+		// 只有一段二进制数据时保留文件，发送时再读取
+		if !stripNewlines && len(p.data) == 0 && p.file == "" {
+			p.file = path
+			return nil
+		}
+		b, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return err
+		}
+		if stripNewlines {
+			b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r"), nil), []byte("\n"), nil)
+		}
+		v = string(b)
+	}
+	if err := p.inlineFile(); err != nil {
+		return err
+	}
+	p.data = append(p.data, v)
+	return nil
+}
+
+// inlineFile 存在多段数据时将之前保留的文件读入请求体
+func (p *curlParser) inlineFile() error {
+	if p.file == "" {
+		return nil
+	}
+	b, err := os.ReadFile(filepath.Clean(p.file))
+	if err != nil {
+		return err
+	}
+	p.file = ""
+	p.data = append(p.data, string(b))
+	return nil
+}
+
+// addDataUrlencode 支持 content、=content、name=content、@file 与 name@file
+func (p *curlParser) addDataUrlencode(v string) error {
+	name, content := "", v
+	if i := strings.IndexAny(v, "=@"); i >= 0 {
+		name, content = v[:i], v[i+1:]
+		if v[i] == '@' {
+			b, err := os.ReadFile(filepath.Clean(content))
+			if err != nil {
+				return err
+			}
+			content = string(b)
+		}
+	}
+
+	content = url.QueryEscape(content)
+	if name != "" {
+		content = name + "=" + content
+	}
+	return p.addData(content, false, false)
+}
+
+// setProxy 将 [scheme://][user:password@]host:port 格式的代理
+// 转换为 HttpProxy 或 SocksProxy
+func (p *curlParser) setProxy(v string) error {
+	scheme, rest, ok := strings.Cut(v, "://")
+	if !ok {
+		scheme, rest = "http", v
+	}
+	switch strings.ToLower(scheme) {
+	case "http":
+		p.c.HttpProxy = strings.TrimSuffix(rest, "/")
+	case "socks5", "socks5h":
+		p.c.SocksProxy = v
+	default:
+		return fmt.Errorf("unsupported curl proxy %q. http and socks5 proxies are supported", v)
+	}
+	return nil
+}
+
+func (p *curlParser) finish() error {
+	if p.url == "" {
+		return errors.New("missing URL in curl command")
+	}
+	// curl 默认使用 http
+	if !strings.Contains(p.url, "://") {
+		p.url = "http://" + p.url
+	}
+
+	data := strings.Join(p.data, "&")
+	method := fasthttp.MethodGet
+	switch {
+	case p.head:
+		method = fasthttp.MethodHead
+	case p.get:
+		if data != "" {
+			sep := "?"
+			if strings.Contains(p.url, "?") {
+				sep = "&"
+			}
+			p.url += sep + data
+		}
+		data = ""
+	case data != "" || p.file != "":
+		method = fasthttp.MethodPost
+		if !p.hasHeader("Content-Type") {
+			p.headers = append(p.headers, "Content-Type: "+mimeApplicationForm)
+		}
+	}
+	if p.method != "" {
+		method = p.method
+	}
+
+	p.c.Url = p.url
+	p.c.Method = method
+	p.c.Headers = append(p.c.Headers, p.headers...)
+	p.c.Body = data
+	if !p.get {
+		p.c.File = p.file
+	}
+
+	return nil
+}
+
+func (p *curlParser) hasHeader(name string) bool {
+	for _, h := range p.headers {
+		if k, _, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(k), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitCommand 按 shell 规则拆分命令行，支持单引号、双引号、$'...'、
+// 反斜杠转义与续行，用于解析从浏览器复制的 curl 命令
+func SplitCommand(s string) (args []string, err error) {
+	var (
+		cur    strings.Builder
+		inWord bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		case ch == '\\':
+			if i+1 < len(s) {
+				i++
+				// 反斜杠加换行为续行
+				if s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+				if s[i] != '\n' && s[i] != '\r' {
+					cur.WriteByte(s[i])
+					inWord = true
+				}
+			}
+		case ch == '\'':
+			j := strings.IndexByte(s[i+1:], '\'')
+			if j < 0 {
+				return nil, errors.New("unclosed single quote in command")
+			}
+			cur.WriteString(s[i+1 : i+1+j])
+			i += j + 1
+			inWord = true
+		case ch == '"':
+			if i, err = readDoubleQuoted(s, i+1, &cur); err != nil {
+				return nil, err
+			}
+			inWord = true
+		case ch == '$' && i+1 < len(s) && s[i+1] == '\'':
+			if i, err = readAnsiQuoted(s, i+2, &cur); err != nil {
+				return nil, err
+			}
+			inWord = true
+		default:
+			cur.WriteByte(ch)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+
+	return
+}
+
+// readDoubleQuoted 读取双引号中的内容，返回右引号的位置
+func readDoubleQuoted(s string, i int, cur *strings.Builder) (int, error) {
+	for ; i < len(s); i++ {
+		switch ch := s[i]; ch {
+		case '"':
+			return i, nil
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte("\"\\$`\n", s[i+1]) >= 0 {
+				i++
+				if s[i] != '\n' {
+					cur.WriteByte(s[i])
+				}
+				continue
+			}
+			cur.WriteByte(ch)
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	return i, errors.New("unclosed double quote in command")
+}
+
+// readAnsiQuoted 读取 $'...' 中的内容并处理转义，返回右引号的位置
+func readAnsiQuoted(s string, i int, cur *strings.Builder) (int, error) {
+	for ; i < len(s); i++ {
+		ch := s[i]
+		if ch == '\'' {
+			return i, nil
+		}
+		if ch != '\\' || i+1 >= len(s) {
+			cur.WriteByte(ch)
+			continue
+		}
+
+		i++
+		switch e := s[i]; e {
+		case 'n':
+			cur.WriteByte('\n')
+		case 'r':
+			cur.WriteByte('\r')
+		case 't':
+			cur.WriteByte('\t')
+		case 'x', 'u', 'U':
+			size := 2
+			if e == 'u' {
+				size = 4
+			} else if e == 'U' {
+				size = 8
+			}
+			j := i + 1
+			for j < len(s) && j < i+1+size && isHexDigit(s[j]) {
+				j++
+			}
+			if j == i+1 {
+				cur.WriteByte('\\')
+				cur.WriteByte(e)
+				continue
+			}
+			n, _ := strconv.ParseUint(s[i+1:j], 16, 32)
+			if e == 'x' {
+				cur.WriteByte(byte(n))
+			} else {
+				cur.WriteRune(rune(n))
+			}
+			i = j - 1
+		default:
+			// \\、\'、\" 等取字符本身
+			cur.WriteByte(e)
+		}
+	}
+	return i, errors.New("unclosed $' quote in command")
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+// Command 返回与请求配置等价的 httpgo 命令行，只包含请求相关的参数
+func (c *Config) Command() string {
+	args := []string{"httpgo"}
+	if c.Method != "" && c.Method != fasthttp.MethodGet {
+		args = append(args, "-X", c.Method)
+	}
+	args = append(args, shellQuote(c.Url))
+	for _, h := range c.Headers {
+		args = append(args, "-H", shellQuote(h))
+	}
+	if c.Body != "" {
+		args = append(args, "-b", shellQuote(c.Body))
+	}
+	if c.File != "" {
+		args = append(args, "-f", shellQuote(c.File))
+	}
+	if c.Insecure {
+		args = append(args, "-k")
+	}
+	if c.Cert != "" {
+		args = append(args, "--cert", shellQuote(c.Cert))
+	}
+	if c.Key != "" {
+		args = append(args, "--key", shellQuote(c.Key))
+	}
+	if c.HttpProxy != "" {
+		args = append(args, "--httpProxy", shellQuote(c.HttpProxy))
+	}
+	if c.SocksProxy != "" {
+		args = append(args, "--socksProxy", shellQuote(c.SocksProxy))
+	}
+	if c.Follow {
+		args = append(args, "--follow")
+	}
+	return strings.Join(args, " ")
+}
+
+// shellQuote 在需要时用单引号包裹参数
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=@%+,", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
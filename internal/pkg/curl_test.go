@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SplitCommand(t *testing.T) {
+	t.Parallel()
+
+	args, err := SplitCommand(`curl 'https://example.com/a?b=1' \
+  -H 'accept: */*' -H "x-name: \"bob\" \$HOME" \
+  --data-raw $'{"a":"it\'s\\n\x41é"}' --compressed`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"curl", "https://example.com/a?b=1",
+		"-H", "accept: */*", "-H", `x-name: "bob" $HOME`,
+		"--data-raw", `{"a":"it's\nAé"}`, "--compressed",
+	}, args)
+
+	args, err = SplitCommand("a\\ b c''d \"\"")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a b", "cd", ""}, args)
+
+	for _, s := range []string{`'a`, `"a`, `$'a`} {
+		_, err = SplitCommand(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func Test_Config_ParseCurl(t *testing.T) {
+	t.Parallel()
+
+	t.Run("devtools", func(t *testing.T) {
+		c := &Config{Method: "GET", Headers: []string{"X-Extra: 1"}}
+		err := c.ParseCurl([]string{
+			"curl", "https://example.com/api", "-XPUT",
+			"-H", "Content-Type: application/json", "-H", "Referer: https://example.com/",
+			"-H", "Accept:", "--data-raw", `{"a":1}`, "-u", "user:pass", "-sSkL",
+			"--compressed", "--cert", "c.pem", "--key", "k.pem", "-x", "http://u:p@127.0.0.1:8080",
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "https://example.com/api", c.Url)
+		assert.Equal(t, "PUT", c.Method)
+		assert.Equal(t, `{"a":1}`, c.Body)
+		assert.Equal(t, []string{
+			"X-Extra: 1",
+			"Content-Type: application/json",
+			"Referer: https://example.com/",
+			"Authorization: Basic dXNlcjpwYXNz",
+			"Accept-Encoding: gzip, deflate, br",
+		}, c.Headers)
+		assert.True(t, c.Insecure)
+		assert.True(t, c.Follow)
+		assert.Equal(t, "c.pem", c.Cert)
+		assert.Equal(t, "k.pem", c.Key)
+		assert.Equal(t, "u:p@127.0.0.1:8080", c.HttpProxy)
+	})
+
+	t.Run("data", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "body.txt")
+		assert.Nil(t, os.WriteFile(path, []byte("x=1\ny=2\n"), 0o600))
+
+		c := &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"example.com", "-d", "a=1", "-d", "@" + path, "--data-urlencode", "q=a b"}))
+		assert.Equal(t, "http://example.com", c.Url)
+		assert.Equal(t, "POST", c.Method)
+		assert.Equal(t, "a=1&x=1y=2&q=a+b", c.Body)
+		assert.Equal(t, []string{"Content-Type: " + mimeApplicationForm}, c.Headers)
+
+		c = &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"example.com", "--data-binary", "@" + path}))
+		assert.Equal(t, path, c.File)
+		assert.Equal(t, "", c.Body)
+
+		c = &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"example.com", "--data-binary", "@" + path, "-d", "z=3"}))
+		assert.Equal(t, "", c.File)
+		assert.Equal(t, "x=1\ny=2\n&z=3", c.Body)
+
+		c = &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"https://example.com/s?a=1", "-G", "-d", "q=go"}))
+		assert.Equal(t, "https://example.com/s?a=1&q=go", c.Url)
+		assert.Equal(t, "GET", c.Method)
+		assert.Equal(t, "", c.Body)
+
+		c = &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"example.com", "--json", `{"a":1}`}))
+		assert.Equal(t, "POST", c.Method)
+		assert.Equal(t, []string{"Content-Type: application/json", "Accept: application/json"}, c.Headers)
+
+		c = &Config{}
+		assert.Nil(t, c.ParseCurl([]string{"example.com", "-I", "-x", "socks5://127.0.0.1:1080", "-b", "a=1; b=2"}))
+		assert.Equal(t, "HEAD", c.Method)
+		assert.Equal(t, "socks5://127.0.0.1:1080", c.SocksProxy)
+		assert.Equal(t, []string{"Cookie: a=1; b=2"}, c.Headers)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, args := range [][]string{
+			{"curl"},
+			{"curl", "a", "b"},
+			{"curl", "a", "--unknown"},
+			{"curl", "a", "-Z"},
+			{"curl", "a", "-H"},
+			{"curl", "a", "-H", "bad"},
+			{"curl", "a", "-u", "user"},
+			{"curl", "a", "-b", "cookies.txt"},
+			{"curl", "a", "-x", "https://proxy:443"},
+			{"curl", "a", "-d", "@missing.txt"},
+		} {
+			assert.NotNil(t, (&Config{}).ParseCurl(args), args)
+		}
+	})
+}
+
+func Test_Config_Command(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		Method:     "POST",
+		Url:        "https://example.com/a?b=1&c=2",
+		Headers:    []string{"Content-Type: application/json"},
+		Body:       `{"name":"it's"}`,
+		File:       "body.bin",
+		Insecure:   true,
+		Cert:       "c.pem",
+		Key:        "k.pem",
+		HttpProxy:  "127.0.0.1:8080",
+		SocksProxy: "socks5://127.0.0.1:1080",
+		Follow:     true,
+	}
+	assert.Equal(t, `httpgo -X POST 'https://example.com/a?b=1&c=2' -H 'Content-Type: application/json' `+
+		`-b '{"name":"it'\''s"}' -f body.bin -k --cert c.pem --key k.pem --httpProxy 127.0.0.1:8080 `+
+		`--socksProxy socks5://127.0.0.1:1080 --follow`, c.Command())
+
+	assert.Equal(t, "httpgo http://example.com", (&Config{Method: "GET", Url: "http://example.com"}).Command())
+}
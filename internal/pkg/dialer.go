@@ -2,11 +2,14 @@ package pkg
 
 import (
     "bufio"
+    "context"
+    "crypto/tls"
     "encoding/base64"
     "fmt"
     "net"
     "net/url"
     "strings"
+    "sync"
     "sync/atomic"
     "time"
 
@@ -17,35 +20,159 @@ import (
 type counterConn struct {
     net.Conn
     n *int64
+    // idle 不为 0 时每次读取前将读超时设置为 idle 之后，用于长时间保持打开的响应
+    idle time.Duration
+
+    // trace 不为空时记录每次请求的 TTFB 与 Transfer 耗时，
+    // 要求连接同一时刻只处理一个请求
+    trace     *phases
+    reading   bool
+    writeEnd  time.Time
+    firstRead time.Time
+    lastRead  time.Time
 }
 
 func (cc *counterConn) Read(b []byte) (n int, err error) {
+    if cc.idle > 0 {
+        _ = cc.Conn.SetReadDeadline(time.Now().Add(cc.idle))
+    }
+
     n, err = cc.Conn.Read(b)
 
     if err == nil {
         atomic.AddInt64(cc.n, int64(n))
     }
 
+    if cc.trace != nil && n > 0 {
+        now := time.Now()
+        if !cc.reading && !cc.writeEnd.IsZero() {
+            observe(cc.trace.ttfb, now.Sub(cc.writeEnd))
+            cc.reading = true
+            cc.firstRead = now
+        }
+        cc.lastRead = now
+    }
+
     return
 }
 
 func (cc *counterConn) Write(b []byte) (n int, err error) {
+    if cc.trace != nil && cc.reading {
+        cc.endExchange()
+    }
+
     n, err = cc.Conn.Write(b)
 
     if err == nil {
         atomic.AddInt64(cc.n, int64(n))
     }
 
+    if cc.trace != nil {
+        cc.writeEnd = time.Now()
+    }
+
     return
 }
 
-var httpDialer = func(throughput *int64, timeout time.Duration) func(string) (net.Conn, error) {
+func (cc *counterConn) Close() error {
+    if cc.trace != nil && cc.reading {
+        cc.endExchange()
+    }
+    return cc.Conn.Close()
+}
+
+// endExchange 在下一次写入或连接关闭时记录上一个响应的传输耗时
+func (cc *counterConn) endExchange() {
+    observe(cc.trace.transfer, cc.lastRead.Sub(cc.firstRead))
+    cc.reading = false
+}
+
+// dnsCacheDuration 与 fasthttp.DefaultDNSCacheDuration 保持一致
+const dnsCacheDuration = time.Minute
+
+type dnsEntry struct {
+    ips      []net.IPAddr
+    idx      uint32
+    resolved time.Time
+}
+
+// dnsCache 缓存域名解析结果，只有真正发起解析时才记录 DNS 耗时
+type dnsCache struct {
+    entries sync.Map
+}
+
+func (d *dnsCache) lookup(host string, deadline time.Time, ph *phases) (net.IP, error) {
+    if v, ok := d.entries.Load(host); ok {
+        e := v.(*dnsEntry)
+        if time.Since(e.resolved) < dnsCacheDuration {
+            i := atomic.AddUint32(&e.idx, 1)
+            return e.ips[int(i)%len(e.ips)].IP, nil
+        }
+    }
+
+    ctx, cancel := context.WithDeadline(context.Background(), deadline)
+    defer cancel()
+
+    start := time.Now()
+    ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+    if err != nil {
+        return nil, err
+    }
+    if len(ips) == 0 {
+        return nil, fmt.Errorf("no such host %s", host)
+    }
+    if ph != nil {
+        observe(ph.dns, time.Since(start))
+    }
+
+    d.entries.Store(host, &dnsEntry{ips: ips, resolved: time.Now()})
+
+    return ips[0].IP, nil
+}
+
+var httpDialer = func(throughput *int64, timeout time.Duration, ph *phases) func(string) (net.Conn, error) {
     dialer := &fasthttp.TCPDialer{}
+    resolver := &dnsCache{}
     return func(address string) (net.Conn, error) {
-        conn, err := dialer.DialDualStackTimeout(address, timeout)
+        deadline := time.Now().Add(timeout)
+        if host, port, err := net.SplitHostPort(address); err == nil && net.ParseIP(host) == nil {
+            ip, err := resolver.lookup(host, deadline, ph)
+            if err != nil {
+                return nil, err
+            }
+            address = net.JoinHostPort(ip.String(), port)
+        }
+
+        start := time.Now()
+        conn, err := dialer.DialDualStackTimeout(address, time.Until(deadline))
+        if err != nil {
+            return nil, err
+        }
+        if ph != nil {
+            observe(ph.connect, time.Since(start))
+        }
+
+        cc := &counterConn{
+            Conn: conn,
+            n:    throughput,
+        }
+
+        return cc, nil
+    }
+}
+
+// unixDialer 忽略请求的目标地址，总是连接到 Unix 域套接字 path，
+// Host 头仍取自请求，与连接的地址无关
+func unixDialer(throughput *int64, path string, timeout time.Duration, ph *phases) fasthttp.DialFunc {
+    return func(_ string) (net.Conn, error) {
+        start := time.Now()
+        conn, err := net.DialTimeout("unix", path, timeout)
         if err != nil {
             return nil, err
         }
+        if ph != nil {
+            observe(ph.connect, time.Since(start))
+        }
 
         cc := &counterConn{
             Conn: conn,
@@ -56,7 +183,105 @@ var httpDialer = func(throughput *int64, timeout time.Duration) func(string) (ne
     }
 }
 
-func httpProxyDialer(throughput *int64, proxy string, timeout time.Duration) fasthttp.DialFunc {
+// tlsDialer 在 dial 返回的连接上完成 TLS 握手并记录握手耗时，
+// fasthttp 会将实现了 Handshake 方法的连接视为已建立的 TLS 连接
+func tlsDialer(dial fasthttp.DialFunc, conf *tls.Config, timeout time.Duration, ph *phases) fasthttp.DialFunc {
+    if conf == nil {
+        conf = &tls.Config{} // #nosec G402
+    }
+    sessionCache := tls.NewLRUClientSessionCache(0)
+
+    var confs sync.Map
+    confForAddr := func(addr string) *tls.Config {
+        if v, ok := confs.Load(addr); ok {
+            return v.(*tls.Config)
+        }
+        c := conf.Clone()
+        if c.ClientSessionCache == nil {
+            c.ClientSessionCache = sessionCache
+        }
+        if c.ServerName == "" {
+            host, _, err := net.SplitHostPort(addr)
+            if err != nil {
+                host = addr
+            }
+            c.ServerName = host
+        }
+        confs.Store(addr, c)
+        return c
+    }
+
+    return func(addr string) (net.Conn, error) {
+        conn, err := dial(addr)
+        if err != nil {
+            return nil, err
+        }
+
+        tc := tls.Client(conn, confForAddr(addr))
+        if timeout > 0 {
+            _ = tc.SetDeadline(time.Now().Add(timeout))
+        }
+
+        start := time.Now()
+        if err = tc.Handshake(); err != nil {
+            _ = conn.Close()
+            if ne, ok := err.(net.Error); ok && ne.Timeout() {
+                return nil, fasthttp.ErrTLSHandshakeTimeout
+            }
+            return nil, err
+        }
+        if ph != nil {
+            observe(ph.tls, time.Since(start))
+        }
+
+        _ = tc.SetDeadline(time.Time{})
+
+        return tc, nil
+    }
+}
+
+// traceDialer 在连接建立完成后开启 TTFB 与 Transfer 的记录
+func traceDialer(dial fasthttp.DialFunc, ph *phases) fasthttp.DialFunc {
+    return func(addr string) (net.Conn, error) {
+        conn, err := dial(addr)
+        if err != nil {
+            return nil, err
+        }
+
+        raw := conn
+        if tc, ok := conn.(*tls.Conn); ok {
+            raw = tc.NetConn()
+        }
+        if cc, ok := raw.(*counterConn); ok {
+            cc.trace = ph
+        }
+
+        return conn, nil
+    }
+}
+
+// idleDialer 在连接建立完成后开启读取空闲超时，
+// 响应头与每段响应体都需要在 idle 内到达
+func idleDialer(dial fasthttp.DialFunc, idle time.Duration) fasthttp.DialFunc {
+    return func(addr string) (net.Conn, error) {
+        conn, err := dial(addr)
+        if err != nil {
+            return nil, err
+        }
+
+        raw := conn
+        if tc, ok := conn.(*tls.Conn); ok {
+            raw = tc.NetConn()
+        }
+        if cc, ok := raw.(*counterConn); ok {
+            cc.idle = idle
+        }
+
+        return conn, nil
+    }
+}
+
+func httpProxyDialer(throughput *int64, proxy string, timeout time.Duration, ph *phases) fasthttp.DialFunc {
     var auth string
     if strings.Contains(proxy, "@") {
         split := strings.Split(proxy, "@")
@@ -67,6 +292,7 @@ func httpProxyDialer(throughput *int64, proxy string, timeout time.Duration) fas
     return func(addr string) (net.Conn, error) {
         var conn net.Conn
         var err error
+        start := time.Now()
         if timeout == 0 {
             conn, err = fasthttp.Dial(proxy)
         } else {
@@ -99,6 +325,9 @@ func httpProxyDialer(throughput *int64, proxy string, timeout time.Duration) fas
             _ = conn.Close()
             return nil, fmt.Errorf("could not connect to proxy")
         }
+        if ph != nil {
+            observe(ph.connect, time.Since(start))
+        }
 
         cc := &counterConn{
             Conn: conn,
@@ -109,7 +338,7 @@ func httpProxyDialer(throughput *int64, proxy string, timeout time.Duration) fas
     }
 }
 
-func httpSocksProxyDialer(throughput *int64, proxyAddr string) fasthttp.DialFunc {
+func httpSocksProxyDialer(throughput *int64, proxyAddr string, ph *phases) fasthttp.DialFunc {
     var (
         u      *url.URL
         err    error
@@ -125,7 +354,11 @@ func httpSocksProxyDialer(throughput *int64, proxyAddr string) fasthttp.DialFunc
             return nil, fmt.Errorf("socks proxy: %w", err)
         }
         var conn net.Conn
+        start := time.Now()
         conn, err = dialer.Dial("tcp", addr)
+        if err == nil && ph != nil {
+            observe(ph.connect, time.Since(start))
+        }
 
         return &counterConn{
             Conn: conn,
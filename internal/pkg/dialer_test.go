@@ -1,7 +1,13 @@
 package pkg
 
 import (
+    "crypto/tls"
+    "io"
+    "log"
     "net"
+    "net/http"
+    "net/http/httptest"
+    "path/filepath"
     "runtime"
     "testing"
     "time"
@@ -31,7 +37,7 @@ func Test_httpDialer(t *testing.T) {
 
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpDialer(&throughput, time.Nanosecond),
+            Dial: httpDialer(&throughput, time.Nanosecond, nil),
         }
 
         req := &fasthttp.Request{}
@@ -47,7 +53,7 @@ func Test_httpDialer(t *testing.T) {
     t.Run("success", func(t *testing.T) {
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpDialer(&throughput, time.Second*3),
+            Dial: httpDialer(&throughput, time.Second*3, nil),
         }
 
         req := &fasthttp.Request{}
@@ -60,6 +66,52 @@ func Test_httpDialer(t *testing.T) {
     })
 }
 
+func Test_unixDialer(t *testing.T) {
+    t.Parallel()
+
+    if runtime.GOOS == "windows" {
+        t.Skip("skip windows test")
+    }
+
+    sock := filepath.Join(t.TempDir(), "app.sock")
+    ln, err := net.Listen("unix", sock)
+    assert.Nil(t, err)
+    t.Cleanup(func() { _ = ln.Close() })
+
+    go func() {
+        _ = fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+            _, _ = ctx.Write(ctx.Host())
+        })
+    }()
+
+    t.Run("missing socket", func(t *testing.T) {
+        var throughput int64
+        dial := unixDialer(&throughput, filepath.Join(t.TempDir(), "missing.sock"), time.Second, nil)
+
+        _, err := dial("example.com:80")
+        assert.NotNil(t, err)
+    })
+
+    t.Run("success", func(t *testing.T) {
+        var throughput int64
+        ph := newPhases()
+        hc := &fasthttp.HostClient{
+            Addr: "example.com:80",
+            Dial: unixDialer(&throughput, sock, time.Second*3, ph),
+        }
+
+        req := &fasthttp.Request{}
+        req.SetRequestURI("http://example.com/")
+        req.URI().SetHost("api.internal")
+        resp := &fasthttp.Response{}
+
+        assert.Nil(t, hc.Do(req, resp))
+        assert.Equal(t, "api.internal", string(resp.Body()))
+        assert.Greater(t, throughput, int64(0))
+        assert.Equal(t, int64(1), ph.connect.count())
+    })
+}
+
 func Test_httpHttpProxyDialer(t *testing.T) {
     t.Parallel()
 
@@ -80,7 +132,7 @@ func Test_httpHttpProxyDialer(t *testing.T) {
         }
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpProxyDialer(&throughput, addr, time.Nanosecond),
+            Dial: httpProxyDialer(&throughput, addr, time.Nanosecond, nil),
         }
 
         req := &fasthttp.Request{}
@@ -96,7 +148,7 @@ func Test_httpHttpProxyDialer(t *testing.T) {
     t.Run("0 timeout", func(t *testing.T) {
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpProxyDialer(&throughput, addr, 0),
+            Dial: httpProxyDialer(&throughput, addr, 0, nil),
         }
 
         req := &fasthttp.Request{}
@@ -111,7 +163,7 @@ func Test_httpHttpProxyDialer(t *testing.T) {
     t.Run("success", func(t *testing.T) {
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpProxyDialer(&throughput, "a:b@"+addr, time.Second*3),
+            Dial: httpProxyDialer(&throughput, "a:b@"+addr, time.Second*3, nil),
         }
 
         req := &fasthttp.Request{}
@@ -141,7 +193,7 @@ func Test_httpSocksProxyDialer(t *testing.T) {
     t.Run("error proxy", func(t *testing.T) {
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpSocksProxyDialer(&throughput, addr),
+            Dial: httpSocksProxyDialer(&throughput, addr, nil),
         }
 
         req := &fasthttp.Request{}
@@ -157,7 +209,7 @@ func Test_httpSocksProxyDialer(t *testing.T) {
     t.Run("success", func(t *testing.T) {
         hc := &fasthttp.HostClient{
             Addr: addr,
-            Dial: httpSocksProxyDialer(&throughput, "socks5://127.0.0.1:88888"),
+            Dial: httpSocksProxyDialer(&throughput, "socks5://127.0.0.1:88888", nil),
         }
 
         req := &fasthttp.Request{}
@@ -168,3 +220,101 @@ func Test_httpSocksProxyDialer(t *testing.T) {
         assert.NotNil(t, hc.Do(req, resp))
     })
 }
+
+func Test_dialer_phases(t *testing.T) {
+    t.Parallel()
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    assert.Nil(t, err)
+
+    _, port, _ := net.SplitHostPort(ln.Addr().String())
+
+    go func() {
+        assert.Nil(t, fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+            time.Sleep(time.Millisecond * 5)
+            ctx.SetBodyString("ok")
+        }))
+    }()
+
+    var throughput int64
+    ph := newPhases()
+    hc := &fasthttp.HostClient{
+        Addr: "localhost:" + port,
+        Dial: traceDialer(httpDialer(&throughput, time.Second*3, ph), ph),
+    }
+
+    for i := 0; i < 3; i++ {
+        req := &fasthttp.Request{}
+        req.SetRequestURI("http://localhost:" + port)
+        resp := &fasthttp.Response{}
+        assert.Nil(t, hc.Do(req, resp))
+    }
+    hc.CloseIdleConnections()
+
+    assert.Equal(t, int64(1), ph.dns.count())
+    assert.Equal(t, int64(1), ph.connect.count())
+    assert.Equal(t, int64(0), ph.tls.count())
+    assert.Equal(t, int64(3), ph.ttfb.count())
+    assert.True(t, ph.ttfb.minValue() >= int64(time.Millisecond*5/time.Microsecond))
+    assert.Eventually(t, func() bool { return ph.transfer.count() == 3 }, time.Second, time.Millisecond*10)
+}
+
+func Test_tlsDialer(t *testing.T) {
+    t.Parallel()
+
+    ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+        _, _ = w.Write([]byte("ok"))
+    }))
+    ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+    ts.StartTLS()
+    defer ts.Close()
+
+    addr := ts.Listener.Addr().String()
+    var throughput int64
+
+    t.Run("success", func(t *testing.T) {
+        ph := newPhases()
+        hc := &fasthttp.HostClient{
+            Addr:  addr,
+            IsTLS: true,
+            Dial: tlsDialer(httpDialer(&throughput, time.Second*3, ph),
+                &tls.Config{InsecureSkipVerify: true}, time.Second*3, ph),
+        }
+
+        req := &fasthttp.Request{}
+        req.SetRequestURI("https://" + addr)
+        resp := &fasthttp.Response{}
+        assert.Nil(t, hc.Do(req, resp))
+        assert.Equal(t, "ok", string(resp.Body()))
+        assert.Equal(t, int64(1), ph.tls.count())
+    })
+
+    t.Run("verify error", func(t *testing.T) {
+        dial := tlsDialer(httpDialer(&throughput, time.Second*3, nil), nil, time.Second*3, nil)
+        _, err := dial(addr)
+        assert.NotNil(t, err)
+    })
+
+    t.Run("dial error", func(t *testing.T) {
+        dial := tlsDialer(httpDialer(&throughput, time.Nanosecond, nil), nil, time.Second, nil)
+        _, err := dial(addr)
+        assert.NotNil(t, err)
+    })
+}
+
+func Test_dnsCache_lookup(t *testing.T) {
+    t.Parallel()
+
+    ph := newPhases()
+    d := &dnsCache{}
+    ip, err := d.lookup("localhost", time.Now().Add(time.Second*3), ph)
+    assert.Nil(t, err)
+    assert.True(t, ip.IsLoopback())
+
+    _, err = d.lookup("localhost", time.Now().Add(time.Second*3), ph)
+    assert.Nil(t, err)
+    assert.Equal(t, int64(1), ph.dns.count())
+
+    _, err = d.lookup("invalid.invalid", time.Now().Add(time.Second), nil)
+    assert.NotNil(t, err)
+}
@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+)
+
+// 请求体的压缩方式，与 Content-Encoding 的取值一致
+const (
+	encodingGzip    = "gzip"
+	encodingBrotli  = "br"
+	encodingZstd    = "zstd"
+	encodingDeflate = "deflate"
+)
+
+// compressBody 按 encoding 压缩请求体，只在初始化时执行一次，空请求体不压缩
+func compressBody(body []byte, encoding string) ([]byte, error) {
+	var compress func(dst, src []byte) []byte
+	switch encoding {
+	case encodingGzip:
+		compress = fasthttp.AppendGzipBytes
+	case encodingBrotli:
+		compress = fasthttp.AppendBrotliBytes
+	case encodingZstd:
+		compress = fasthttp.AppendZstdBytes
+	default:
+		return nil, fmt.Errorf("unsupported body compression %q. gzip, br and zstd are supported", encoding)
+	}
+
+	if len(body) == 0 {
+		return body, nil
+	}
+	return compress(nil, body), nil
+}
+
+// bodyCounter 统计响应体的字节数，wire 为传输的字节数（压缩后），
+// decoded 为按 Content-Encoding 解压后的字节数
+type bodyCounter struct {
+	wire    int64
+	decoded int64
+}
+
+// add 累加一个响应的响应体大小，在记录延迟之后调用，无法解压的响应按传输的字节数计算
+func (b *bodyCounter) add(resp *fasthttp.Response) {
+	body := resp.Body()
+	decoded, err := decodedLen(resp.Header.ContentEncoding(), body)
+	if err != nil {
+		decoded = len(body)
+	}
+
+	atomic.AddInt64(&b.wire, int64(len(body)))
+	atomic.AddInt64(&b.decoded, int64(decoded))
+}
+
+// decodedLen 返回 body 按 encoding 解压后的字节数。解压使用 fasthttp 的解压器池，
+// 结果直接写入 io.Discard，不为解压后的数据分配内存，未压缩或不支持的编码返回原长度
+func decodedLen(encoding, body []byte) (int, error) {
+	var write func(w io.Writer, p []byte) (int, error)
+	switch string(encoding) {
+	case encodingGzip:
+		write = fasthttp.WriteGunzip
+	case encodingBrotli:
+		write = fasthttp.WriteUnbrotli
+	case encodingZstd:
+		write = fasthttp.WriteUnzstd
+	case encodingDeflate:
+		write = fasthttp.WriteInflate
+	default:
+		return len(body), nil
+	}
+	return write(io.Discard, body)
+}
+
+// ratio 返回解压后与传输字节数之比
+func (b *bodyCounter) ratio() float64 {
+	wire := atomic.LoadInt64(&b.wire)
+	if wire == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&b.decoded)) / float64(wire)
+}
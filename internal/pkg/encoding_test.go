@@ -0,0 +1,152 @@
+package pkg
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_compressBody(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(strings.Repeat(`{"event":"click"}`, 100))
+	for encoding, decompress := range map[string]func(dst, src []byte) ([]byte, error){
+		encodingGzip:   fasthttp.AppendGunzipBytes,
+		encodingBrotli: fasthttp.AppendUnbrotliBytes,
+		encodingZstd:   fasthttp.AppendUnzstdBytes,
+	} {
+		compressed, err := compressBody(body, encoding)
+		assert.Nil(t, err, encoding)
+		assert.Less(t, len(compressed), len(body), encoding)
+		decompressed, err := decompress(nil, compressed)
+		assert.Nil(t, err, encoding)
+		assert.Equal(t, body, decompressed, encoding)
+	}
+
+	compressed, err := compressBody(nil, encodingGzip)
+	assert.Nil(t, err)
+	assert.Empty(t, compressed)
+
+	_, err = compressBody(body, "deflate")
+	assert.NotNil(t, err)
+	_, err = compressBody(nil, "lz4")
+	assert.NotNil(t, err)
+}
+
+func Test_bodyCounter(t *testing.T) {
+	t.Parallel()
+
+	b := &bodyCounter{}
+	assert.Equal(t, float64(0), b.ratio())
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	plain := []byte(strings.Repeat("a", 1000))
+	resp.SetBody(fasthttp.AppendGzipBytes(nil, plain))
+	resp.Header.SetContentEncoding(encodingGzip)
+	b.add(resp)
+	gzipped := int64(len(resp.Body()))
+
+	resp.Reset()
+	resp.SetBodyString("plain")
+	b.add(resp)
+
+	resp.Reset()
+	resp.SetBodyString("not gzip")
+	resp.Header.SetContentEncoding(encodingGzip)
+	b.add(resp)
+
+	assert.Equal(t, gzipped+5+8, b.wire)
+	assert.Equal(t, int64(1000+5+8), b.decoded)
+	assert.Greater(t, b.ratio(), float64(1))
+}
+
+func Test_decodedLen(t *testing.T) {
+	t.Parallel()
+
+	plain := []byte(strings.Repeat("abc", 1000))
+	for enc, body := range map[string][]byte{
+		encodingGzip:    fasthttp.AppendGzipBytes(nil, plain),
+		encodingBrotli:  fasthttp.AppendBrotliBytes(nil, plain),
+		encodingZstd:    fasthttp.AppendZstdBytes(nil, plain),
+		encodingDeflate: fasthttp.AppendDeflateBytes(nil, plain),
+		"":              plain,
+		"compress":      plain,
+	} {
+		n, err := decodedLen([]byte(enc), body)
+		assert.Nil(t, err, enc)
+		assert.Equal(t, len(plain), n, enc)
+	}
+
+	_, err := decodedLen([]byte(encodingGzip), []byte("not gzip"))
+	assert.NotNil(t, err)
+}
+
+func Test_HttpGo_Run_Compression(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var (
+		mut    sync.Mutex
+		bodies []string
+	)
+	payload := strings.Repeat("0123456789", 500)
+	go func() {
+		_ = fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+			body, err := ctx.Request.BodyUncompressed()
+			mut.Lock()
+			if err == nil && string(ctx.Request.Header.ContentEncoding()) == encodingZstd {
+				bodies = append(bodies, string(body))
+			}
+			mut.Unlock()
+
+			if string(ctx.Request.Header.Peek(fasthttp.HeaderAcceptEncoding)) == "gzip" {
+				ctx.Response.Header.SetContentEncoding(encodingGzip)
+				ctx.SetBody(fasthttp.AppendGzipBytes(nil, []byte(payload)))
+				return
+			}
+			ctx.SetBodyString(payload)
+		})
+	}()
+
+	var buf bytes.Buffer
+	p := New(Config{
+		Url:            "http://" + ln.Addr().String(),
+		Method:         fasthttp.MethodPost,
+		Body:           strings.Repeat(`{"a":1}`, 100),
+		CompressBody:   encodingZstd,
+		AcceptEncoding: "gzip",
+		Count:          5,
+		Connections:    1,
+		Output:         outputJSON,
+	})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	mut.Lock()
+	assert.Len(t, bodies, 5)
+	for _, body := range bodies {
+		assert.Equal(t, strings.Repeat(`{"a":1}`, 100), body)
+	}
+	mut.Unlock()
+
+	r := p.report()
+	assert.Equal(t, encodingZstd, r.Config.CompressBody)
+	assert.Equal(t, "gzip", r.Config.AcceptEncoding)
+	assert.Equal(t, int64(5*len(payload)), r.Throughput.DecodedBodyBytes)
+	assert.Greater(t, r.Throughput.BodyBytes, int64(0))
+	assert.Less(t, r.Throughput.BodyBytes, r.Throughput.DecodedBodyBytes)
+	assert.Contains(t, p.stat.output(), "decoded")
+
+	_, err = newHttpClient(&Config{Url: "http://example.com", Body: "a", CompressBody: "lz4"})
+	assert.NotNil(t, err)
+}
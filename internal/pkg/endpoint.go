@@ -0,0 +1,38 @@
+package pkg
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// endpoint 保存单个端点的统计数据，场景模式下每个请求对应一个端点
+type endpoint struct {
+	name    string
+	reqs    int64
+	errs    int64
+	codes   [6]int64
+	latency *histogram
+}
+
+func newEndpoint(name string) *endpoint {
+	return &endpoint{name: name, latency: newHistogram()}
+}
+
+func (e *endpoint) record(code int, latency time.Duration, err error) {
+	if err != nil {
+		atomic.AddInt64(&e.errs, 1)
+		return
+	}
+
+	atomic.AddInt64(&e.reqs, 1)
+	atomic.AddInt64(&e.codes[codeIndex(code)], 1)
+	e.latency.record(latency.Microseconds())
+}
+
+// codeIndex 返回状态码所属的分类，依次为 1xx ~ 5xx 与其他
+func codeIndex(code int) int {
+	if i := code/100 - 1; i >= 0 && i < 5 {
+		return i
+	}
+	return 5
+}
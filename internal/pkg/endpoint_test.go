@@ -0,0 +1,32 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_endpoint_record(t *testing.T) {
+	t.Parallel()
+
+	ep := newEndpoint("login")
+	ep.record(200, time.Millisecond, nil)
+	ep.record(503, time.Millisecond*3, nil)
+	ep.record(0, 0, errors.New("error"))
+
+	assert.Equal(t, "login", ep.name)
+	assert.Equal(t, int64(2), ep.reqs)
+	assert.Equal(t, int64(1), ep.errs)
+	assert.Equal(t, [6]int64{0, 1, 0, 0, 1, 0}, ep.codes)
+	assert.Equal(t, int64(2), ep.latency.count())
+}
+
+func Test_codeIndex(t *testing.T) {
+	t.Parallel()
+
+	for code, i := range map[int]int{100: 0, 204: 1, 302: 2, 404: 3, 599: 4, 0: 5, 600: 5, 99: 5} {
+		assert.Equal(t, i, codeIndex(code), code)
+	}
+}
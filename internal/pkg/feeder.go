@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// 数据文件的读取方式
+const (
+	dataSequential = "sequential"
+	dataRandom     = "random"
+	dataCircular   = "circular"
+)
+
+// dataPrefix 为引用数据列的模板变量前缀，例如 {{data.username}}
+const dataPrefix = "data."
+
+// errDataExhausted 表示按顺序读取的数据文件已用完
+var errDataExhausted = errors.New("data file exhausted")
+
+// feeder 保存数据文件中的所有行，每次请求按读取方式取出一行
+type feeder struct {
+	columns map[string]int
+	rows    [][]string
+	mode    string
+	pos     uint64
+}
+
+// newFeeder 读取 CSV（首行为列名）或 JSONL（每行一个 JSON 对象）数据文件，
+// 扩展名为 .jsonl 或 .ndjson 时按 JSONL 解析
+func newFeeder(path, mode string) (f *feeder, err error) {
+	switch mode {
+	case "":
+		mode = dataCircular
+	case dataSequential, dataRandom, dataCircular:
+	default:
+		return nil, fmt.Errorf("unsupported data mode %q. sequential, random and circular are supported", mode)
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	f = &feeder{mode: mode, columns: make(map[string]int)}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson":
+		err = f.readJSONL(file)
+	default:
+		err = f.readCSV(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data file %s: %w", path, err)
+	}
+
+	if len(f.rows) == 0 {
+		return nil, fmt.Errorf("no rows in data file %s", path)
+	}
+
+	return
+}
+
+func (f *feeder) readCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+
+	header, err := cr.Read()
+	if err != nil {
+		return err
+	}
+	for i, name := range header {
+		f.columns[strings.TrimSpace(name)] = i
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		f.rows = append(f.rows, record)
+	}
+}
+
+func (f *feeder) readJSONL(r io.Reader) error {
+	var objects []map[string]json.RawMessage
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return err
+		}
+		objects = append(objects, obj)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	// 列为所有对象键的并集，按名称排序以保证顺序稳定
+	var names []string
+	for _, obj := range objects {
+		for k := range obj {
+			if _, ok := f.columns[k]; !ok {
+				f.columns[k] = 0
+				names = append(names, k)
+			}
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		f.columns[name] = i
+	}
+
+	for _, obj := range objects {
+		row := make([]string, len(names))
+		for k, v := range obj {
+			row[f.columns[k]] = jsonValue(v)
+		}
+		f.rows = append(f.rows, row)
+	}
+
+	return nil
+}
+
+// jsonValue 返回字符串的内容，其他类型的值保持 JSON 原文
+func jsonValue(v json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(v, &s); err == nil {
+		return s
+	}
+	if string(v) == "null" {
+		return ""
+	}
+	return string(v)
+}
+
+// next 按读取方式取出一行，按顺序读取完毕时返回 false
+func (f *feeder) next() ([]string, bool) {
+	n := uint64(len(f.rows))
+	switch f.mode {
+	case dataRandom:
+		return f.rows[rand.Uint64N(n)], true
+	case dataSequential:
+		i := atomic.AddUint64(&f.pos, 1) - 1
+		if i >= n {
+			return nil, false
+		}
+		return f.rows[i], true
+	default:
+		i := atomic.AddUint64(&f.pos, 1) - 1
+		return f.rows[i%n], true
+	}
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newFeeder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("csv", func(t *testing.T) {
+		path := writeScenario(t, "users.csv", "id, name\n1,alice\n2,\"bob, jr\"\n")
+		f, err := newFeeder(path, "")
+		assert.Nil(t, err)
+		assert.Equal(t, dataCircular, f.mode)
+		assert.Equal(t, map[string]int{"id": 0, "name": 1}, f.columns)
+		assert.Equal(t, [][]string{{"1", "alice"}, {"2", "bob, jr"}}, f.rows)
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		path := writeScenario(t, "users.jsonl", `{"name":"alice","id":1}
+
+{"name":"bob","tags":["a"],"extra":null}
+`)
+		f, err := newFeeder(path, dataSequential)
+		assert.Nil(t, err)
+		assert.Equal(t, map[string]int{"extra": 0, "id": 1, "name": 2, "tags": 3}, f.columns)
+		assert.Equal(t, [][]string{{"", "1", "alice", ""}, {"", "", "bob", `["a"]`}}, f.rows)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := newFeeder(writeScenario(t, "a.csv", "id\n1\n"), "shuffle")
+		assert.NotNil(t, err)
+
+		_, err = newFeeder("missing.csv", "")
+		assert.NotNil(t, err)
+
+		_, err = newFeeder(writeScenario(t, "a.csv", "id\n"), "")
+		assert.NotNil(t, err)
+
+		_, err = newFeeder(writeScenario(t, "a.csv", "id\n1,2\n"), "")
+		assert.NotNil(t, err)
+
+		_, err = newFeeder(writeScenario(t, "a.jsonl", "{\n"), "")
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_feeder_next(t *testing.T) {
+	t.Parallel()
+
+	rows := [][]string{{"a"}, {"b"}, {"c"}}
+	values := func(f *feeder, n int) (out []string) {
+		for i := 0; i < n; i++ {
+			row, ok := f.next()
+			if !ok {
+				return
+			}
+			out = append(out, row[0])
+		}
+		return
+	}
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "b"}, values(&feeder{rows: rows, mode: dataCircular}, 5))
+	assert.Equal(t, []string{"a", "b", "c"}, values(&feeder{rows: rows, mode: dataSequential}, 5))
+
+	for _, v := range values(&feeder{rows: rows, mode: dataRandom}, 20) {
+		assert.Contains(t, []string{"a", "b", "c"}, v)
+	}
+}
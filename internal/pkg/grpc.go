@@ -0,0 +1,581 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	// 描述文件与反射结果中未包含的标准类型从程序内置的描述中查找
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// grpcCodeCount 为 gRPC 定义的状态码数量，OK(0) 至 Unauthenticated(16)
+const grpcCodeCount = 17
+
+// grpcStats 统计 gRPC 的调用，codes 为各 grpc-status 的次数，
+// sent 与 received 为调用中发送与收到的消息数
+type grpcStats struct {
+	conns    int
+	codes    [grpcCodeCount]int64
+	sent     int64
+	received int64
+}
+
+// add 记录一次调用的状态码，未定义的状态码返回 false
+func (s *grpcStats) add(code int) bool {
+	if code < 0 || code >= grpcCodeCount {
+		return false
+	}
+	atomic.AddInt64(&s.codes[code], 1)
+	return true
+}
+
+// rawCodec 原样收发已编码的消息，请求在初始化时编码一次，压测时不解码响应
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	switch m := v.(type) {
+	case []byte:
+		return m, nil
+	case *[]byte:
+		return *m, nil
+	}
+	return nil, fmt.Errorf("unexpected message type %T", v)
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	p, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("unexpected message type %T", v)
+	}
+	*p = data
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+// grpcClient 调用一个 gRPC 方法，调用按顺序轮流使用各连接，grpc-status 作为状态码记录。
+// 流式方法发送全部消息后关闭发送方向，读取响应直到服务端结束调用
+type grpcClient struct {
+	addr    string
+	method  string
+	desc    protoreflect.MethodDescriptor
+	stream  *grpc.StreamDesc
+	msgs    [][]byte
+	md      metadata.MD
+	timeout time.Duration
+	stats   *grpcStats
+	conns   []*grpc.ClientConn
+	next    uint64
+
+	writeCloser io.WriteCloser
+}
+
+// initGrpc 初始化 gRPC 模式，连接数、并发数、QPS 与持续时间的含义与 HTTP 相同
+func (p *HttpGo) initGrpc() (err error) {
+	switch {
+	case p.c.HTTP2 || p.c.HTTP3 || p.c.Pipeline:
+		return errors.New("grpc mode cannot be used with --http2, --http3 or --pipeline")
+	case p.c.Stream || p.c.CompressBody != "":
+		return errors.New("grpc mode cannot be used with --stream or --compress-body")
+	case p.c.requestsFile() != "" || p.c.Raw != "":
+		return errors.New("grpc mode cannot be used with scenario, .http, HAR or raw request files")
+	}
+
+	// grpc 模式不统计 HTTP 状态码，按状态码分类的比例应改用 ok_ratio
+	for _, a := range p.asserts {
+		if strings.HasSuffix(a.metric, "xx_ratio") {
+			return fmt.Errorf("assertion %q does not apply in grpc mode, use ok_ratio instead", a.expr)
+		}
+	}
+
+	var gc *grpcClient
+	if gc, err = newGrpcClient(p.c); err != nil {
+		return
+	}
+	p.client = gc
+	p.stat.url = p.c.Url + gc.method
+
+	return
+}
+
+func newGrpcClient(c *Config) (gc *grpcClient, err error) {
+	gc = &grpcClient{
+		timeout:     c.Timeout,
+		stats:       c.grpc,
+		writeCloser: defaultWriteCloser{Writer: os.Stdout},
+	}
+	if gc.stats == nil {
+		gc.stats = &grpcStats{}
+	}
+
+	var service, method string
+	if service, method, err = splitGrpcMethod(c.GrpcMethod); err != nil {
+		return
+	}
+	gc.method = "/" + service + "/" + method
+
+	var opts []grpc.DialOption
+	if opts, err = c.grpcDialOptions(); err != nil {
+		return
+	}
+	n := c.http2Conns()
+	if c.Debug {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		var cc *grpc.ClientConn
+		if cc, err = grpc.NewClient("passthrough:///"+c.addr, opts...); err != nil {
+			return
+		}
+		gc.conns = append(gc.conns, cc)
+	}
+	gc.addr = c.addr
+	gc.stats.conns = n
+
+	kvs, err := headers(c.Headers).kvs()
+	if err != nil {
+		return
+	}
+	gc.md = metadata.MD{}
+	for i := 0; i < len(kvs); i += 2 {
+		gc.md.Append(kvs[i], kvs[i+1])
+	}
+
+	var files *protoregistry.Files
+	if c.ProtoSet != "" {
+		files, err = readProtoSet(c.ProtoSet)
+	} else {
+		files, err = gc.reflect(service)
+	}
+	if err != nil {
+		return
+	}
+	if gc.desc, err = findGrpcMethod(files, service, method); err != nil {
+		return
+	}
+	if gc.desc.IsStreamingClient() || gc.desc.IsStreamingServer() {
+		gc.stream = &grpc.StreamDesc{
+			StreamName:    method,
+			ClientStreams: gc.desc.IsStreamingClient(),
+			ServerStreams: gc.desc.IsStreamingServer(),
+		}
+	}
+
+	var body []byte
+	if body, err = c.grpcBody(); err != nil {
+		return
+	}
+	gc.msgs, err = marshalGrpcMessages(files, gc.desc, body)
+
+	return
+}
+
+// grpcDialOptions 返回连接选项，https 使用 TLS，连接使用与 HTTP 相同的代理与吞吐量统计
+func (c *Config) grpcDialOptions() (opts []grpc.DialOption, err error) {
+	var u *url.URL
+	if u, err = url.Parse(c.Url); err != nil {
+		return
+	}
+	switch u.Scheme {
+	case "http":
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	case "https":
+		c.isTLS = true
+		if c.tlsConf, err = c.getTlsConfig(); err != nil {
+			return
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(c.tlsConf)))
+	default:
+		return nil, fmt.Errorf("unsupported grpc scheme %q. http and https are supported", u.Scheme)
+	}
+	c.addr = addMissingPort(u.Host, c.isTLS)
+
+	dial := c.getConnDialer()
+	opts = append(opts,
+		grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+			return dial(addr)
+		}),
+		grpc.WithUserAgent("httpgo/"+Version),
+	)
+	if c.Host != "" {
+		opts = append(opts, grpc.WithAuthority(c.Host))
+	}
+
+	return
+}
+
+// grpcBody 返回 JSON 格式的请求消息，为空时发送空消息
+func (c *Config) grpcBody() ([]byte, error) {
+	body := []byte(c.Body)
+	if c.File != "" {
+		var err error
+		if body, err = os.ReadFile(filepath.Clean(c.File)); err != nil {
+			return nil, err
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+	return body, nil
+}
+
+// splitGrpcMethod 拆分 package.Service/Method 或 package.Service.Method 格式的方法名
+func splitGrpcMethod(name string) (service, method string, err error) {
+	name = strings.TrimPrefix(name, "/")
+	i := strings.LastIndexAny(name, "/.")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", fmt.Errorf("invalid grpc method %q, want package.Service/Method", name)
+	}
+	return name[:i], name[i+1:], nil
+}
+
+func findGrpcMethod(files *protoregistry.Files, service, method string) (protoreflect.MethodDescriptor, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("grpc service %s not found", service)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a grpc service", service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, fmt.Errorf("grpc method %s not found in service %s", method, service)
+	}
+	return md, nil
+}
+
+// marshalGrpcMessages 将 JSON 消息编码为请求消息，客户端流式方法可以使用 JSON 数组发送多条消息
+func marshalGrpcMessages(files *protoregistry.Files, md protoreflect.MethodDescriptor, body []byte) ([][]byte, error) {
+	list := []json.RawMessage{body}
+	if md.IsStreamingClient() && bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := protojson.UnmarshalOptions{Resolver: dynamicpb.NewTypes(files)}
+	msgs := make([][]byte, 0, len(list))
+	for _, raw := range list {
+		msg := dynamicpb.NewMessage(md.Input())
+		if err := opts.Unmarshal(raw, msg); err != nil {
+			return nil, fmt.Errorf("invalid %s message: %w", md.Input().FullName(), err)
+		}
+		b, err := proto.Marshal(msg)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, b)
+	}
+
+	return msgs, nil
+}
+
+// readProtoSet 读取 protoc --descriptor_set_out 生成的描述文件
+func readProtoSet(path string) (*protoregistry.Files, error) {
+	b, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+	}
+	return newGrpcFiles(set.GetFile())
+}
+
+// newGrpcFiles 按依赖顺序注册描述，未包含的依赖从程序内置的标准类型中查找
+func newGrpcFiles(list []*descriptorpb.FileDescriptorProto) (*protoregistry.Files, error) {
+	pending := make(map[string]*descriptorpb.FileDescriptorProto, len(list))
+	names := make([]string, 0, len(list))
+	for _, fd := range list {
+		pending[fd.GetName()] = fd
+		names = append(names, fd.GetName())
+	}
+	sort.Strings(names)
+
+	files := new(protoregistry.Files)
+	var add func(name string) error
+	add = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fd, ok := pending[name]
+		if !ok {
+			f, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("missing proto file %s", name)
+			}
+			return files.RegisterFile(f)
+		}
+		for _, dep := range fd.GetDependency() {
+			if err := add(dep); err != nil {
+				return err
+			}
+		}
+		f, err := protodesc.NewFile(fd, files)
+		if err != nil {
+			return err
+		}
+		return files.RegisterFile(f)
+	}
+
+	for _, name := range names {
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// reflect 通过服务端反射（grpc.reflection.v1）获取 service 所在文件及其依赖的描述
+func (c *grpcClient) reflect(service string) (*protoregistry.Files, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	stream, err := rpb.NewServerReflectionClient(c.conns[0]).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("server reflection: %w", err)
+	}
+	defer func() { _ = stream.CloseSend() }()
+
+	fds := make(map[string]*descriptorpb.FileDescriptorProto)
+	ask := func(req *rpb.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return fmt.Errorf("server reflection: %w", err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return fmt.Errorf("server reflection: %w", err)
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return fmt.Errorf("server reflection: %s", e.GetErrorMessage())
+		}
+		for _, b := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(b, fd); err != nil {
+				return fmt.Errorf("server reflection: %w", err)
+			}
+			fds[fd.GetName()] = fd
+		}
+		return nil
+	}
+
+	if err = ask(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	}); err != nil {
+		return nil, err
+	}
+	// 服务端可能只返回部分依赖，缺少的依赖按文件名继续获取
+	for name := missingGrpcDep(fds); name != ""; name = missingGrpcDep(fds) {
+		if err = ask(&rpb.ServerReflectionRequest{
+			MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+		}); err != nil {
+			return nil, err
+		}
+		if fds[name] == nil {
+			return nil, fmt.Errorf("server reflection: missing proto file %s", name)
+		}
+	}
+
+	list := make([]*descriptorpb.FileDescriptorProto, 0, len(fds))
+	for _, fd := range fds {
+		list = append(list, fd)
+	}
+	return newGrpcFiles(list)
+}
+
+// missingGrpcDep 返回 fds 中缺少且不是内置标准类型的依赖
+func missingGrpcDep(fds map[string]*descriptorpb.FileDescriptorProto) string {
+	for _, fd := range fds {
+		for _, dep := range fd.GetDependency() {
+			if fds[dep] != nil {
+				continue
+			}
+			if _, err := protoregistry.GlobalFiles.FindFileByPath(dep); err != nil {
+				return dep
+			}
+		}
+	}
+	return ""
+}
+
+func (c *grpcClient) do() (*endpoint, int, time.Duration, error) {
+	cc := c.conns[int((atomic.AddUint64(&c.next, 1)-1)%uint64(len(c.conns)))]
+
+	ctx, cancel := c.context()
+	defer cancel()
+
+	start := time.Now()
+	err := c.call(ctx, cc, nil)
+	latency := time.Since(start)
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, 0, 0, err
+	}
+	return nil, int(st.Code()), latency, nil
+}
+
+func (c *grpcClient) context() (context.Context, context.CancelFunc) {
+	ctx := metadata.NewOutgoingContext(context.Background(), c.md)
+	if c.timeout > 0 {
+		return context.WithTimeout(ctx, c.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// call 调用方法，recv 不为 nil 时依次处理收到的响应消息，
+// 返回的错误为调用的 grpc-status，成功时为 nil
+func (c *grpcClient) call(ctx context.Context, cc *grpc.ClientConn, recv func([]byte), opts ...grpc.CallOption) error {
+	opts = append(opts, grpc.ForceCodec(rawCodec{}))
+
+	if c.stream == nil {
+		var reply []byte
+		atomic.AddInt64(&c.stats.sent, 1)
+		if err := cc.Invoke(ctx, c.method, c.msgs[0], &reply, opts...); err != nil {
+			return err
+		}
+		atomic.AddInt64(&c.stats.received, 1)
+		if recv != nil {
+			recv(reply)
+		}
+		return nil
+	}
+
+	s, err := cc.NewStream(ctx, c.stream, c.method, opts...)
+	if err != nil {
+		return err
+	}
+	// 服务端提前结束调用时发送返回 io.EOF，调用的状态由接收方获取
+	for _, msg := range c.msgs {
+		if err = s.SendMsg(msg); err != nil {
+			break
+		}
+		atomic.AddInt64(&c.stats.sent, 1)
+	}
+	if err == nil {
+		err = s.CloseSend()
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	for {
+		var reply []byte
+		if err = s.RecvMsg(&reply); err != nil {
+			break
+		}
+		atomic.AddInt64(&c.stats.received, 1)
+		if recv != nil {
+			recv(reply)
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// doOnce 调用一次方法，输出请求与响应的元数据和 JSON 格式的消息
+func (c *grpcClient) doOnce() (err error) {
+	ctx, cancel := c.context()
+	defer cancel()
+
+	var header, trailer metadata.MD
+	var replies [][]byte
+	err = c.call(ctx, c.conns[0], func(b []byte) { replies = append(replies, b) },
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "Connected to %s\r\n\r\n", c.addr)
+	_, _ = fmt.Fprintf(&buf, "%s\r\n", c.method)
+	writeGrpcMetadata(&buf, c.md)
+	for _, msg := range c.msgs {
+		buf.WriteString("\r\n")
+		c.writeMessage(&buf, c.desc.Input(), msg)
+	}
+
+	buf.WriteString("\n\n")
+	writeGrpcMetadata(&buf, header)
+	for _, msg := range replies {
+		buf.WriteString("\r\n")
+		c.writeMessage(&buf, c.desc.Output(), msg)
+	}
+	_, _ = fmt.Fprintf(&buf, "\r\ngrpc-status: %d %s\r\n", st.Code(), st.Code())
+	if st.Message() != "" {
+		_, _ = fmt.Fprintf(&buf, "grpc-message: %s\r\n", st.Message())
+	}
+	writeGrpcMetadata(&buf, trailer)
+
+	_, _ = c.writeCloser.Write(buf.Bytes())
+	return c.writeCloser.Close()
+}
+
+func (c *grpcClient) writeMessage(buf *bytes.Buffer, md protoreflect.MessageDescriptor, b []byte) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		_, _ = fmt.Fprintf(buf, "<invalid %s message: %v>\r\n", md.FullName(), err)
+		return
+	}
+	out, _ := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(msg)
+	buf.Write(out)
+	buf.WriteString("\r\n")
+}
+
+func writeGrpcMetadata(buf *bytes.Buffer, md metadata.MD) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			_, _ = fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+}
+
+// grpcCodeName 返回状态码的名称，例如 OK、Unavailable
+func grpcCodeName(code int) string {
+	return codes.Code(code).String() // #nosec G115
+}
@@ -0,0 +1,330 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// benchProto 为测试服务的描述，bench.Echo 包含四种调用方式，
+// Msg 的 code 不为 0 时服务端以该状态码结束调用
+func benchProto() *descriptorpb.FileDescriptorProto {
+	field := func(name string, n int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(n),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	method := func(name string, client, server bool) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:            proto.String(name),
+			InputType:       proto.String(".bench.Msg"),
+			OutputType:      proto.String(".bench.Msg"),
+			ClientStreaming: proto.Bool(client),
+			ServerStreaming: proto.Bool(server),
+		}
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("bench/echo.proto"),
+		Package:    proto.String("bench"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Msg"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+				field("code", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("at", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Echo"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("Unary", false, false),
+				method("Server", false, true),
+				method("Client", true, false),
+				method("Bidi", true, true),
+			},
+		}},
+	}
+}
+
+// grpcEcho 实现 bench.Echo，记录请求的 :authority、x-token 与收到的 text
+type grpcEcho struct {
+	msg protoreflect.MessageDescriptor
+
+	mut         sync.Mutex
+	authorities map[string]bool
+	tokens      map[string]bool
+	texts       map[string]bool
+}
+
+// newGrpcServer 在回环地址上启动 bench.Echo 服务与反射服务，返回服务地址
+func newGrpcServer(t *testing.T) (*grpcEcho, string) {
+	t.Helper()
+
+	files, err := newGrpcFiles([]*descriptorpb.FileDescriptorProto{benchProto()})
+	assert.Nil(t, err)
+	d, err := files.FindDescriptorByName("bench.Msg")
+	assert.Nil(t, err)
+	e := &grpcEcho{
+		msg:         d.(protoreflect.MessageDescriptor),
+		authorities: map[string]bool{},
+		tokens:      map[string]bool{},
+		texts:       map[string]bool{},
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "bench.Echo",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Unary",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				m := dynamicpb.NewMessage(e.msg)
+				if err := dec(m); err != nil {
+					return nil, err
+				}
+				e.seen(ctx, m)
+				return m, e.status(m)
+			},
+		}},
+		Streams: []grpc.StreamDesc{
+			{StreamName: "Server", ServerStreams: true, Handler: func(_ any, s grpc.ServerStream) error {
+				m := dynamicpb.NewMessage(e.msg)
+				if err := s.RecvMsg(m); err != nil {
+					return err
+				}
+				e.seen(s.Context(), m)
+				for i := 0; i < 3; i++ {
+					if err := s.SendMsg(m); err != nil {
+						return err
+					}
+				}
+				return e.status(m)
+			}},
+			{StreamName: "Client", ClientStreams: true, Handler: func(_ any, s grpc.ServerStream) error {
+				return e.stream(s, false)
+			}},
+			{StreamName: "Bidi", ClientStreams: true, ServerStreams: true, Handler: func(_ any, s grpc.ServerStream) error {
+				return e.stream(s, true)
+			}},
+		},
+		Metadata: "bench/echo.proto",
+	}, struct{}{})
+	rpb.RegisterServerReflectionServer(srv, reflection.NewServerV1(reflection.ServerOptions{Services: srv, DescriptorResolver: files}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	return e, lis.Addr().String()
+}
+
+// stream 读取全部消息，echo 为 true 时逐条回复，否则读取完毕后回复最后一条
+func (e *grpcEcho) stream(s grpc.ServerStream, echo bool) error {
+	var last *dynamicpb.Message
+	for {
+		m := dynamicpb.NewMessage(e.msg)
+		err := s.RecvMsg(m)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		e.seen(s.Context(), m)
+		if echo {
+			if err = s.SendMsg(m); err != nil {
+				return err
+			}
+		}
+		last = m
+	}
+	if last == nil {
+		return status.Error(codes.InvalidArgument, "no messages")
+	}
+	if !echo {
+		return s.SendMsg(last)
+	}
+	return nil
+}
+
+func (e *grpcEcho) seen(ctx context.Context, m *dynamicpb.Message) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	for _, v := range md.Get(":authority") {
+		e.authorities[v] = true
+	}
+	for _, v := range md.Get("x-token") {
+		e.tokens[v] = true
+	}
+	e.texts[m.Get(e.msg.Fields().ByName("text")).String()] = true
+}
+
+func (e *grpcEcho) status(m *dynamicpb.Message) error {
+	if code := m.Get(e.msg.Fields().ByName("code")).Int(); code != 0 {
+		return status.Error(codes.Code(code), "requested") // #nosec G115
+	}
+	return nil
+}
+
+func Test_HttpGo_Run_GRPC(t *testing.T) {
+	t.Parallel()
+
+	e, addr := newGrpcServer(t)
+
+	// 描述文件不包含 timestamp.proto，从内置的标准类型中查找
+	set, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{benchProto()}})
+	assert.Nil(t, err)
+	protoSet := filepath.Join(t.TempDir(), "echo.pb")
+	assert.Nil(t, os.WriteFile(protoSet, set, 0o600))
+
+	t.Run("unary with reflection", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:            addr,
+			GRPC:           true,
+			GrpcMethod:     "bench.Echo/Unary",
+			Body:           `{"text":"hello","at":"2024-01-01T00:00:00Z"}`,
+			Headers:        []string{"X-Token: abc"},
+			Host:           "api.example.com",
+			Connections:    4,
+			StreamsPerConn: 2,
+			Count:          40,
+			Output:         outputJSON,
+		})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, int64(40), r.Totals.Requests)
+		assert.Equal(t, map[string]int64{"OK": 40}, r.GRPC.Codes)
+		assert.Equal(t, 2, r.GRPC.Connections)
+		assert.Equal(t, int64(0), r.Codes.Code2xx)
+		assert.Greater(t, r.Throughput.Bytes, int64(0))
+
+		output := p.stat.output()
+		assert.Contains(t, output, "gRPC codes:")
+		assert.Contains(t, output, "OK - ")
+		assert.NotContains(t, output, "HTTP codes:")
+
+		e.mut.Lock()
+		defer e.mut.Unlock()
+		assert.Equal(t, []string{"api.example.com"}, keys(e.authorities))
+		assert.Equal(t, []string{"abc"}, keys(e.tokens))
+		assert.True(t, e.texts["hello"])
+	})
+
+	t.Run("status codes", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Unary", ProtoSet: protoSet, Body: `{"code":14}`, Connections: 2, Count: 10, Output: outputJSON, Asserts: []string{"ok_ratio>=99%"}})
+		p.stat.w = &buf
+		err := p.Run()
+		var ae *AssertionError
+		assert.True(t, errors.As(err, &ae))
+		assert.Equal(t, []string{"ok_ratio>=99% (actual: 0.000%)"}, ae.Failed)
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, map[string]int64{"Unavailable": 10}, r.GRPC.Codes)
+	})
+
+	t.Run("server streaming", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: "http://" + addr, GRPC: true, GrpcMethod: "/bench.Echo/Server", ProtoSet: protoSet, Connections: 2, Count: 10, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, map[string]int64{"OK": 10}, r.GRPC.Codes)
+		assert.GreaterOrEqual(t, r.GRPC.Received, 3*r.GRPC.Sent-3)
+	})
+
+	t.Run("client streaming", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo.Client", Body: `[{"text":"a"},{"text":"b"}]`, Connections: 2, Count: 10, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, map[string]int64{"OK": 10}, r.GRPC.Codes)
+		assert.GreaterOrEqual(t, r.GRPC.Sent, int64(20))
+		assert.GreaterOrEqual(t, r.GRPC.Received, int64(10))
+	})
+
+	t.Run("bidi streaming", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Bidi", Body: `[{"text":"a"},{"text":"b"},{"text":"c"}]`, Connections: 2, Count: 10, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, map[string]int64{"OK": 10}, r.GRPC.Codes)
+		assert.GreaterOrEqual(t, r.GRPC.Received, int64(30))
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		p := New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Server", Body: `{"text":"hi"}`, Debug: true})
+		assert.Nil(t, p.init())
+
+		var buf bytes.Buffer
+		p.client.(*grpcClient).writeCloser = defaultWriteCloser{&buf}
+		assert.Nil(t, p.doOnce())
+		assert.Contains(t, buf.String(), "/bench.Echo/Server")
+		assert.Equal(t, 4, bytes.Count(buf.Bytes(), []byte(`"hi"`)))
+		assert.Contains(t, buf.String(), "grpc-status: 0 OK")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Missing", ProtoSet: protoSet}).Run())
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Missing/Unary"}).Run())
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "Unary", ProtoSet: protoSet}).Run())
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Unary", ProtoSet: protoSet, Body: `{"unknown":1}`}).Run())
+		assert.NotNil(t, New(Config{Url: "ftp://" + addr, GRPC: true, GrpcMethod: "bench.Echo/Unary", ProtoSet: protoSet}).Run())
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Unary", HTTP2: true}).Run())
+		assert.NotNil(t, New(Config{Url: addr, GRPC: true, GrpcMethod: "bench.Echo/Unary", Asserts: []string{"2xx_ratio>=99%"}}).Run())
+	})
+}
+
+func Test_splitGrpcMethod(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"bench.Echo/Unary", "/bench.Echo/Unary", "bench.Echo.Unary"} {
+		service, method, err := splitGrpcMethod(name)
+		assert.Nil(t, err)
+		assert.Equal(t, "bench.Echo", service)
+		assert.Equal(t, "Unary", method)
+	}
+	_, _, err := splitGrpcMethod("bench.Echo/")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,254 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAR 的回放方式
+const (
+	harPaced = "paced"
+	harLoop  = "loop"
+)
+
+// harFile 为 HAR 1.2 文件中回放需要的部分
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time  `json:"startedDateTime"`
+	Request         harRequest `json:"request"`
+}
+
+type harRequest struct {
+	Method   string         `json:"method"`
+	Url      string         `json:"url"`
+	Headers  []harNameValue `json:"headers"`
+	PostData *harPostData   `json:"postData"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string     `json:"mimeType"`
+	Text     string     `json:"text"`
+	Params   []harParam `json:"params"`
+}
+
+type harParam struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	FileName string `json:"fileName"`
+}
+
+// harSkipHeaders 为回放时不发送的请求头，由客户端根据实际连接与请求体生成
+var harSkipHeaders = map[string]bool{
+	"content-length":    true,
+	"connection":        true,
+	"transfer-encoding": true,
+	"keep-alive":        true,
+}
+
+// readHar 读取 HAR 文件，按开始时间排序后返回各请求及其相对第一个请求的时间，
+// 忽略非 http/https 的请求
+func readHar(path string) (list []scenario, offsets []time.Duration, err error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return
+	}
+
+	var f harFile
+	if err = json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse HAR file %s: %w", path, err)
+	}
+
+	entries := f.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	for _, e := range entries {
+		u, perr := url.Parse(e.Request.Url)
+		if perr != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+
+		one := 1
+		s := scenario{Name: harEntryName(u), Method: e.Request.Method, Url: e.Request.Url, Weight: &one}
+		for _, h := range e.Request.Headers {
+			// HTTP/2 的伪首部（:authority 等）不能作为 HTTP/1.1 请求头发送
+			if strings.HasPrefix(h.Name, ":") || harSkipHeaders[strings.ToLower(h.Name)] {
+				continue
+			}
+			s.Headers = append(s.Headers, h.Name+": "+h.Value)
+		}
+		if pd := e.Request.PostData; pd != nil {
+			if s.Body, s.Headers, err = harBody(pd, s.Headers); err != nil {
+				return nil, nil, fmt.Errorf("invalid postData of %s %s in HAR file %s: %w", s.Method, s.Url, path, err)
+			}
+		}
+
+		offsets = append(offsets, e.StartedDateTime.Sub(entries[0].StartedDateTime))
+		list = append(list, s)
+	}
+
+	if len(list) == 0 {
+		return nil, nil, fmt.Errorf("no http request in HAR file %s", path)
+	}
+
+	// 首个请求可能被忽略，以第一个回放的请求为起点
+	for i := len(offsets) - 1; i >= 0; i-- {
+		offsets[i] -= offsets[0]
+	}
+
+	return
+}
+
+// harBody 返回请求体，没有记录原始文本时按 params 重新编码并保持字段顺序。
+// multipart/form-data 使用记录的 boundary，没有记录时生成新的 boundary 并替换 Content-Type 请求头，
+// 文件字段没有记录内容，不能重新构建
+func harBody(pd *harPostData, headers []string) (string, []string, error) {
+	if pd.Text != "" || len(pd.Params) == 0 {
+		return pd.Text, headers, nil
+	}
+
+	parts := make([]formPart, 0, len(pd.Params))
+	for _, p := range pd.Params {
+		if p.FileName != "" {
+			return "", nil, fmt.Errorf("file field %q has no recorded content", p.Name)
+		}
+		parts = append(parts, formPart{name: p.Name, value: p.Value})
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(pd.MimeType)
+	if mediaType != mimeMultipartForm {
+		body, _, err := buildFormBody(nil, parts)
+		return string(body), headers, err
+	}
+
+	ct := -1
+	for i, h := range headers {
+		if name, value, _ := strings.Cut(h, ":"); strings.EqualFold(strings.TrimSpace(name), "Content-Type") {
+			ct = i
+			if _, hp, err := mime.ParseMediaType(strings.TrimSpace(value)); err == nil && params["boundary"] == "" {
+				params = hp
+			}
+		}
+	}
+
+	body, contentType, err := buildMultipartBody(nil, parts, params["boundary"])
+	if err != nil {
+		return "", nil, err
+	}
+	if params["boundary"] == "" {
+		if ct >= 0 {
+			headers[ct] = "Content-Type: " + contentType
+		} else {
+			headers = append(headers, "Content-Type: "+contentType)
+		}
+	}
+
+	return string(body), headers, nil
+}
+
+// harEntryName 以 URL 路径作为统计的端点名称，同一路径的请求合并统计
+func harEntryName(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// harReplay 按 HAR 中记录的相对时间回放请求，speed 大于 1 时加速回放
+type harReplay struct {
+	client  *httpClient
+	offsets []time.Duration
+}
+
+func newHarReplay(c *httpClient, speed float64) *harReplay {
+	r := &harReplay{client: c, offsets: make([]time.Duration, len(c.offsets))}
+	for i, off := range c.offsets {
+		r.offsets[i] = time.Duration(float64(off) / speed)
+	}
+	return r
+}
+
+// offset 返回第 n 个请求的计划发送时间，所有请求回放完毕后返回 false
+func (r *harReplay) offset(n float64) (time.Duration, bool) {
+	i := int(n)
+	if i >= len(r.offsets) {
+		return 0, false
+	}
+	return r.offsets[i], true
+}
+
+// span 返回回放所有请求需要的时间
+func (r *harReplay) span() time.Duration {
+	return r.offsets[len(r.offsets)-1]
+}
+
+// rate 返回回放的平均速率，用于界面展示
+func (r *harReplay) rate() int {
+	if sec := r.span().Seconds(); sec > 0 {
+		return int(math.Max(1, math.Round(float64(len(r.offsets))/sec)))
+	}
+	return len(r.offsets)
+}
+
+// request 发送第 n 个请求并记录结果
+func (r *harReplay) request(p *HttpGo, intended time.Time, n int64) {
+	ep, code, latency, err := r.client.doTarget(r.client.targets[n])
+	p.result(intended, ep, code, latency, err)
+}
+
+// initHar 读取 HAR 文件，每个请求构建一个请求模板，同一路径的请求共用一个端点
+func (c *httpClient) initHar(conf *Config) (err error) {
+	if conf.Debug {
+		return errors.New("debug mode does not support HAR files")
+	}
+
+	var list []scenario
+	if list, c.offsets, err = readHar(conf.Har); err != nil {
+		return
+	}
+	if conf.tlsConf, err = conf.getTlsConfig(); err != nil {
+		return
+	}
+	c.targets, c.weights, err = conf.scenarioTargets(list)
+
+	return
+}
+
+// initHar 校验 HAR 的回放方式，按原始时间回放时使用开放模型，
+// 持续时间为回放所有请求的时间加上一个超时时间
+func (p *HttpGo) initHar(hc *httpClient) error {
+	switch p.c.HarMode {
+	case harLoop:
+		return nil
+	case "", harPaced:
+	default:
+		return fmt.Errorf("unsupported HAR mode %q. paced and loop are supported", p.c.HarMode)
+	}
+
+	p.replay = newHarReplay(hc, p.c.Speed)
+	p.c.Duration = p.replay.span() + p.c.Timeout
+	p.stat.duration = p.c.Duration
+	p.stat.rate = p.replay.rate()
+
+	return nil
+}
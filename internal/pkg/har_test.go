@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// harJSON 生成包含 entries 的 HAR 文件内容，entries 依次为 开始时间偏移（毫秒）、方法与 URL
+func harJSON(entries ...[3]string) string {
+	var b bytes.Buffer
+	b.WriteString(`{"log":{"version":"1.2","entries":[`)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		var ms int
+		_, _ = fmt.Sscan(e[0], &ms)
+		fmt.Fprintf(&b, `{"startedDateTime":%q,"request":{"method":%q,"url":%q,"headers":[{"name":"X-Seq","value":"%d"}]}}`,
+			start.Add(time.Duration(ms)*time.Millisecond).Format(time.RFC3339Nano), e[1], e[2], i)
+	}
+	b.WriteString(`]}}`)
+	return b.String()
+}
+
+func Test_readHar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		path := writeScenario(t, "s.har", `{"log":{"version":"1.2","entries":[
+{"startedDateTime":"2024-01-01T00:00:01.500Z","request":{"method":"POST","url":"https://example.com/login",
+ "headers":[{"name":":authority","value":"example.com"},{"name":"Content-Length","value":"7"},{"name":"Referer","value":"https://example.com/"}],
+ "postData":{"mimeType":"application/x-www-form-urlencoded","params":[{"name":"a","value":"1 2"}]}}},
+{"startedDateTime":"2024-01-01T00:00:00.500Z","request":{"method":"GET","url":"data:image/png;base64,AAAA","headers":[]}},
+{"startedDateTime":"2024-01-01T00:00:01.000Z","request":{"method":"GET","url":"https://example.com","headers":[]}},
+{"startedDateTime":"2024-01-01T00:00:01.200Z","request":{"method":"PUT","url":"https://example.com/items?id=1","headers":[],
+ "postData":{"mimeType":"application/json","text":"{\"a\":1}"}}}
+]}}`)
+
+		list, offsets, err := readHar(path)
+		assert.Nil(t, err)
+		assert.Equal(t, []time.Duration{0, 200 * time.Millisecond, 500 * time.Millisecond}, offsets)
+		assert.Len(t, list, 3)
+
+		assert.Equal(t, "/", list[0].Name)
+		assert.Equal(t, "https://example.com", list[0].Url)
+		assert.Equal(t, 1, *list[0].Weight)
+		assert.Equal(t, "/items", list[1].Name)
+		assert.Equal(t, `{"a":1}`, list[1].Body)
+		assert.Equal(t, "/login", list[2].Name)
+		assert.Equal(t, []string{"Referer: https://example.com/"}, list[2].Headers)
+		assert.Equal(t, "a=1+2", list[2].Body)
+	})
+
+	t.Run("post params", func(t *testing.T) {
+		path := writeScenario(t, "s.har", `{"log":{"version":"1.2","entries":[
+{"startedDateTime":"2024-01-01T00:00:00Z","request":{"method":"POST","url":"https://example.com/form","headers":[],
+ "postData":{"mimeType":"application/x-www-form-urlencoded","params":[{"name":"z","value":"1"},{"name":"a","value":"&"}]}}},
+{"startedDateTime":"2024-01-01T00:00:01Z","request":{"method":"POST","url":"https://example.com/upload",
+ "headers":[{"name":"Content-Type","value":"multipart/form-data; boundary=----abc"}],
+ "postData":{"mimeType":"multipart/form-data; boundary=----abc","params":[{"name":"z","value":"1"},{"name":"a","value":"2"}]}}},
+{"startedDateTime":"2024-01-01T00:00:02Z","request":{"method":"POST","url":"https://example.com/upload",
+ "headers":[{"name":"content-type","value":"multipart/form-data; boundary=xyz"}],
+ "postData":{"mimeType":"multipart/form-data","params":[{"name":"a","value":"1"}]}}},
+{"startedDateTime":"2024-01-01T00:00:03Z","request":{"method":"POST","url":"https://example.com/upload","headers":[],
+ "postData":{"mimeType":"multipart/form-data","params":[{"name":"a","value":"1"}]}}}
+]}}`)
+
+		list, _, err := readHar(path)
+		assert.Nil(t, err)
+		assert.Len(t, list, 4)
+
+		// 字段保持记录的顺序
+		assert.Equal(t, "z=1&a=%26", list[0].Body)
+
+		// 使用记录的 boundary，请求头原样发送
+		assert.Equal(t, []string{"Content-Type: multipart/form-data; boundary=----abc"}, list[1].Headers)
+		assert.Equal(t, "------abc\r\nContent-Disposition: form-data; name=\"z\"\r\n\r\n1\r\n"+
+			"------abc\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n2\r\n------abc--\r\n", list[1].Body)
+
+		// mimeType 中没有 boundary 时取自 Content-Type 请求头
+		assert.Equal(t, []string{"content-type: multipart/form-data; boundary=xyz"}, list[2].Headers)
+		assert.True(t, strings.HasPrefix(list[2].Body, "--xyz\r\n"), list[2].Body)
+
+		// 都没有记录时生成新的 boundary 并设置 Content-Type
+		assert.Len(t, list[3].Headers, 1)
+		ct, params, err := mime.ParseMediaType(strings.TrimPrefix(list[3].Headers[0], "Content-Type: "))
+		assert.Nil(t, err)
+		assert.Equal(t, mimeMultipartForm, ct)
+		assert.True(t, strings.HasPrefix(list[3].Body, "--"+params["boundary"]+"\r\n"), list[3].Body)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := readHar("not-exist.har")
+		assert.NotNil(t, err)
+
+		_, _, err = readHar(writeScenario(t, "s.har", `{`))
+		assert.NotNil(t, err)
+
+		_, _, err = readHar(writeScenario(t, "s.har", `{"log":{"version":"1.2","entries":[
+{"startedDateTime":"2024-01-01T00:00:00Z","request":{"method":"POST","url":"https://example.com/upload","headers":[],
+ "postData":{"mimeType":"multipart/form-data","params":[{"name":"f","fileName":"a.png","contentType":"image/png"}]}}}]}}`))
+		assert.NotNil(t, err)
+
+		_, _, err = readHar(writeScenario(t, "s.har", harJSON([3]string{"0", "GET", "ws://example.com"})))
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_harReplay(t *testing.T) {
+	t.Parallel()
+
+	r := newHarReplay(&httpClient{offsets: []time.Duration{0, time.Second, 4 * time.Second}}, 2)
+	assert.Equal(t, 2*time.Second, r.span())
+	assert.Equal(t, 2, r.rate())
+
+	off, ok := r.offset(1)
+	assert.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, off)
+	_, ok = r.offset(3)
+	assert.False(t, ok)
+
+	r = newHarReplay(&httpClient{offsets: []time.Duration{0, 0}}, 1)
+	assert.Equal(t, 2, r.rate())
+}
+
+func Test_HttpGo_Run_Har(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mut   sync.Mutex
+		seqs  []string
+		times []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		seqs = append(seqs, r.Header.Get("X-Seq"))
+		times = append(times, time.Now())
+		mut.Unlock()
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	path := writeScenario(t, "s.har", harJSON(
+		[3]string{"0", "GET", srv.URL + "/a?x=1"},
+		[3]string{"200", "POST", srv.URL + "/missing"},
+		[3]string{"400", "GET", srv.URL + "/a?x=2"},
+	))
+
+	t.Run("paced", func(t *testing.T) {
+		mut.Lock()
+		seqs, times = nil, nil
+		mut.Unlock()
+
+		var buf bytes.Buffer
+		p := New(Config{Har: path, Speed: 2, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		mut.Lock()
+		defer mut.Unlock()
+		assert.Equal(t, []string{"0", "1", "2"}, seqs)
+		assert.InDelta(t, 200*time.Millisecond, times[2].Sub(times[0]), float64(80*time.Millisecond))
+
+		r := p.report()
+		assert.Equal(t, harPaced, r.Config.HarMode)
+		assert.Equal(t, 2.0, r.Config.Speed)
+		assert.Len(t, r.Endpoints, 2)
+		assert.Equal(t, "/a", r.Endpoints[0].Name)
+		assert.Equal(t, int64(2), r.Endpoints[0].Codes.Code2xx)
+		assert.Equal(t, int64(1), r.Endpoints[1].Codes.Code4xx)
+		assert.Equal(t, path, p.stat.url)
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Har: path, HarMode: harLoop, Count: 9, Connections: 2, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(9), r.Totals.Requests)
+		assert.Equal(t, int64(6), r.Endpoints[0].Requests)
+		assert.Equal(t, int64(3), r.Endpoints[1].Requests)
+		assert.Equal(t, 0.0, r.Config.Speed)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		assert.NotNil(t, New(Config{Har: path, HarMode: "fast"}).Run())
+		assert.NotNil(t, New(Config{Har: path, Scenario: path}).Run())
+		assert.NotNil(t, New(Config{Har: path, Debug: true}).Run())
+	})
+}
+
+func Test_httpClient_initHar_template(t *testing.T) {
+	t.Parallel()
+
+	path := writeScenario(t, "s.har", `{"log":{"version":"1.2","entries":[
+{"startedDateTime":"2024-01-01T00:00:00Z","request":{"method":"POST","url":"http://example.com/a","headers":[],
+ "postData":{"mimeType":"text/plain","text":"{{seq}}"}}}]}}`)
+
+	fc, err := newHttpClient(&Config{Har: path})
+	assert.Nil(t, err)
+	assert.Nil(t, fc.targets[0].tmpl)
+
+	fc, err = newHttpClient(&Config{Har: path, HarTemplate: true})
+	assert.Nil(t, err)
+	assert.NotNil(t, fc.targets[0].tmpl)
+}
@@ -31,11 +31,12 @@ func (h headers) writeToHttp(req *fasthttp.Request) error {
 func (h headers) kvs() ([]string, error) {
     list := make([]string, 0, len(h)*2)
     for _, header := range h {
-        kv := strings.Split(header, ":")
-        if len(kv) != 2 {
+        // 值中可以包含冒号，例如 Referer: https://example.com
+        k, v, ok := strings.Cut(header, ":")
+        if !ok || strings.TrimSpace(k) == "" {
             return nil, fmt.Errorf("failed to parse request header %s", header)
         }
-        list = append(list, strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+        list = append(list, strings.TrimSpace(k), strings.TrimSpace(v))
     }
     return list, nil
 }
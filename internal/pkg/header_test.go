@@ -12,7 +12,7 @@ func Test_Header_kvs_invalid_header(t *testing.T) {
 
     testCases := []headers{
         {"foo"},
-        {"foo:bar:baz"},
+        {" :bar"},
     }
     for _, tc := range testCases {
         t.Run(tc[0], func(t *testing.T) {
@@ -47,6 +47,14 @@ func Test_Header_kvs(t *testing.T) {
     }
 }
 
+func Test_Header_kvs_colon_in_value(t *testing.T) {
+    t.Parallel()
+
+    kvs, err := headers{"Referer: https://example.com:8443/a"}.kvs()
+    assert.Nil(t, err)
+    assert.Equal(t, []string{"Referer", "https://example.com:8443/a"}, kvs)
+}
+
 func Test_Header_WriteToHttp(t *testing.T) {
     t.Parallel()
 
@@ -0,0 +1,178 @@
+package pkg
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// histSubBucketBits 决定每个量级内的子桶数量，7 位即 128 个子桶，
+	// 相对误差不超过 1/64
+	histSubBucketBits  = 7
+	histSubBucketCount = 1 << histSubBucketBits
+	histSubBucketHalf  = histSubBucketCount / 2
+	// histMaxBits 决定可记录的最大值，单位为微秒时约为 19 小时
+	histMaxBits   = 36
+	histMaxValue  = int64(1)<<histMaxBits - 1
+	histBucketLen = histSubBucketCount + (histMaxBits-histSubBucketBits)*histSubBucketHalf
+)
+
+// histogram 是一个 HDR 风格的对数分桶直方图，内存占用固定，
+// 与记录的样本数量无关
+type histogram struct {
+	mut    sync.Mutex
+	counts [histBucketLen]int64
+	total  int64
+	min    int64
+	max    int64
+	sum    float64
+	sum2   float64
+}
+
+func newHistogram() *histogram {
+	return &histogram{}
+}
+
+func (h *histogram) record(v int64) {
+	if v < 0 {
+		v = 0
+	}
+	if v > histMaxValue {
+		v = histMaxValue
+	}
+
+	h.mut.Lock()
+	h.counts[histIndex(v)]++
+	if h.total == 0 || v < h.min {
+		h.min = v
+	}
+	if v > h.max {
+		h.max = v
+	}
+	h.total++
+	f := float64(v)
+	h.sum += f
+	h.sum2 += f * f
+	h.mut.Unlock()
+}
+
+func (h *histogram) merge(o *histogram) {
+	o.mut.Lock()
+	counts, total, min, max, sum, sum2 := o.counts, o.total, o.min, o.max, o.sum, o.sum2
+	o.mut.Unlock()
+
+	if total == 0 {
+		return
+	}
+
+	h.mut.Lock()
+	for i, n := range counts {
+		h.counts[i] += n
+	}
+	if h.total == 0 || min < h.min {
+		h.min = min
+	}
+	if max > h.max {
+		h.max = max
+	}
+	h.total += total
+	h.sum += sum
+	h.sum2 += sum2
+	h.mut.Unlock()
+}
+
+func (h *histogram) count() int64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.total
+}
+
+func (h *histogram) minValue() int64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.min
+}
+
+func (h *histogram) maxValue() int64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.max
+}
+
+func (h *histogram) mean() float64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / float64(h.total)
+}
+
+// stdev 返回样本标准差
+func (h *histogram) stdev() float64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if h.total < 2 {
+		return 0
+	}
+	n := float64(h.total)
+	variance := (h.sum2 - h.sum*h.sum/n) / (n - 1)
+	if variance < 0 {
+		return 0
+	}
+	return math.Sqrt(variance)
+}
+
+// percentile 返回第 q 百分位（0 < q <= 100）的值，
+// 结果为所在桶的上界，并且不会超过已记录的最大值
+func (h *histogram) percentile(q float64) int64 {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if h.total == 0 {
+		return 0
+	}
+	if q >= 100 {
+		return h.max
+	}
+
+	target := int64(math.Ceil(q / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen int64
+	for i, n := range h.counts {
+		if seen += n; seen >= target {
+			v := histHighestValue(i)
+			if v > h.max {
+				v = h.max
+			}
+			if v < h.min {
+				v = h.min
+			}
+			return v
+		}
+	}
+
+	return h.max
+}
+
+func histIndex(v int64) int {
+	if v < histSubBucketCount {
+		return int(v)
+	}
+	exp := bits.Len64(uint64(v)) - histSubBucketBits
+	sub := int(v >> uint(exp))
+	return histSubBucketCount + (exp-1)*histSubBucketHalf + sub - histSubBucketHalf
+}
+
+func histHighestValue(i int) int64 {
+	if i < histSubBucketCount {
+		return int64(i)
+	}
+	exp := (i-histSubBucketCount)/histSubBucketHalf + 1
+	sub := int64((i-histSubBucketCount)%histSubBucketHalf + histSubBucketHalf)
+	return (sub+1)<<uint(exp) - 1
+}
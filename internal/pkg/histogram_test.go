@@ -0,0 +1,123 @@
+package pkg
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_histogram_percentile(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram()
+	assert.Equal(t, int64(0), h.percentile(50))
+
+	for i := int64(1); i <= 100000; i++ {
+		h.record(i)
+	}
+
+	testCases := []struct {
+		q    float64
+		want float64
+	}{
+		{50, 50000},
+		{75, 75000},
+		{90, 90000},
+		{99, 99000},
+		{99.9, 99900},
+		{99.99, 99990},
+	}
+
+	for _, tc := range testCases {
+		got := float64(h.percentile(tc.q))
+		assert.InEpsilon(t, tc.want, got, 1.0/histSubBucketHalf, "p%v", tc.q)
+	}
+
+	assert.Equal(t, int64(100000), h.percentile(100))
+	assert.Equal(t, int64(1), h.minValue())
+	assert.Equal(t, int64(100000), h.maxValue())
+	assert.Equal(t, int64(100000), h.count())
+}
+
+func Test_histogram_exactSmallValues(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram()
+	for i := int64(0); i < histSubBucketCount; i++ {
+		h.record(i)
+	}
+	assert.Equal(t, int64(63), h.percentile(50))
+}
+
+func Test_histogram_bounds(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram()
+	h.record(-1)
+	h.record(math.MaxInt64)
+	assert.Equal(t, int64(0), h.minValue())
+	assert.Equal(t, histMaxValue, h.maxValue())
+	assert.Equal(t, histMaxValue, h.percentile(99))
+}
+
+func Test_histogram_meanAndStdev(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram()
+	assert.Zero(t, h.mean())
+	assert.Zero(t, h.stdev())
+
+	for _, v := range []int64{1, 6, 5, 7, 9, 8} {
+		h.record(v)
+	}
+	assert.Equal(t, 6.0, h.mean())
+	assert.InDelta(t, 2.8284271247461903, h.stdev(), 1e-9)
+}
+
+func Test_histogram_merge(t *testing.T) {
+	t.Parallel()
+
+	a, b := newHistogram(), newHistogram()
+	a.record(10)
+	b.record(1000)
+	b.record(5)
+
+	a.merge(b)
+	a.merge(newHistogram())
+	assert.Equal(t, int64(3), a.count())
+	assert.Equal(t, int64(5), a.minValue())
+	assert.Equal(t, int64(1000), a.maxValue())
+}
+
+func Test_histogram_concurrent(t *testing.T) {
+	t.Parallel()
+
+	h := newHistogram()
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			for j := int64(0); j < 1000; j++ {
+				h.record(j)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(10000), h.count())
+}
+
+func Test_histIndex(t *testing.T) {
+	t.Parallel()
+
+	last := -1
+	for v := int64(0); v < 1<<20; v += 7 {
+		i := histIndex(v)
+		assert.True(t, i >= last)
+		assert.True(t, histHighestValue(i) >= v)
+		last = i
+	}
+	assert.Equal(t, histBucketLen-1, histIndex(histMaxValue))
+}
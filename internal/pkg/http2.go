@@ -0,0 +1,271 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"golang.org/x/net/http2"
+)
+
+// http2Stats 统计 HTTP/2 的连接与流，场景中的所有地址共用
+type http2Stats struct {
+	conns        int64
+	connErrors   int64
+	streams      int64
+	streamErrors int64
+	active       int64
+	maxActive    int64
+}
+
+// begin 记录一个新的流并更新同时活跃的流的最大值
+func (s *http2Stats) begin() {
+	atomic.AddInt64(&s.streams, 1)
+	active := atomic.AddInt64(&s.active, 1)
+	for {
+		max := atomic.LoadInt64(&s.maxActive)
+		if active <= max || atomic.CompareAndSwapInt64(&s.maxActive, max, active) {
+			return
+		}
+	}
+}
+
+func (s *http2Stats) end() {
+	atomic.AddInt64(&s.active, -1)
+}
+
+// http2Slot 为一个连接的位置，连接失效后在原位置重新建立
+type http2Slot struct {
+	mut sync.Mutex
+	cc  *http2.ClientConn
+}
+
+// http2Doer 通过 HTTP/2 发送请求，https 使用 ALPN 协商 h2，http 使用 h2c（prior knowledge），
+// 请求按顺序轮流使用各连接，每个连接上的并发流数为并发数除以连接数
+type http2Doer struct {
+	addr    string
+	scheme  string
+	dial    fasthttp.DialFunc
+	timeout time.Duration
+	stats   *http2Stats
+	tr      *http2.Transport
+
+	next  uint64
+	slots []http2Slot
+}
+
+// newHttp2Doer 返回连接到 addr 的 HTTP/2 客户端，连接数为 conns
+func (c *Config) newHttp2Doer(addr string, isTLS bool, conns int) *http2Doer {
+	d := &http2Doer{
+		addr:    addr,
+		scheme:  "http",
+		dial:    c.getConnDialer(),
+		timeout: c.Timeout,
+		stats:   c.h2,
+		// 流数达到服务端的 MAX_CONCURRENT_STREAMS 时等待，而不是新建连接
+		tr:    &http2.Transport{AllowHTTP: true, StrictMaxConcurrentStreams: true},
+		slots: make([]http2Slot, conns),
+	}
+	if d.stats == nil {
+		d.stats = &http2Stats{}
+	}
+
+	if isTLS {
+		d.scheme = "https"
+		conf := &tls.Config{} // #nosec G402
+		if c.tlsConf != nil {
+			conf = c.tlsConf.Clone()
+		}
+		// 同时提供 http/1.1，不支持 h2 的服务端仍能完成握手，以便给出明确的错误
+		conf.NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+		d.dial = tlsDialer(d.dial, conf, c.Timeout, c.phases)
+	}
+
+	return d
+}
+
+func (d *http2Doer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	ctx := context.Background()
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	hreq, err := newHttpRequest(ctx, req, d.scheme+"://"+d.addr)
+	if err != nil {
+		return err
+	}
+
+	cc, err := d.conn()
+	if err != nil {
+		atomic.AddInt64(&d.stats.connErrors, 1)
+		return err
+	}
+
+	d.stats.begin()
+	defer d.stats.end()
+
+	hresp, err := cc.RoundTrip(hreq)
+	if err == nil {
+		err = readHttpResponse(hresp, resp)
+	}
+	if err != nil {
+		return d.failed(ctx, err)
+	}
+
+	return nil
+}
+
+// DoRedirects 用于调试模式，按 Location 跟随重定向
+func (d *http2Doer) DoRedirects(req *fasthttp.Request, resp *fasthttp.Response, maxRedirects int) error {
+	for i := 0; ; i++ {
+		if err := d.Do(req, resp); err != nil {
+			return err
+		}
+		if !fasthttp.StatusCodeIsRedirect(resp.StatusCode()) {
+			return nil
+		}
+		if i >= maxRedirects {
+			return fasthttp.ErrTooManyRedirects
+		}
+
+		location := resp.Header.Peek(fasthttp.HeaderLocation)
+		if len(location) == 0 {
+			return fasthttp.ErrMissingLocation
+		}
+		req.URI().UpdateBytes(location)
+		if resp.StatusCode() == fasthttp.StatusSeeOther {
+			req.Header.SetMethod(fasthttp.MethodGet)
+			req.ResetBody()
+		}
+	}
+}
+
+// failed 区分流级与连接级错误，超时与 fasthttp 的超时错误一致
+func (d *http2Doer) failed(ctx context.Context, err error) error {
+	var se http2.StreamError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		atomic.AddInt64(&d.stats.streamErrors, 1)
+		return fasthttp.ErrTimeout
+	case errors.As(err, &se):
+		atomic.AddInt64(&d.stats.streamErrors, 1)
+	default:
+		atomic.AddInt64(&d.stats.connErrors, 1)
+	}
+	return err
+}
+
+// conn 按顺序选择一个连接，连接不存在或已不能发起新的流时重新建立
+func (d *http2Doer) conn() (*http2.ClientConn, error) {
+	s := &d.slots[int((atomic.AddUint64(&d.next, 1)-1)%uint64(len(d.slots)))]
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.cc != nil && s.cc.CanTakeNewRequest() {
+		return s.cc, nil
+	}
+	// 收到 GOAWAY 的连接在已有的流结束后由服务端关闭
+	s.cc = nil
+
+	conn, err := d.dial(d.addr)
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := conn.(*tls.Conn); ok {
+		if proto := tc.ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+			_ = conn.Close()
+			return nil, fmt.Errorf("server %s does not support HTTP/2 (ALPN negotiated %q)", d.addr, proto)
+		}
+	}
+
+	if s.cc, err = d.tr.NewClientConn(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	atomic.AddInt64(&d.stats.conns, 1)
+
+	return s.cc, nil
+}
+
+// newHttpRequest 将 fasthttp 请求转换为发往 base 的 net/http 请求，Host 头作为 :authority 发送，
+// HTTP/2 与 HTTP/3 中不允许的连接级请求头被忽略
+func newHttpRequest(ctx context.Context, req *fasthttp.Request, base string) (*http.Request, error) {
+	uri := req.URI()
+
+	var (
+		body   io.Reader
+		length int64
+	)
+	switch {
+	case req.IsBodyStream():
+		body, length = req.BodyStream(), int64(req.Header.ContentLength())
+		if length < 0 {
+			length = -1
+		}
+	case len(req.Body()) > 0:
+		body, length = bytes.NewReader(req.Body()), int64(len(req.Body()))
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, string(req.Header.Method()),
+		base+string(uri.RequestURI()), body)
+	if err != nil {
+		return nil, err
+	}
+	hreq.ContentLength = length
+	hreq.Host = string(req.Header.Host())
+	if hreq.Host == "" {
+		hreq.Host = string(uri.Host())
+	}
+
+	for k, v := range req.Header.All() {
+		switch string(k) {
+		case fasthttp.HeaderHost, fasthttp.HeaderConnection, fasthttp.HeaderContentLength,
+			fasthttp.HeaderTransferEncoding, "Keep-Alive", "Proxy-Connection", fasthttp.HeaderUpgrade:
+			continue
+		}
+		hreq.Header.Add(string(k), string(v))
+	}
+	if hreq.Header.Get(fasthttp.HeaderUserAgent) == "" {
+		hreq.Header.Set(fasthttp.HeaderUserAgent, "httpgo/"+Version)
+	}
+
+	return hreq, nil
+}
+
+// readHttpResponse 将 net/http 响应读入 fasthttp 响应，响应体读取完毕才算请求结束
+func readHttpResponse(hresp *http.Response, resp *fasthttp.Response) error {
+	defer func() { _ = hresp.Body.Close() }()
+
+	resp.Reset()
+	resp.SetStatusCode(hresp.StatusCode)
+	for k, vs := range hresp.Header {
+		if k == fasthttp.HeaderContentLength {
+			continue
+		}
+		for _, v := range vs {
+			resp.Header.Add(k, v)
+		}
+	}
+
+	_, err := io.Copy(resp.BodyWriter(), hresp.Body)
+	return err
+}
+
+// http2Conns 返回 HTTP/2 与 HTTP/3 的连接数，并发数按每个连接的流数分配到各连接
+func (c *Config) http2Conns() int {
+	streams := c.StreamsPerConn
+	if streams <= 0 {
+		streams = 1
+	}
+	return (c.maxConns() + streams - 1) / streams
+}
@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// h2Recorder 记录服务端收到的请求的协议、连接与请求体
+type h2Recorder struct {
+	mut    sync.Mutex
+	protos map[string]int
+	conns  map[string]bool
+	hosts  map[string]bool
+	bodies []string
+}
+
+func newH2Recorder() *h2Recorder {
+	return &h2Recorder{protos: map[string]int{}, conns: map[string]bool{}, hosts: map[string]bool{}}
+}
+
+func (h *h2Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/reset" {
+		panic(http.ErrAbortHandler)
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	h.mut.Lock()
+	h.protos[r.Proto]++
+	h.conns[r.RemoteAddr] = true
+	h.hosts[r.Host] = true
+	h.bodies = append(h.bodies, string(body))
+	h.mut.Unlock()
+
+	w.Header().Set("X-Proto", r.Proto)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func Test_HttpGo_Run_HTTP2(t *testing.T) {
+	t.Parallel()
+
+	t.Run("h2c", func(t *testing.T) {
+		h := newH2Recorder()
+		ts := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:            ts.URL + "/echo",
+			Method:         http.MethodPost,
+			Body:           "hello",
+			Host:           "api.example.com",
+			HTTP2:          true,
+			StreamsPerConn: 3,
+			Connections:    6,
+			Count:          30,
+			Output:         outputJSON,
+		})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, r.Totals.Requests, r.Codes.Code2xx)
+		assert.Equal(t, 3, r.HTTP2.StreamsPerConn)
+		assert.Equal(t, int64(2), r.HTTP2.Connections)
+		assert.GreaterOrEqual(t, r.HTTP2.Streams, int64(30))
+		assert.LessOrEqual(t, r.HTTP2.MaxConcurrentStreams, int64(6))
+		assert.Equal(t, int64(0), r.HTTP2.StreamErrors)
+		assert.Contains(t, p.stat.output(), "HTTP/2:")
+
+		h.mut.Lock()
+		defer h.mut.Unlock()
+		assert.Equal(t, []string{"HTTP/2.0"}, keys(h.protos))
+		assert.Len(t, h.conns, 2)
+		assert.Equal(t, []string{"api.example.com"}, keys(h.hosts))
+		for _, body := range h.bodies {
+			assert.Equal(t, "hello", body)
+		}
+	})
+
+	t.Run("h2 over tls", func(t *testing.T) {
+		h := newH2Recorder()
+		ts := httptest.NewUnstartedServer(h)
+		ts.EnableHTTP2 = true
+		ts.StartTLS()
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL, Insecure: true, HTTP2: true, Connections: 4, Count: 10, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, int64(4), r.HTTP2.Connections)
+		assert.Contains(t, r.Phases, "tls")
+
+		h.mut.Lock()
+		defer h.mut.Unlock()
+		assert.Equal(t, []string{"HTTP/2.0"}, keys(h.protos))
+	})
+
+	// 全部请求失败时请求数不会增加，按时长结束
+	t.Run("no alpn", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(newH2Recorder())
+		ts.Config.ErrorLog = log.New(io.Discard, "", 0)
+		ts.StartTLS()
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL, Insecure: true, HTTP2: true, Connections: 1, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Totals.Errors, int64(0))
+		assert.GreaterOrEqual(t, r.HTTP2.ConnectionErrors, r.Totals.Errors)
+		assert.Equal(t, int64(0), r.HTTP2.Connections)
+		for msg := range r.Errors {
+			assert.True(t, strings.Contains(msg, "does not support HTTP/2"), msg)
+		}
+	})
+
+	t.Run("stream reset", func(t *testing.T) {
+		ts := httptest.NewServer(h2c.NewHandler(newH2Recorder(), &http2.Server{}))
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/reset", HTTP2: true, Connections: 2, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Totals.Errors, int64(0))
+		assert.GreaterOrEqual(t, r.HTTP2.StreamErrors, r.Totals.Errors)
+		assert.Equal(t, int64(0), r.HTTP2.ConnectionErrors)
+		assert.Equal(t, int64(2), r.HTTP2.Connections)
+	})
+
+	t.Run("with pipeline", func(t *testing.T) {
+		p := New(Config{Url: "http://127.0.0.1:1", HTTP2: true, Pipeline: true})
+		assert.NotNil(t, p.Run())
+	})
+}
+
+func Test_Config_http2Conns(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 10, (&Config{Connections: 10}).http2Conns())
+	assert.Equal(t, 4, (&Config{Connections: 10, StreamsPerConn: 3}).http2Conns())
+	assert.Equal(t, 1, (&Config{Connections: 10, StreamsPerConn: 100}).http2Conns())
+	assert.Equal(t, 5, (&Config{Rate: 10, MaxInFlight: 50, StreamsPerConn: 10}).http2Conns())
+}
+
+func Test_http2Stats(t *testing.T) {
+	t.Parallel()
+
+	s := &http2Stats{}
+	s.begin()
+	s.begin()
+	s.end()
+	s.begin()
+	s.end()
+	s.end()
+	assert.Equal(t, int64(3), s.streams)
+	assert.Equal(t, int64(2), s.maxActive)
+	assert.Equal(t, int64(0), s.active)
+}
+
+func keys[V any](m map[string]V) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
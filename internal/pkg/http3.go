@@ -0,0 +1,236 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/valyala/fasthttp"
+)
+
+// http3Stats 统计 HTTP/3 的连接与流，场景中的所有地址共用，握手耗时记录在 QUIC 阶段
+type http3Stats struct {
+	conns        int64
+	connErrors   int64
+	zeroRTT      int64
+	streams      int64
+	streamErrors int64
+}
+
+// http3Conn 为一个 QUIC 连接，seen 为已计入吞吐量的收发字节数
+type http3Conn struct {
+	qc   *quic.Conn
+	cc   *http3.ClientConn
+	seen uint64
+}
+
+// count 将连接上新收发的字节数计入吞吐量，并发调用时只计入一次
+func (hc *http3Conn) count(throughput *int64) {
+	cs := hc.qc.ConnectionStats()
+	n := cs.BytesSent + cs.BytesReceived
+	for {
+		seen := atomic.LoadUint64(&hc.seen)
+		if n <= seen {
+			return
+		}
+		if atomic.CompareAndSwapUint64(&hc.seen, seen, n) {
+			atomic.AddInt64(throughput, int64(n-seen))
+			return
+		}
+	}
+}
+
+// http3Slot 为一个连接的位置，连接关闭后在原位置重新建立
+type http3Slot struct {
+	mut  sync.Mutex
+	conn *http3Conn
+}
+
+// drop 丢弃位置上的连接 hc，下一个请求重新建立连接
+func (s *http3Slot) drop(hc *http3Conn) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.conn == hc {
+		s.conn = nil
+		_ = hc.qc.CloseWithError(0, "")
+	}
+}
+
+// http3Doer 通过 QUIC 使用 HTTP/3 发送请求，请求按顺序轮流使用各连接。
+// 会话票据在各连接间共享，重新建立的连接尝试 0-RTT，GET 与 HEAD 请求在握手完成前即发出
+type http3Doer struct {
+	addr       string
+	timeout    time.Duration
+	stats      *http3Stats
+	phases     *phases
+	throughput *int64
+	tlsConf    *tls.Config
+	quicConf   *quic.Config
+	tr         *http3.Transport
+
+	next  uint64
+	slots []http3Slot
+}
+
+// newHttp3Doer 返回连接到 addr 的 HTTP/3 客户端，连接数为 conns
+func (c *Config) newHttp3Doer(addr string, isTLS bool, conns int) (*http3Doer, error) {
+	if !isTLS {
+		return nil, fmt.Errorf("HTTP/3 requires https, got plain http address %s", addr)
+	}
+
+	conf := &tls.Config{} // #nosec G402
+	if c.tlsConf != nil {
+		conf = c.tlsConf.Clone()
+	}
+	if conf.ClientSessionCache == nil {
+		conf.ClientSessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	conf.NextProtos = []string{http3.NextProtoH3}
+
+	d := &http3Doer{
+		addr:       addr,
+		timeout:    c.Timeout,
+		stats:      c.h3,
+		phases:     c.phases,
+		throughput: &c.throughput,
+		tlsConf:    conf,
+		quicConf:   &quic.Config{HandshakeIdleTimeout: c.Timeout},
+		// 与 fasthttp 一致，不自动添加 Accept-Encoding 与解压响应体
+		tr:    &http3.Transport{DisableCompression: true},
+		slots: make([]http3Slot, conns),
+	}
+	if d.stats == nil {
+		d.stats = &http3Stats{}
+	}
+
+	return d, nil
+}
+
+func (d *http3Doer) Do(req *fasthttp.Request, resp *fasthttp.Response) error {
+	ctx := context.Background()
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	hreq, err := newHttpRequest(ctx, req, "https://"+d.addr)
+	if err != nil {
+		return err
+	}
+	// 幂等请求可以作为 0-RTT 数据发送，连接未使用 0-RTT 时与普通请求相同
+	switch hreq.Method {
+	case http.MethodGet:
+		hreq.Method = http3.MethodGet0RTT
+	case http.MethodHead:
+		hreq.Method = http3.MethodHead0RTT
+	}
+
+	s := &d.slots[int((atomic.AddUint64(&d.next, 1)-1)%uint64(len(d.slots)))]
+	hc, err := d.conn(ctx, s)
+	if err != nil {
+		atomic.AddInt64(&d.stats.connErrors, 1)
+		return err
+	}
+	atomic.AddInt64(&d.stats.streams, 1)
+	defer hc.count(d.throughput)
+
+	hresp, err := hc.cc.RoundTrip(hreq)
+	if err == nil {
+		err = readHttpResponse(hresp, resp)
+	}
+	if err != nil {
+		if errors.Is(err, quic.Err0RTTRejected) {
+			s.drop(hc)
+		}
+		return d.failed(ctx, hc, err)
+	}
+
+	return nil
+}
+
+// DoRedirects 用于调试模式，按 Location 跟随重定向
+func (d *http3Doer) DoRedirects(req *fasthttp.Request, resp *fasthttp.Response, maxRedirects int) error {
+	for i := 0; ; i++ {
+		if err := d.Do(req, resp); err != nil {
+			return err
+		}
+		if !fasthttp.StatusCodeIsRedirect(resp.StatusCode()) {
+			return nil
+		}
+		if i >= maxRedirects {
+			return fasthttp.ErrTooManyRedirects
+		}
+
+		location := resp.Header.Peek(fasthttp.HeaderLocation)
+		if len(location) == 0 {
+			return fasthttp.ErrMissingLocation
+		}
+		req.URI().UpdateBytes(location)
+		if resp.StatusCode() == fasthttp.StatusSeeOther {
+			req.Header.SetMethod(fasthttp.MethodGet)
+			req.ResetBody()
+		}
+	}
+}
+
+// failed 区分流级与连接级错误，连接仍然可用时 HTTP/3 错误视为流被重置，
+// 超时与 fasthttp 的超时错误一致
+func (d *http3Doer) failed(ctx context.Context, hc *http3Conn, err error) error {
+	var he *http3.Error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		atomic.AddInt64(&d.stats.streamErrors, 1)
+		return fasthttp.ErrTimeout
+	case errors.As(err, &he) && hc.qc.Context().Err() == nil:
+		atomic.AddInt64(&d.stats.streamErrors, 1)
+	default:
+		atomic.AddInt64(&d.stats.connErrors, 1)
+	}
+	return err
+}
+
+// conn 返回位置上的连接，连接不存在或已关闭时重新建立
+func (d *http3Doer) conn(ctx context.Context, s *http3Slot) (*http3Conn, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.conn != nil && s.conn.qc.Context().Err() == nil {
+		return s.conn, nil
+	}
+	s.conn = nil
+
+	start := time.Now()
+	qc, err := quic.DialAddrEarly(ctx, d.addr, d.tlsConf, d.quicConf)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&d.stats.conns, 1)
+	go d.handshake(qc, start)
+
+	s.conn = &http3Conn{qc: qc, cc: d.tr.NewClientConn(qc)}
+	return s.conn, nil
+}
+
+// handshake 在握手完成后记录握手耗时与是否使用了 0-RTT，
+// 使用 0-RTT 时连接在握手完成前即可发送请求
+func (d *http3Doer) handshake(qc *quic.Conn, start time.Time) {
+	select {
+	case <-qc.HandshakeComplete():
+	case <-qc.Context().Done():
+		return
+	}
+
+	if d.phases != nil {
+		observe(d.phases.quic, time.Since(start))
+	}
+	if qc.ConnectionState().Used0RTT {
+		atomic.AddInt64(&d.stats.zeroRTT, 1)
+	}
+}
@@ -0,0 +1,162 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// newHttp3Server 在回环地址上启动 HTTP/3 服务端，返回 https 地址
+func newHttp3Server(t *testing.T, h http.Handler) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	srv := &http3.Server{
+		Handler: h,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		}),
+		QUICConfig: &quic.Config{Allow0RTT: true},
+	}
+	go func() { _ = srv.Serve(conn) }()
+	t.Cleanup(func() {
+		_ = srv.Close()
+		_ = conn.Close()
+	})
+
+	return "https://" + conn.LocalAddr().String()
+}
+
+func Test_HttpGo_Run_HTTP3(t *testing.T) {
+	t.Parallel()
+
+	h := newH2Recorder()
+	url := newHttp3Server(t, h)
+
+	t.Run("requests", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:            url + "/echo",
+			Method:         http.MethodPost,
+			Body:           "hello",
+			Host:           "api.example.com",
+			Insecure:       true,
+			HTTP3:          true,
+			StreamsPerConn: 3,
+			Connections:    6,
+			Count:          30,
+			Output:         outputJSON,
+		})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, r.Totals.Requests, r.Codes.Code2xx)
+		assert.Equal(t, 3, r.HTTP3.StreamsPerConn)
+		assert.Equal(t, int64(2), r.HTTP3.Connections)
+		assert.GreaterOrEqual(t, r.HTTP3.Streams, int64(30))
+		assert.Equal(t, int64(0), r.HTTP3.StreamErrors)
+		assert.Greater(t, r.Throughput.Bytes, int64(0))
+		assert.Contains(t, p.stat.output(), "HTTP/3:")
+
+		h.mut.Lock()
+		defer h.mut.Unlock()
+		assert.Equal(t, []string{"HTTP/3.0"}, keys(h.protos))
+		assert.Equal(t, []string{"api.example.com"}, keys(h.hosts))
+		for _, body := range h.bodies {
+			assert.Equal(t, "hello", body)
+		}
+	})
+
+	// 全部请求失败时请求数不会增加，按时长结束
+	t.Run("stream reset", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: url + "/reset", Insecure: true, HTTP3: true, Connections: 2, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Totals.Errors, int64(0))
+		assert.GreaterOrEqual(t, r.HTTP3.StreamErrors, r.Totals.Errors)
+		assert.Equal(t, int64(0), r.HTTP3.ConnectionErrors)
+		assert.Equal(t, int64(2), r.HTTP3.Connections)
+	})
+
+	t.Run("handshake failure", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: url, HTTP3: true, Connections: 1, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.HTTP3.ConnectionErrors, int64(0))
+		assert.Equal(t, int64(0), r.HTTP3.Connections)
+	})
+
+	t.Run("with http2", func(t *testing.T) {
+		p := New(Config{Url: url, HTTP3: true, HTTP2: true})
+		assert.NotNil(t, p.Run())
+	})
+
+	t.Run("plain http", func(t *testing.T) {
+		p := New(Config{Url: "http://127.0.0.1:1", HTTP3: true})
+		assert.NotNil(t, p.Run())
+	})
+}
+
+func Test_http3Doer_zeroRTT(t *testing.T) {
+	t.Parallel()
+
+	url := newHttp3Server(t, newH2Recorder())
+	c := &Config{Url: url, Insecure: true, Timeout: time.Second, phases: newPhases(), h3: &http3Stats{}}
+	c.tlsConf, _ = c.getTlsConfig()
+	d, err := c.newHttp3Doer(url[len("https://"):], true, 1)
+	assert.Nil(t, err)
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI(url + "/")
+
+	assert.Nil(t, d.Do(req, resp))
+	assert.Equal(t, "HTTP/3.0", string(resp.Header.Peek("X-Proto")))
+
+	// 服务端在握手完成后才发送会话票据，关闭连接后重新建立直到使用 0-RTT
+	assert.Eventually(t, func() bool {
+		d.slots[0].drop(d.slots[0].conn)
+		assert.Nil(t, d.Do(req, resp))
+		return atomic.LoadInt64(&c.h3.zeroRTT) > 0
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Greater(t, c.h3.conns, int64(1))
+	assert.Greater(t, c.phases.quic.count(), int64(0))
+}
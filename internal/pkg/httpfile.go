@@ -0,0 +1,306 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// httpFileMethods 为请求行中可以出现的方法，请求行只有 URL 时使用 GET
+var httpFileMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "DELETE": true,
+	"CONNECT": true, "OPTIONS": true, "TRACE": true, "PATCH": true,
+}
+
+// httpFileVars 将 REST Client 与 JetBrains HTTP Client 的动态变量转换为模板变量
+var httpFileVars = map[string]string{
+	"$guid":         "uuid",
+	"$uuid":         "uuid",
+	"$random.uuid":  "uuid",
+	"$timestamp":    "now.unix",
+	"$isoTimestamp": "now",
+	"$randomInt":    "randInt",
+}
+
+// readHttpFile 读取 JetBrains / VS Code REST Client 格式的 .http 文件，
+// 请求之间以 ### 分隔，@name = value 定义的文件变量在读取时替换，
+// 其余 {{变量}} 保留为请求模板
+func readHttpFile(path string) (list []scenario, err error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return
+	}
+
+	var (
+		vars  = make(map[string]string)
+		block []string
+	)
+	flush := func() error {
+		s, ok, err := parseHttpRequest(block, vars, filepath.Dir(path))
+		block = block[:0]
+		if err != nil || !ok {
+			return err
+		}
+		list = append(list, s)
+		return nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if strings.HasPrefix(line, "###") {
+			if err = flush(); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			// ### 之后的文字作为下一个请求的名称
+			if name := strings.TrimSpace(line[3:]); name != "" {
+				block = append(block, "# @name "+name)
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if err = sc.Err(); err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no request in %s", path)
+	}
+
+	return
+}
+
+// parseHttpRequest 解析 ### 之间的一段内容，只有注释与变量定义时返回 false
+func parseHttpRequest(lines []string, vars map[string]string, dir string) (s scenario, ok bool, err error) {
+	i := 0
+	// 请求行之前为注释、变量定义与空行
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if comment, ok := httpFileComment(line); ok {
+			// # @name login 或 # @name=login 为请求命名
+			if name, found := strings.CutPrefix(comment, "@name"); found {
+				s.Name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "="))
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "@") {
+			k, v, found := strings.Cut(line[1:], "=")
+			if !found {
+				return s, false, fmt.Errorf("invalid variable definition %q", line)
+			}
+			vars[strings.TrimSpace(k)] = expandHttpFileVars(strings.TrimSpace(v), vars)
+			continue
+		}
+		break
+	}
+	if i == len(lines) {
+		return s, false, nil
+	}
+
+	// 请求行：[METHOD] URL [HTTP-Version]
+	fields := strings.Fields(expandHttpFileVars(lines[i], vars))
+	if len(fields) > 0 && httpFileMethods[strings.ToUpper(fields[0])] {
+		s.Method, fields = strings.ToUpper(fields[0]), fields[1:]
+	}
+	if len(fields) > 0 && strings.HasPrefix(fields[len(fields)-1], "HTTP/") {
+		fields = fields[:len(fields)-1]
+	}
+	if len(fields) != 1 {
+		return s, false, fmt.Errorf("invalid request line %q", lines[i])
+	}
+	s.Url = fields[0]
+
+	// 以 ? 或 & 开头的行为查询参数的续行
+	for i++; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || (line[0] != '?' && line[0] != '&') {
+			break
+		}
+		s.Url += expandHttpFileVars(line, vars)
+	}
+
+	for ; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			i++
+			break
+		}
+		if _, ok := httpFileComment(line); ok {
+			continue
+		}
+		if !strings.Contains(line, ":") {
+			return s, false, fmt.Errorf("invalid header %q", line)
+		}
+		s.Headers = append(s.Headers, expandHttpFileVars(line, vars))
+	}
+
+	s.Body, s.File = httpFileBody(lines[i:], vars, dir)
+	one := 1
+	s.Weight = &one
+
+	return s, true, nil
+}
+
+// httpFileComment 判断是否为 # 或 // 开头的注释，返回注释内容
+func httpFileComment(line string) (string, bool) {
+	if strings.HasPrefix(line, "#") {
+		return strings.TrimSpace(line[1:]), true
+	}
+	if strings.HasPrefix(line, "//") {
+		return strings.TrimSpace(line[2:]), true
+	}
+	return "", false
+}
+
+// httpFileBody 返回请求体，以 < 开头的单行请求体表示从文件读取（相对 .http 文件所在目录），
+// 以 > 开头的响应处理脚本及之后的内容被忽略
+func httpFileBody(lines []string, vars map[string]string, dir string) (body, file string) {
+	end := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "> ") || strings.HasPrefix(line, ">>") {
+			end = i
+			break
+		}
+	}
+	for end > 0 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	lines = lines[:end]
+
+	if len(lines) == 1 && strings.HasPrefix(lines[0], "< ") {
+		file = strings.TrimSpace(lines[0][2:])
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		return "", file
+	}
+
+	return expandHttpFileVars(strings.Join(lines, "\n"), vars), ""
+}
+
+// expandHttpFileVars 替换 s 中的文件变量与动态变量，其余变量原样保留
+func expandHttpFileVars(s string, vars map[string]string) string {
+	if !strings.Contains(s, "{{") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "{{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(s[i+2:], "}}")
+		if j < 0 {
+			break
+		}
+
+		b.WriteString(s[:i])
+		expr := strings.TrimSpace(s[i+2 : i+2+j])
+		fields := strings.Fields(expr)
+		if v, ok := vars[expr]; ok {
+			b.WriteString(v)
+		} else if len(fields) > 0 && httpFileVars[fields[0]] != "" {
+			b.WriteString("{{")
+			b.WriteString(httpFileVar(fields))
+			b.WriteString("}}")
+		} else {
+			b.WriteString(s[i : i+2+j+2])
+		}
+		s = s[i+2+j+2:]
+	}
+	b.WriteString(s)
+
+	return b.String()
+}
+
+// httpFileVar 转换动态变量，$randomInt 的上限不包含在内
+func httpFileVar(fields []string) string {
+	name := httpFileVars[fields[0]]
+	if name != "randInt" || len(fields) != 3 {
+		return strings.Join(append([]string{name}, fields[1:]...), " ")
+	}
+	max, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return strings.Join(append([]string{name}, fields[1:]...), " ")
+	}
+	return name + " " + fields[1] + " " + strconv.Itoa(max-1)
+}
+
+// selectHttpRequests 按 --name 选择请求，每项格式为 名称[:权重]，
+// 未指定时使用文件中的所有请求
+func selectHttpRequests(list []scenario, names []string) ([]scenario, error) {
+	if len(names) == 0 {
+		return list, nil
+	}
+
+	byName := make(map[string]scenario, len(list))
+	for _, s := range list {
+		if s.Name == "" {
+			continue
+		}
+		if _, ok := byName[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate request name %q", s.Name)
+		}
+		byName[s.Name] = s
+	}
+
+	selected := make([]scenario, 0, len(names))
+	for _, item := range names {
+		// 名称本身可以包含冒号，只有与名称不完全匹配且最后一个冒号之后为整数时才视为权重
+		name, weight := item, 1
+		if _, ok := byName[item]; !ok {
+			if i := strings.LastIndex(item, ":"); i >= 0 {
+				if w, err := strconv.Atoi(item[i+1:]); err == nil {
+					if w <= 0 {
+						return nil, fmt.Errorf("invalid weight in --name %q", item)
+					}
+					name, weight = item[:i], w
+				}
+			}
+		}
+
+		s, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("no request named %q", name)
+		}
+		s.Weight = &weight
+		selected = append(selected, s)
+	}
+
+	return selected, nil
+}
+
+// initHttpFile 读取 .http 文件，按 --name 选择请求并为每个请求构建请求模板
+func (c *httpClient) initHttpFile(conf *Config) (err error) {
+	if conf.Debug {
+		return errors.New("debug mode does not support .http files")
+	}
+
+	var list []scenario
+	if list, err = readHttpFile(conf.HttpFile); err != nil {
+		return
+	}
+	if list, err = selectHttpRequests(list, conf.Names); err != nil {
+		return
+	}
+	if conf.tlsConf, err = conf.getTlsConfig(); err != nil {
+		return
+	}
+	c.targets, c.weights, err = conf.scenarioTargets(list)
+
+	return
+}
@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testHttpFile = `@host = https://example.com
+@token = abc
+@auth = Bearer {{token}}
+
+# @name login
+POST {{host}}/login HTTP/1.1
+Content-Type: application/json
+// 注释
+Authorization: {{auth}}
+
+{
+  "user": "bob",
+  "id": "{{$guid}}"
+}
+
+> {% client.global.set("token", response.body.token); %}
+
+### search
+GET {{host}}/search
+    ?q=go
+    &n={{$randomInt 1 10}}
+Accept: */*
+
+###
+
+// 只有注释的段落被忽略
+
+###
+PUT /upload
+Host: upload.example.com
+
+< ./body.bin
+`
+
+func Test_readHttpFile(t *testing.T) {
+	t.Parallel()
+
+	path := writeScenario(t, "api.http", testHttpFile)
+	list, err := readHttpFile(path)
+	assert.Nil(t, err)
+	assert.Len(t, list, 3)
+
+	login := list[0]
+	assert.Equal(t, "login", login.Name)
+	assert.Equal(t, "POST", login.Method)
+	assert.Equal(t, "https://example.com/login", login.Url)
+	assert.Equal(t, []string{"Content-Type: application/json", "Authorization: Bearer abc"}, login.Headers)
+	assert.Equal(t, "{\n  \"user\": \"bob\",\n  \"id\": \"{{uuid}}\"\n}", login.Body)
+	assert.Equal(t, 1, *login.Weight)
+
+	search := list[1]
+	assert.Equal(t, "search", search.Name)
+	assert.Equal(t, "GET", search.Method)
+	assert.Equal(t, "https://example.com/search?q=go&n={{randInt 1 9}}", search.Url)
+	assert.Equal(t, []string{"Accept: */*"}, search.Headers)
+	assert.Equal(t, "", search.Body)
+
+	upload := list[2]
+	assert.Equal(t, "", upload.Name)
+	assert.Equal(t, "/upload", upload.Url)
+	assert.Equal(t, filepath.Join(filepath.Dir(path), "body.bin"), upload.File)
+
+	for _, content := range []string{
+		"",
+		"# only comment",
+		"@broken\nGET /a",
+		"GET /a b HTTP/1.1",
+		"GET /a\nbroken header",
+	} {
+		_, err = readHttpFile(writeScenario(t, "a.http", content))
+		assert.NotNil(t, err, content)
+	}
+
+	_, err = readHttpFile("not-exist.http")
+	assert.NotNil(t, err)
+}
+
+func Test_expandHttpFileVars(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]string{"a": "1", "empty": ""}
+	assert.Equal(t, "x", expandHttpFileVars("x", vars))
+	assert.Equal(t, "1-{{seq}}--{{now.unix}}-{{}}-{{a", expandHttpFileVars("{{a}}-{{seq}}-{{ empty }}-{{$timestamp}}-{{}}-{{a", vars))
+	assert.Equal(t, "{{randInt}}", expandHttpFileVars("{{$randomInt}}", vars))
+}
+
+func Test_selectHttpRequests(t *testing.T) {
+	t.Parallel()
+
+	one := 1
+	list := []scenario{{Name: "a", Weight: &one}, {Name: "b", Weight: &one}, {Weight: &one}}
+
+	selected, err := selectHttpRequests(list, nil)
+	assert.Nil(t, err)
+	assert.Len(t, selected, 3)
+
+	selected, err = selectHttpRequests(list, []string{"b:3", "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "b", selected[0].Name)
+	assert.Equal(t, 3, *selected[0].Weight)
+	assert.Equal(t, 1, *selected[1].Weight)
+	assert.Equal(t, 1, *list[1].Weight)
+
+	for _, names := range [][]string{{"c"}, {"a:0"}, {"a:-1"}, {"a:x"}} {
+		_, err = selectHttpRequests(list, names)
+		assert.NotNil(t, err, names)
+	}
+
+	// 名称中的冒号之后不是整数时整体作为名称
+	list = append(list, scenario{Name: "api:v2", Weight: &one}, scenario{Name: "api:2", Weight: &one})
+	selected, err = selectHttpRequests(list, []string{"api:v2", "api:v2:4", "api:2", "api:2:5"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"api:v2", "api:v2", "api:2", "api:2"}, []string{selected[0].Name, selected[1].Name, selected[2].Name, selected[3].Name})
+	assert.Equal(t, []int{1, 4, 1, 5}, []int{*selected[0].Weight, *selected[1].Weight, *selected[2].Weight, *selected[3].Weight})
+
+	_, err = selectHttpRequests(append(list, scenario{Name: "a", Weight: &one}), []string{"b"})
+	assert.EqualError(t, err, `duplicate request name "a"`)
+}
+
+func Test_HttpGo_Run_HttpFile(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	path := writeScenario(t, "api.http", `@token = abc
+### ok
+GET /ok
+Authorization: Bearer {{token}}
+
+### denied
+POST /denied
+`)
+
+	var buf bytes.Buffer
+	p := New(Config{Url: srv.URL, HttpFile: path, Names: []string{"ok:3", "denied"}, Output: outputJSON, Count: 8, Connections: 1})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	r := p.report()
+	assert.Equal(t, path, r.Config.HttpFile)
+	assert.Len(t, r.Endpoints, 2)
+	assert.Equal(t, "ok", r.Endpoints[0].Name)
+	assert.Equal(t, int64(6), r.Endpoints[0].Codes.Code2xx)
+	assert.Equal(t, int64(2), r.Endpoints[1].Codes.Code4xx)
+
+	assert.NotNil(t, New(Config{HttpFile: path, Names: []string{"missing"}}).Run())
+	assert.NotNil(t, New(Config{HttpFile: path, Debug: true}).Run())
+	assert.NotNil(t, New(Config{HttpFile: path, Har: path}).Run())
+}
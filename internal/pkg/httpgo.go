@@ -2,9 +2,13 @@ package pkg
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"os/signal"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -19,6 +23,13 @@ const (
 	defaultDuration     = time.Second * 10
 	defaultTimeout      = time.Second * 3
 	defaultMaxRedirects = 30
+	defaultMaxInFlight  = 1024
+)
+
+// 结果输出格式
+const (
+	outputTUI  = "tui"
+	outputJSON = "json"
 )
 
 // HttpGo denotes httpgo application
@@ -29,10 +40,20 @@ type HttpGo struct {
 	wg sync.WaitGroup
 
 	mut       sync.Mutex
+	beginTime time.Time
 	startTime time.Time
 	roundReqs int64
 	done      bool
 	doneChan  chan struct{}
+	// exhausted 表示按顺序读取的数据文件已用完，worker 完成手头的请求后退出
+	exhausted atomic.Bool
+	asserts   []assertion
+	// replay 不为 nil 时按 HAR 中记录的时间回放请求
+	replay *harReplay
+	// ws 不为 nil 时压测 WebSocket 服务
+	ws *wsBench
+	// sse 不为 nil 时压测 SSE 服务
+	sse *sseBench
 	*stat
 }
 
@@ -60,11 +81,51 @@ func New(c Config) *HttpGo {
 		p.c.MaxRedirects = defaultMaxRedirects
 	}
 
+	if p.c.Speed <= 0 {
+		p.c.Speed = 1
+	}
+
+	if p.c.openModel() && p.c.MaxInFlight <= 0 {
+		p.c.MaxInFlight = defaultMaxInFlight
+	}
+
+	p.c.phases = newPhases()
+	if p.c.AcceptEncoding != "" {
+		p.c.bodies = &bodyCounter{}
+	}
+	if p.c.HTTP2 {
+		p.c.h2 = &http2Stats{}
+	}
+	if p.c.HTTP3 {
+		p.c.h3 = &http3Stats{}
+	}
+	if p.c.WebSocket {
+		p.c.ws = newWsStats()
+	}
+	if p.c.SSE {
+		p.c.sse = newSseStats()
+	}
+	if p.c.GRPC {
+		p.c.grpc = &grpcStats{}
+	}
+	if (p.c.HTTP2 || p.c.HTTP3 || p.c.GRPC) && p.c.StreamsPerConn <= 0 {
+		p.c.StreamsPerConn = 1
+	}
+
 	p.stat = newStat()
 	p.stat.count = p.c.Count
 	p.stat.duration = p.c.Duration
 	p.stat.connections = p.c.Connections
+	p.stat.rate = p.c.Rate
+	p.stat.maxInFlight = p.c.MaxInFlight
 	p.stat.throughput = &p.c.throughput
+	p.stat.phases = p.c.phases
+	p.stat.bodies = p.c.bodies
+	p.stat.h2 = p.c.h2
+	p.stat.h3 = p.c.h3
+	p.stat.ws = p.c.ws
+	p.stat.sse = p.c.sse
+	p.stat.grpc = p.c.grpc
 	p.initCmd = p.run
 
 	return p
@@ -80,28 +141,183 @@ func (p *HttpGo) Run() (err error) {
 		return p.doOnce()
 	}
 
-	return p.stat.start()
+	if p.c.Output == outputJSON || p.c.NoTUI {
+		err = p.runHeadless()
+	} else {
+		err = p.stat.start()
+	}
+	if err != nil {
+		return
+	}
+
+	return p.checkAssertions()
+}
+
+// runHeadless 不启动交互界面执行压测，定期向标准错误输出进度，
+// 收到中断信号时提前结束
+func (p *HttpGo) runHeadless() error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	finished := make(chan struct{})
+	go func() {
+		p.run()
+		close(finished)
+	}()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-finished:
+			p.stat.done = true
+			break loop
+		case <-sig:
+			p.stop()
+			<-finished
+			p.stat.quitting = true
+			break loop
+		case <-ticker.C:
+			p.stat.writeProgress()
+		}
+	}
+
+	if p.c.Output == outputJSON {
+		return p.writeReport()
+	}
+
+	return p.stat.writePlain()
 }
 
 func (p *HttpGo) init() (err error) {
-	if p.c.Url == "" {
+	if p.c.Url == "" && p.c.requestsFile() == "" && p.c.Raw == "" {
 		return errors.New("缺少 URL 参数")
 	}
+	if err = p.c.checkRequestsFile(); err != nil {
+		return
+	}
+
+	switch p.c.Output {
+	case "", outputTUI, outputJSON:
+	default:
+		return fmt.Errorf("unsupported output format %q. tui and json are supported", p.c.Output)
+	}
+
+	for _, expr := range p.c.Asserts {
+		var a assertion
+		if a, err = parseAssertion(expr); err != nil {
+			return
+		}
+		if a.metric == "ok_ratio" && !p.c.GRPC {
+			return fmt.Errorf("assertion %q only applies in grpc mode", expr)
+		}
+		p.asserts = append(p.asserts, a)
+	}
 
+	if err = p.c.unixUrl(); err != nil {
+		return
+	}
 	p.c.Url = addMissingSchemaAndHost(p.c.Url)
 	p.stat.url = p.c.Url
+	if f := p.c.requestsFile(); f != "" {
+		p.stat.url = f
+	}
+	if p.c.UnixSocket != "" {
+		if p.c.HTTP3 || p.c.HttpProxy != "" || p.c.SocksProxy != "" {
+			return errors.New("--unix-socket cannot be used with --http3, --httpProxy or --socksProxy")
+		}
+		p.stat.url += " via unix:" + p.c.UnixSocket
+	}
+
+	if p.c.Rate > 0 && p.c.Qps > 0 {
+		return errors.New("--rate and --qps cannot be used together")
+	}
+	if p.c.HTTP2 && p.c.Pipeline {
+		return errors.New("--http2 and --pipeline cannot be used together")
+	}
+	if p.c.HTTP3 && (p.c.HTTP2 || p.c.Pipeline) {
+		return errors.New("--http3 cannot be used with --http2 or --pipeline")
+	}
 
-	if p.c.Qps > 0 {
+	if p.c.Stages != "" {
+		if err = p.initStages(); err != nil {
+			return
+		}
+	} else if p.c.Qps > 0 {
 		p.limiter = newTokenLimiter(p.c.Qps)
 	}
 
+	if p.c.WebSocket {
+		return p.initWebSocket()
+	}
+	if p.c.GRPC {
+		return p.initGrpc()
+	}
+	if p.c.SSE {
+		if err = p.initSSE(); err != nil {
+			return
+		}
+	}
+
 	if p.client == nil {
-		p.client, err = newHttpClient(p.c)
+		var hc *httpClient
+		if hc, err = newHttpClient(p.c); err != nil {
+			return
+		}
+		p.client = hc
+		p.stat.endpoints = hc.endpoints()
+		// 原始请求未指定 URL 时，目标地址在读取请求后才确定
+		if p.c.Raw != "" {
+			p.stat.url = p.c.Url
+		}
+		if p.c.Har != "" {
+			err = p.initHar(hc)
+		}
+		if p.c.SSE {
+			p.sse = newSseBench(p, hc)
+		}
 	}
 
 	return
 }
 
+// initStages 解析负载曲线，按目标值作用的对象调整限流器、连接数或到达速率，
+// 压测持续时间为各阶段时长之和
+func (p *HttpGo) initStages() error {
+	if p.c.Qps > 0 || p.c.Rate > 0 {
+		return errors.New("--stages cannot be used with --qps or --rate, use --stageBy instead")
+	}
+
+	list, err := parseStages(p.c.Stages)
+	if err != nil {
+		return err
+	}
+
+	s := newStages(list, p.c.StageBy)
+	switch s.by {
+	case stageByQps:
+		p.limiter = newStageLimiter(s)
+	case stageByRate:
+		p.stat.rate = s.max()
+	case stageByConnections:
+		if p.c.Connections = s.max(); p.c.Connections == 0 {
+			return errors.New("--stages needs at least one stage with connections")
+		}
+		p.stat.connections = p.c.Connections
+	default:
+		return fmt.Errorf("unsupported stage target %q. qps, rate and connections are supported", s.by)
+	}
+
+	p.c.Duration = s.total()
+	p.stat.duration = p.c.Duration
+	p.stat.stages = s
+
+	return nil
+}
+
 func addMissingSchemaAndHost(url string) string {
 	if !strings.HasPrefix(url, "://") && strings.HasPrefix(url, ":") {
 		return "http://localhost" + url
@@ -119,17 +335,40 @@ func addMissingSchemaAndHost(url string) string {
 
 func (p *HttpGo) run() tea.Msg {
 	p.startTime = time.Now()
+	p.beginTime = p.startTime
+	if p.stages != nil {
+		p.stages.begin(p.beginTime)
+		// 负载曲线结束后可能不再有请求，按时长结束压测
+		timer := time.AfterFunc(p.stages.total(), p.stop)
+		defer timer.Stop()
+	}
+
+	if p.ws != nil {
+		p.ws.run()
+		return done
+	}
+	if p.sse != nil {
+		p.sse.run()
+		return done
+	}
+
+	if p.c.openModel() {
+		newArrival(p).run()
+		return done
+	}
+
 	n := p.c.Connections
 	p.wg.Add(n)
 	for i := 0; i < n; i++ {
-		go p.worker()
+		go p.worker(i)
 	}
 	p.wg.Wait()
+	p.stop()
 
 	return done
 }
 
-func (p *HttpGo) worker() {
+func (p *HttpGo) worker(id int) {
 	defer p.wg.Done()
 
 	for {
@@ -137,22 +376,65 @@ func (p *HttpGo) worker() {
 		case <-p.doneChan:
 			return
 		default:
-			if p.limiter.allow() {
-				p.statistic(p.do())
+			if p.stages != nil && p.stages.by == stageByConnections && !p.stages.active(id) {
+				time.Sleep(interval)
+				continue
+			}
+			if intended, ok := p.limiter.allow(); ok {
+				p.request(intended)
+			}
+			if p.exhausted.Load() {
+				return
 			}
 		}
 	}
 }
 
-const interval = time.Millisecond * 10
+// request 发送一次请求并记录结果，intended 为请求计划的发送时间，
+// 非零时额外记录修正协调遗漏后的延迟
+func (p *HttpGo) request(intended time.Time) {
+	ep, code, latency, err := p.do()
+	p.result(intended, ep, code, latency, err)
+}
+
+// result 记录 ep 上一次请求的结果，intended 非零时额外记录修正后的延迟
+func (p *HttpGo) result(intended time.Time, ep *endpoint, code int, latency time.Duration, err error) {
+	// 按顺序读取的数据文件用完后不再发起请求，不计为错误
+	if errors.Is(err, errDataExhausted) {
+		p.exhausted.Store(true)
+		return
+	}
+	p.record(intended, ep, code, latency, err)
+}
+
+const (
+	interval         = time.Millisecond * 10
+	progressInterval = time.Second
+)
 
 func (p *HttpGo) statistic(code int, latency time.Duration, err error) {
+	p.record(time.Time{}, nil, code, latency, err)
+}
+
+// record 记录一次请求的结果，ep 不为 nil 时同时计入对应端点，
+// intended 非零时额外记录修正后的延迟，与原始延迟使用相同的样本
+func (p *HttpGo) record(intended time.Time, ep *endpoint, code int, latency time.Duration, err error) {
+	var sinceIntended time.Duration
+	if !intended.IsZero() {
+		sinceIntended = time.Since(intended)
+	}
+
 	p.mut.Lock()
 	defer p.mut.Unlock()
 	if p.done {
 		return
 	}
 
+	if ep != nil {
+		ep.record(code, latency, err)
+	}
+
+	elapsed := time.Since(p.startTime)
 	if err != nil {
 		p.appendError(err)
 	} else {
@@ -160,13 +442,19 @@ func (p *HttpGo) statistic(code int, latency time.Duration, err error) {
 		atomic.AddInt64(&p.reqs, 1)
 		p.appendCode(code)
 		p.appendLatency(latency)
+		if !intended.IsZero() {
+			p.appendCorrectedLatency(sinceIntended, latency)
+		}
+		if !p.beginTime.IsZero() {
+			p.appendSecond(time.Since(p.beginTime))
+		}
 	}
 
-	elapsed := time.Since(p.startTime)
 	if p.c.Count > 0 && atomic.LoadInt64(&p.reqs) == int64(p.c.Count) {
 		p.appendRps(float64(p.roundReqs) / elapsed.Seconds())
-		p.done = true
-		close(p.doneChan)
+		// 最后一个不满 interval 的窗口同样计入总耗时，否则短时间的压测耗时为 0
+		atomic.AddInt64(&p.elapsed, int64(elapsed))
+		p.finish()
 		return
 	}
 
@@ -180,7 +468,21 @@ func (p *HttpGo) statistic(code int, latency time.Duration, err error) {
 	}
 
 	if p.c.Count <= 0 && atomic.LoadInt64(&p.elapsed) >= int64(p.c.Duration) {
-		p.done = true
-		close(p.doneChan)
+		p.finish()
 	}
 }
+
+// stop 通知所有 worker 退出
+func (p *HttpGo) stop() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if !p.done {
+		p.finish()
+	}
+}
+
+// finish 标记压测结束，调用方需持有 p.mut
+func (p *HttpGo) finish() {
+	p.done = true
+	close(p.doneChan)
+}
@@ -1,9 +1,18 @@
 package pkg
 
 import (
+	"bytes"
 	"errors"
 	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -26,6 +35,34 @@ func Test_HttpGo_Run(t *testing.T) {
 		assert.Nil(t, p.Run())
 	})
 
+	t.Run("json output", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: "url", Output: outputJSON, Count: 2, Connections: 1})
+		p.stat.w = &buf
+		p.client = newFakeClient()
+
+		assert.Nil(t, p.Run())
+		assert.Contains(t, buf.String(), `"status": "done"`)
+		assert.Contains(t, buf.String(), `"requests": 2`)
+	})
+
+	t.Run("no tui", func(t *testing.T) {
+		var buf, progress bytes.Buffer
+		p := New(Config{Url: "url", NoTUI: true, Count: 2, Connections: 1})
+		p.stat.w = &buf
+		p.stat.ew = &progress
+		p.client = newFakeClient()
+
+		assert.Nil(t, p.Run())
+		assert.Contains(t, buf.String(), "Requests:  2/2")
+		assert.Contains(t, buf.String(), "Done!")
+	})
+
+	t.Run("unsupported output", func(t *testing.T) {
+		p := New(Config{Url: "url", Output: "xml"})
+		assert.NotNil(t, p.Run())
+	})
+
 	t.Run("success", func(t *testing.T) {
 		p := New(Config{Url: "url"})
 		p.stat.initCmd = func() tea.Msg {
@@ -40,6 +77,63 @@ func Test_HttpGo_Run(t *testing.T) {
 	})
 }
 
+func Test_HttpGo_Run_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("skip windows test")
+	}
+
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	assert.Nil(t, err)
+
+	var mut sync.Mutex
+	hosts := map[string]bool{}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		hosts[r.Host+r.URL.Path] = true
+		mut.Unlock()
+	}))
+	ts.Listener = ln
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	t.Run("flag", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: "http://api.internal/a", UnixSocket: sock, Count: 5, Connections: 2, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(5), r.Codes.Code2xx)
+		assert.Greater(t, r.Throughput.Bytes, int64(0))
+		assert.Equal(t, sock, r.Config.UnixSocket)
+		assert.Contains(t, p.stat.url, "via unix:"+sock)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		var buf bytes.Buffer
+		u := "http+unix://" + url.PathEscape(sock) + "/b"
+		p := New(Config{Url: u, Host: "override.internal", Count: 5, Connections: 1, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(5), r.Codes.Code2xx)
+		assert.Equal(t, "http://localhost/b", r.Config.Url)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NotNil(t, New(Config{Url: "https://localhost", UnixSocket: sock, HTTP3: true}).Run())
+		assert.NotNil(t, New(Config{Url: "http://localhost", UnixSocket: sock, SocksProxy: "socks5://127.0.0.1:1080"}).Run())
+	})
+
+	mut.Lock()
+	defer mut.Unlock()
+	assert.ElementsMatch(t, []string{"api.internal/a", "override.internal/b"}, keys(hosts))
+}
+
 func Test_Pit_Init(t *testing.T) {
 	t.Parallel()
 
@@ -76,6 +170,70 @@ func Test_Pit_Internal_Run(t *testing.T) {
 	assert.Equal(t, done, p.run().(int))
 }
 
+func Test_Pit_Internal_Run_Qps(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Qps: 100, Count: 3, Connections: 1})
+	assert.Nil(t, p.init())
+	p.client = newFakeClient()
+	assert.Equal(t, done, p.run().(int))
+	assert.Equal(t, int64(3), p.stat.corrected.count())
+}
+
+func Test_HttpGo_Run_Scenario(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	path := writeScenario(t, "s.yaml", `
+requests:
+  - {name: ok, url: /ok, weight: 3}
+  - {name: missing, url: /missing}
+`)
+
+	var buf bytes.Buffer
+	p := New(Config{Url: srv.URL, Scenario: path, Output: outputJSON, Count: 8, Connections: 1})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	r := p.report()
+	assert.Len(t, r.Endpoints, 2)
+	assert.Equal(t, int64(6), r.Endpoints[0].Codes.Code2xx)
+	assert.Equal(t, int64(2), r.Endpoints[1].Codes.Code4xx)
+	assert.Equal(t, path, p.stat.url)
+	assert.Contains(t, buf.String(), `"name": "missing"`)
+}
+
+func Test_HttpGo_Run_DataSequential(t *testing.T) {
+	t.Parallel()
+
+	var mut sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		paths = append(paths, r.URL.Path)
+		mut.Unlock()
+	}))
+	defer srv.Close()
+
+	path := writeScenario(t, "users.csv", "id\n1\n2\n3\n")
+
+	var buf bytes.Buffer
+	p := New(Config{Url: srv.URL + "/users/{{data.id}}", Data: path, DataMode: dataSequential, Output: outputJSON, Count: 100, Connections: 2})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	sort.Strings(paths)
+	assert.Equal(t, []string{"/users/1", "/users/2", "/users/3"}, paths)
+	assert.Equal(t, int64(3), p.report().Totals.Requests)
+	assert.Equal(t, int64(0), p.report().Totals.Errors)
+}
+
 func Test_Pit_Statistic(t *testing.T) {
 	t.Parallel()
 
@@ -95,9 +253,11 @@ func Test_Pit_Statistic(t *testing.T) {
 	t.Run("reach count", func(t *testing.T) {
 		p := New(Config{})
 		p.c.Count = 1
+		p.startTime = time.Now().Add(-time.Millisecond * 5)
 		p.statistic(200, time.Millisecond, nil)
 		assert.Equal(t, int64(1), p.stat.code2xx)
-		assert.Equal(t, 1, len(p.stat.latencies))
+		assert.Equal(t, int64(1), p.stat.latency.count())
+		assert.GreaterOrEqual(t, p.stat.elapsed, int64(time.Millisecond*5))
 		assert.True(t, p.done)
 	})
 
@@ -107,9 +267,38 @@ func Test_Pit_Statistic(t *testing.T) {
 		p.c.Duration = time.Millisecond * 10
 		p.statistic(200, time.Millisecond, nil)
 		assert.Equal(t, int64(1), p.stat.code2xx)
-		assert.Equal(t, 1, len(p.stat.latencies))
+		assert.Equal(t, int64(1), p.stat.latency.count())
 		assert.True(t, p.done)
 	})
+
+	// 修正后的延迟与原始延迟使用相同的样本，结束后以及出错时都不记录
+	t.Run("corrected latency", func(t *testing.T) {
+		p := New(Config{Qps: 10})
+		intended := time.Now().Add(-time.Millisecond * 5)
+		p.result(intended, nil, 200, time.Millisecond, nil)
+		p.result(intended, nil, 0, 0, errors.New(""))
+		p.stop()
+		p.result(intended, nil, 200, time.Millisecond, nil)
+
+		assert.Equal(t, int64(1), p.stat.latency.count())
+		assert.Equal(t, int64(1), p.stat.corrected.count())
+		assert.GreaterOrEqual(t, p.stat.corrected.maxValue(), int64(5000))
+	})
+}
+
+func Test_HttpGo_stop(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{})
+	p.stop()
+	p.stop()
+	assert.True(t, p.done)
+
+	select {
+	case <-p.doneChan:
+	default:
+		t.Fatal("done channel should be closed")
+	}
 }
 
 func Test_addMissingSchemaAndHost(t *testing.T) {
@@ -150,9 +339,9 @@ func newFakeClient(err ...error) *fakeClient {
 	return &fakeClient{err: e}
 }
 
-func (fc *fakeClient) do() (int, time.Duration, error) {
+func (fc *fakeClient) do() (*endpoint, int, time.Duration, error) {
 	atomic.AddInt64(&fc.count, 1)
-	return 0, 0, nil
+	return nil, 0, 0, nil
 }
 
 func (fc *fakeClient) doOnce() error {
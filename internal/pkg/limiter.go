@@ -2,35 +2,42 @@ package pkg
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// limiter 控制请求的发送速率，allow 返回请求按速率计划应当发送的时间，
+// 用于计算修正协调遗漏（coordinated omission）后的延迟，无计划时返回零值
 type limiter interface {
-	allow() bool
+	allow() (time.Time, bool)
 }
 
 type nopeLimiter bool
 
-func (nopeLimiter) allow() bool { return true }
+func (nopeLimiter) allow() (time.Time, bool) { return time.Time{}, true }
 
 type tokenLimiter struct {
-	mut   sync.Mutex
-	limit float64
-	burst int
-	token int
-	last  time.Time
+	mut    sync.Mutex
+	limit  float64
+	burst  int
+	token  int
+	last   time.Time
+	start  time.Time
+	issued int64
 }
 
 func newTokenLimiter(qps int) *tokenLimiter {
+	now := time.Now()
 	return &tokenLimiter{
 		limit: float64(qps),
 		burst: qps,
 		token: qps,
-		last:  time.Now(),
+		last:  now,
+		start: now,
 	}
 }
 
-func (t *tokenLimiter) allow() bool {
+func (t *tokenLimiter) allow() (time.Time, bool) {
 	t.mut.Lock()
 	defer t.mut.Unlock()
 
@@ -41,10 +48,18 @@ func (t *tokenLimiter) allow() bool {
 	if t.token < t.burst {
 		t.token++
 		t.last = time.Now()
-		return true
+		return t.intended(), true
 	}
 
-	return false
+	return time.Time{}, false
+}
+
+// intended 返回第 issued 个请求按固定速率计划的发送时间，
+// 令牌初始为满，第一个请求在 1/limit 秒后才被允许
+func (t *tokenLimiter) intended() time.Time {
+	t.issued++
+	d := time.Duration(float64(t.issued) / t.limit * float64(time.Second))
+	return t.start.Add(d)
 }
 
 func (t *tokenLimiter) revoked(d time.Duration) int {
@@ -52,3 +67,38 @@ func (t *tokenLimiter) revoked(d time.Duration) int {
 	nsec := float64(d%time.Second) * t.limit
 	return int(sec + nsec/1e9)
 }
+
+// stageLimiter 按负载曲线计划每个请求的发送时间，未到计划时间时不允许发送，
+// 并等待至多 interval 以免 worker 在低速率阶段空转
+type stageLimiter struct {
+	s      *stages
+	issued int64
+}
+
+func newStageLimiter(s *stages) *stageLimiter {
+	return &stageLimiter{s: s}
+}
+
+func (l *stageLimiter) allow() (time.Time, bool) {
+	n := atomic.LoadInt64(&l.issued)
+	off, ok := l.s.offset(float64(n))
+	if !ok {
+		time.Sleep(interval)
+		return time.Time{}, false
+	}
+
+	intended := l.s.startTime().Add(off)
+	if wait := time.Until(intended); wait > 0 {
+		if wait > interval {
+			wait = interval
+		}
+		time.Sleep(wait)
+		return time.Time{}, false
+	}
+
+	if !atomic.CompareAndSwapInt64(&l.issued, n, n+1) {
+		return time.Time{}, false
+	}
+
+	return intended, true
+}
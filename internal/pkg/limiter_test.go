@@ -17,7 +17,9 @@ func Test_nopeLimiter_allow(t *testing.T) {
     for i := 0; i < 100; i++ {
         go func() {
             defer wg.Done()
-            assert.True(t, nope.allow())
+            intended, ok := nope.allow()
+            assert.True(t, ok)
+            assert.True(t, intended.IsZero())
         }()
     }
     wg.Wait()
@@ -30,7 +32,7 @@ func Test_tokenLimiter_allow(t *testing.T) {
     lim := newTokenLimiter(qps)
     lim.token = 0
 
-    assert.True(t, lim.allow())
+    assert.True(t, allowed(lim))
     // cover exceed revoke
     time.Sleep(oneTokenDuration * 2)
 
@@ -39,12 +41,55 @@ func Test_tokenLimiter_allow(t *testing.T) {
     for i := 0; i < qps; i++ {
         go func() {
             defer wg.Done()
-            assert.True(t, lim.allow())
+            assert.True(t, allowed(lim))
         }()
     }
     wg.Wait()
 
-    assert.False(t, lim.allow())
+    assert.False(t, allowed(lim))
     time.Sleep(oneTokenDuration)
-    assert.True(t, lim.allow())
+    assert.True(t, allowed(lim))
+}
+
+func Test_tokenLimiter_intended(t *testing.T) {
+    t.Parallel()
+
+    lim := newTokenLimiter(10)
+    lim.token = 0
+
+    var last time.Time
+    for i := 0; i < 5; i++ {
+        intended, ok := lim.allow()
+        assert.True(t, ok)
+        assert.Equal(t, lim.start.Add(time.Duration(i+1)*time.Second/10), intended)
+        assert.True(t, intended.After(last) || i == 0)
+        last = intended
+    }
+}
+
+func allowed(l limiter) bool {
+    _, ok := l.allow()
+    return ok
+}
+
+func Test_stageLimiter(t *testing.T) {
+    t.Parallel()
+
+    list, _ := parseStages("1s:1000,1h:1000")
+    s := newStages(list, stageByQps)
+    s.begin(time.Now().Add(-time.Second * 2))
+    lim := newStageLimiter(s)
+
+    intended, ok := lim.allow()
+    assert.True(t, ok)
+    assert.Equal(t, s.startTime(), intended)
+
+    for allowed(lim) {
+    }
+    assert.InDelta(t, 1500, lim.issued, 10)
+
+    s.begin(time.Now().Add(-time.Hour * 2))
+    lim = newStageLimiter(s)
+    lim.issued = 500 + 3600*1000
+    assert.False(t, allowed(lim))
 }
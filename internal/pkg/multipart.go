@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// mimeOctetStream 为无法从扩展名判断类型的上传文件使用的 Content-Type
+const mimeOctetStream = "application/octet-stream"
+
+// formPart 为表单中的一个字段，file 不为空时为上传的文件
+type formPart struct {
+	name        string
+	value       string
+	file        string
+	contentType string
+}
+
+// newFilePart 解析 path;type=image/png 形式的文件字段，未指定类型时按扩展名判断
+func newFilePart(name, value string) formPart {
+	p := formPart{name: name, file: value}
+	if i := strings.LastIndex(value, ";type="); i >= 0 {
+		p.file, p.contentType = value[:i], strings.TrimSpace(value[i+len(";type="):])
+	}
+	if p.contentType == "" {
+		p.contentType = mime.TypeByExtension(filepath.Ext(p.file))
+	}
+	if p.contentType == "" {
+		p.contentType = mimeOctetStream
+	}
+	return p
+}
+
+// buildFormBody 构建表单请求体，有文件字段时使用 multipart/form-data，
+// 返回的 contentType 为空时使用 application/x-www-form-urlencoded
+func buildFormBody(dst []byte, parts []formPart) (body []byte, contentType string, err error) {
+	if !hasFilePart(parts) {
+		args := fasthttp.AcquireArgs()
+		defer fasthttp.ReleaseArgs(args)
+		for _, p := range parts {
+			args.Add(p.name, p.value)
+		}
+		return args.AppendBytes(dst), "", nil
+	}
+
+	return buildMultipartBody(dst, parts, "")
+}
+
+// buildMultipartBody 构建 multipart/form-data 请求体，boundary 为空时随机生成，
+// 返回的 contentType 中带有使用的 boundary
+func buildMultipartBody(dst []byte, parts []formPart, boundary string) (body []byte, contentType string, err error) {
+	var size int64
+	for _, p := range parts {
+		if p.file == "" {
+			continue
+		}
+		fi, err := os.Stat(filepath.Clean(p.file))
+		if err != nil {
+			return nil, "", err
+		}
+		size += fi.Size()
+	}
+
+	// 文件只读取一次，请求体按文件大小预先分配
+	buf := bytes.NewBuffer(dst)
+	buf.Grow(int(size) + 256*len(parts))
+	w := multipart.NewWriter(buf)
+	if boundary != "" {
+		if err = w.SetBoundary(boundary); err != nil {
+			return
+		}
+	}
+	for _, p := range parts {
+		if p.file == "" {
+			if err = w.WriteField(p.name, p.value); err != nil {
+				return
+			}
+			continue
+		}
+		if err = writeFilePart(w, p); err != nil {
+			return
+		}
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+func hasFilePart(parts []formPart) bool {
+	for _, p := range parts {
+		if p.file != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeFilePart(w *multipart.Writer, p formPart) error {
+	f, err := os.Open(filepath.Clean(p.file))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(p.name), escapeQuotes(filepath.Base(p.file))))
+	h.Set("Content-Type", p.contentType)
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
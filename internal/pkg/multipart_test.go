@@ -0,0 +1,135 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_newFilePart(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, formPart{name: "a", file: "x.png", contentType: "image/png"}, newFilePart("a", "x.png"))
+	assert.Equal(t, formPart{name: "a", file: "x;1.png", contentType: "image/webp"}, newFilePart("a", "x;1.png;type=image/webp"))
+	assert.Equal(t, formPart{name: "a", file: "x", contentType: mimeOctetStream}, newFilePart("a", "x"))
+}
+
+func Test_buildFormBody(t *testing.T) {
+	t.Parallel()
+
+	t.Run("urlencoded", func(t *testing.T) {
+		body, contentType, err := buildFormBody(nil, []formPart{{name: "a", value: "1 2"}, {name: "b", value: "&"}})
+		assert.Nil(t, err)
+		assert.Equal(t, "", contentType)
+		assert.Equal(t, "a=1+2&b=%26", string(body))
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		path := writeScenario(t, `a"b.txt`, "hello")
+		body, contentType, err := buildFormBody(nil, []formPart{
+			{name: "title", value: "hi"},
+			newFilePart("doc", path+";type=text/plain"),
+		})
+		assert.Nil(t, err)
+
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		assert.Nil(t, err)
+		assert.Equal(t, "multipart/form-data", mediaType)
+
+		r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+		part, err := r.NextPart()
+		assert.Nil(t, err)
+		assert.Equal(t, "title", part.FormName())
+		b, _ := io.ReadAll(part)
+		assert.Equal(t, "hi", string(b))
+
+		part, err = r.NextPart()
+		assert.Nil(t, err)
+		assert.Equal(t, "doc", part.FormName())
+		assert.Equal(t, `a"b.txt`, part.FileName())
+		assert.Equal(t, "text/plain", part.Header.Get("Content-Type"))
+		b, _ = io.ReadAll(part)
+		assert.Equal(t, "hello", string(b))
+
+		_, err = r.NextPart()
+		assert.Equal(t, io.EOF, err)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, _, err := buildFormBody(nil, []formPart{newFilePart("doc", "missing.bin")})
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_HttpGo_Run_Multipart(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var (
+		mut      sync.Mutex
+		uploads  []string
+		chunked  int
+		withSize int
+	)
+	go func() {
+		_ = fasthttp.Serve(ln, func(ctx *fasthttp.RequestCtx) {
+			mut.Lock()
+			defer mut.Unlock()
+			if ctx.Request.Header.ContentLength() > 0 {
+				withSize++
+			} else {
+				chunked++
+			}
+			form, err := ctx.MultipartForm()
+			if err != nil {
+				ctx.SetStatusCode(fasthttp.StatusBadRequest)
+				return
+			}
+			fh := form.File["file"][0]
+			f, _ := fh.Open()
+			b, _ := io.ReadAll(f)
+			_ = f.Close()
+			uploads = append(uploads, form.Value["name"][0]+":"+fh.Filename+":"+string(b))
+		})
+	}()
+
+	content := strings.Repeat("0123456789", 1000)
+	path := writeScenario(t, "upload.bin", content)
+
+	var buf bytes.Buffer
+	p := New(Config{
+		Url:         "http://" + ln.Addr().String() + "/upload",
+		Args:        []string{"name=bob", "file=@" + path},
+		Form:        true,
+		Count:       20,
+		Connections: 4,
+		Output:      outputJSON,
+	})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	r := p.report()
+	assert.Equal(t, int64(20), r.Totals.Requests)
+	assert.Equal(t, int64(20), r.Codes.Code2xx)
+
+	mut.Lock()
+	defer mut.Unlock()
+	// 达到请求数前已发出的请求也会完成
+	assert.GreaterOrEqual(t, len(uploads), 20)
+	for _, u := range uploads {
+		assert.Equal(t, "bob:upload.bin:"+content, u)
+	}
+	assert.Equal(t, len(uploads), withSize)
+	assert.Equal(t, 0, chunked)
+}
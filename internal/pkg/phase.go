@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"time"
+)
+
+// phases 记录连接建立与请求收发各阶段的耗时分布，单位为微秒。
+// DNS、Connect、TLS、QUIC 只在新建连接时记录，QUIC 为 HTTP/3 连接的握手耗时，
+// TTFB 为请求写完到收到首字节的时间，Transfer 为收到首字节到响应读取完毕的时间
+type phases struct {
+	dns      *histogram
+	connect  *histogram
+	tls      *histogram
+	quic     *histogram
+	ttfb     *histogram
+	transfer *histogram
+}
+
+func newPhases() *phases {
+	return &phases{
+		dns:      newHistogram(),
+		connect:  newHistogram(),
+		tls:      newHistogram(),
+		quic:     newHistogram(),
+		ttfb:     newHistogram(),
+		transfer: newHistogram(),
+	}
+}
+
+type namedHistogram struct {
+	name string
+	h    *histogram
+}
+
+func (p *phases) list() []namedHistogram {
+	return []namedHistogram{
+		{"DNS", p.dns},
+		{"Connect", p.connect},
+		{"TLS", p.tls},
+		{"QUIC", p.quic},
+		{"TTFB", p.ttfb},
+		{"Transfer", p.transfer},
+	}
+}
+
+// empty 表示尚未记录任何阶段耗时
+func (p *phases) empty() bool {
+	for _, ph := range p.list() {
+		if ph.h.count() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func observe(h *histogram, d time.Duration) {
+	h.record(d.Microseconds())
+}
@@ -0,0 +1,20 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_phases(t *testing.T) {
+	t.Parallel()
+
+	p := newPhases()
+	assert.True(t, p.empty())
+	assert.Len(t, p.list(), 6)
+
+	observe(p.transfer, time.Millisecond)
+	assert.False(t, p.empty())
+	assert.Equal(t, int64(1000), p.transfer.maxValue())
+}
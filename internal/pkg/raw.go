@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// rawRequest 为从文件中读取的原始 HTTP/1.1 请求，发送时不做任何修改
+type rawRequest struct {
+	data   []byte
+	method string
+	host   string
+	close  bool
+}
+
+// readRawRequest 读取原始请求文件，只解析请求行与请求头中的 Host 和 Connection，
+// 用于确定目标地址与连接是否可以复用
+func readRawRequest(path string) (r *rawRequest, err error) {
+	r = &rawRequest{}
+	if r.data, err = os.ReadFile(filepath.Clean(path)); err != nil {
+		return nil, err
+	}
+
+	head := r.data
+	if i := bytes.Index(head, []byte("\n\r\n")); i >= 0 {
+		head = head[:i]
+	} else if i = bytes.Index(head, []byte("\n\n")); i >= 0 {
+		head = head[:i]
+	}
+	lines := strings.Split(string(head), "\n")
+
+	requestLine := strings.Fields(lines[0])
+	if len(requestLine) != 3 || !strings.HasPrefix(requestLine[2], "HTTP/1.") {
+		return nil, fmt.Errorf("invalid request line %q in raw request %s", strings.TrimSpace(lines[0]), path)
+	}
+	r.method = requestLine[0]
+
+	for _, line := range lines[1:] {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch {
+		case strings.EqualFold(k, "host") && r.host == "":
+			r.host = v
+		case strings.EqualFold(k, "connection") && strings.EqualFold(v, "close"):
+			r.close = true
+		}
+	}
+
+	return r, nil
+}
+
+// rawConn 为原始请求使用的连接，br 读取响应
+type rawConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+// rawDoer 在自行管理的连接上发送原始请求，绕过 fasthttp 对请求头的规范化，
+// 连接通过与其他客户端相同的拨号器建立，吞吐量与阶段耗时照常统计
+type rawDoer struct {
+	req     *rawRequest
+	addr    string
+	dial    fasthttp.DialFunc
+	timeout time.Duration
+
+	mut  sync.Mutex
+	idle []*rawConn
+}
+
+func (d *rawDoer) Do(_ *fasthttp.Request, resp *fasthttp.Response) error {
+	conn, reused, err := d.acquire()
+	if err != nil {
+		return err
+	}
+
+	// 复用的空闲连接可能已被服务端关闭，换新连接重试一次
+	if err = d.roundTrip(conn, resp); err != nil && reused {
+		_ = conn.Close()
+		if conn, err = d.newConn(); err != nil {
+			return err
+		}
+		err = d.roundTrip(conn, resp)
+	}
+
+	if err != nil || d.req.close || resp.ConnectionClose() {
+		_ = conn.Close()
+		return err
+	}
+
+	d.release(conn)
+	return nil
+}
+
+// DoRedirects 用于调试模式，原始请求不跟随重定向
+func (d *rawDoer) DoRedirects(req *fasthttp.Request, resp *fasthttp.Response, _ int) error {
+	return d.Do(req, resp)
+}
+
+func (d *rawDoer) roundTrip(conn *rawConn, resp *fasthttp.Response) error {
+	if d.timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(d.timeout)); err != nil {
+			return err
+		}
+	}
+	if _, err := conn.Write(d.req.data); err != nil {
+		return err
+	}
+
+	resp.Reset()
+	resp.SkipBody = d.req.method == fasthttp.MethodHead
+	return resp.Read(conn.br)
+}
+
+func (d *rawDoer) acquire() (*rawConn, bool, error) {
+	d.mut.Lock()
+	if n := len(d.idle); n > 0 {
+		conn := d.idle[n-1]
+		d.idle = d.idle[:n-1]
+		d.mut.Unlock()
+		return conn, true, nil
+	}
+	d.mut.Unlock()
+
+	conn, err := d.newConn()
+	return conn, false, err
+}
+
+func (d *rawDoer) newConn() (*rawConn, error) {
+	conn, err := d.dial(d.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &rawConn{Conn: conn, br: bufio.NewReader(conn)}, nil
+}
+
+func (d *rawDoer) release(conn *rawConn) {
+	d.mut.Lock()
+	d.idle = append(d.idle, conn)
+	d.mut.Unlock()
+}
+
+// initRaw 读取原始请求文件，目标地址取自 URL，未指定 URL 时取自请求中的 Host 头
+func (c *httpClient) initRaw(conf *Config) (err error) {
+	if conf.Pipeline || conf.Stream || conf.CompressBody != "" || conf.HTTP2 || conf.HTTP3 || conf.Data != "" {
+		return errors.New("raw requests cannot be used with --pipeline, --stream, --compress-body, --http2, --http3 or --data")
+	}
+
+	var r *rawRequest
+	if r, err = readRawRequest(conf.Raw); err != nil {
+		return
+	}
+	if conf.Url == "" {
+		if r.host == "" {
+			return errors.New("missing URL argument or Host header in raw request")
+		}
+		conf.Url = "http://" + r.host
+	}
+	conf.Method = r.method
+
+	if err = conf.setReqBasic(c.request); err != nil {
+		return
+	}
+	if conf.tlsConf, err = conf.getTlsConfig(); err != nil {
+		return
+	}
+
+	d := &rawDoer{req: r, addr: conf.addr, dial: conf.getDialer(conf.isTLS), timeout: conf.Timeout}
+	c.raw = r.data
+	if conf.Debug {
+		c.onceDoer = d
+	} else {
+		c.doer = d
+	}
+
+	return
+}
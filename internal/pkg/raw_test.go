@@ -0,0 +1,190 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+// rawServer 记录收到的原始请求，每个请求返回 204，请求带有 Connection: close 时关闭连接
+type rawServer struct {
+	ln    net.Listener
+	mut   sync.Mutex
+	reqs  []string
+	conns int
+}
+
+func newRawServer(t *testing.T) *rawServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := &rawServer{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *rawServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mut.Lock()
+		s.conns++
+		s.mut.Unlock()
+		go s.handle(conn)
+	}
+}
+
+func (s *rawServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	br := bufio.NewReader(conn)
+	for {
+		var req fasthttp.Request
+		req.Header.DisableNormalizing()
+		req.Header.SetNoDefaultContentType(true)
+		var raw bytes.Buffer
+		if err := req.Read(bufio.NewReader(io.TeeReader(br, &raw))); err != nil {
+			return
+		}
+		s.mut.Lock()
+		s.reqs = append(s.reqs, raw.String())
+		s.mut.Unlock()
+
+		_, _ = conn.Write([]byte("HTTP/1.1 204 No Content\r\n\r\n"))
+		if req.Header.ConnectionClose() {
+			return
+		}
+	}
+}
+
+func (s *rawServer) received() ([]string, int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string{}, s.reqs...), s.conns
+}
+
+func Test_readRawRequest(t *testing.T) {
+	t.Parallel()
+
+	r, err := readRawRequest(writeScenario(t, "req.txt", "HEAD /a HTTP/1.1\r\nhost: example.com\r\nHOST: other\r\nConnection: Close\r\n\r\nx: y"))
+	assert.Nil(t, err)
+	assert.Equal(t, "HEAD", r.method)
+	assert.Equal(t, "example.com", r.host)
+	assert.True(t, r.close)
+
+	r, err = readRawRequest(writeScenario(t, "req.txt", "GET / HTTP/1.0\n\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "", r.host)
+	assert.False(t, r.close)
+
+	for _, content := range []string{"", "GET /\r\n\r\n", "GET / HTTP/2\r\n\r\n"} {
+		_, err = readRawRequest(writeScenario(t, "req.txt", content))
+		assert.NotNil(t, err, content)
+	}
+
+	_, err = readRawRequest("not-exist.txt")
+	assert.NotNil(t, err)
+}
+
+func Test_HttpGo_Run_Raw(t *testing.T) {
+	t.Parallel()
+
+	s := newRawServer(t)
+	raw := "POST /login HTTP/1.1\r\nhost: " + s.ln.Addr().String() + "\r\nX-Dup: 1\r\nx-dup: 2\r\ncontent-TYPE: text/plain\r\nContent-Length: 2\r\n\r\nhi"
+	path := writeScenario(t, "req.txt", raw)
+
+	var buf bytes.Buffer
+	p := New(Config{Raw: path, Count: 5, Connections: 1, Output: outputJSON})
+	p.stat.w = &buf
+	assert.Nil(t, p.Run())
+
+	reqs, conns := s.received()
+	assert.Len(t, reqs, 5)
+	for _, req := range reqs {
+		assert.Equal(t, raw, req)
+	}
+	assert.Equal(t, 1, conns)
+
+	r := p.report()
+	assert.Equal(t, int64(5), r.Totals.Requests)
+	assert.Equal(t, "http://"+s.ln.Addr().String(), r.Config.Url)
+	assert.Equal(t, "POST", r.Config.Method)
+	assert.Equal(t, path, r.Config.Raw)
+	assert.Greater(t, r.Throughput.Bytes, int64(0))
+}
+
+func Test_rawDoer(t *testing.T) {
+	t.Parallel()
+
+	s := newRawServer(t)
+	addr := s.ln.Addr().String()
+
+	t.Run("connection close", func(t *testing.T) {
+		path := writeScenario(t, "req.txt", "GET / HTTP/1.1\r\nHost: x\r\nConnection: close\r\n\r\n")
+		fc, err := newHttpClient(&Config{Url: "http://" + addr, Timeout: time.Second, Raw: path})
+		assert.Nil(t, err)
+
+		_, before := s.received()
+		for i := 0; i < 3; i++ {
+			_, code, _, err := fc.do()
+			assert.Nil(t, err)
+			assert.Equal(t, 204, code)
+		}
+		_, after := s.received()
+		assert.Equal(t, 3, after-before)
+	})
+
+	t.Run("retry closed idle conn", func(t *testing.T) {
+		path := writeScenario(t, "req.txt", "GET / HTTP/1.1\r\nHost: x\r\n\r\n")
+		fc, err := newHttpClient(&Config{Url: "http://" + addr, Timeout: time.Second, Raw: path})
+		assert.Nil(t, err)
+
+		_, _, _, err = fc.do()
+		assert.Nil(t, err)
+		d := fc.doer.(*rawDoer)
+		assert.Len(t, d.idle, 1)
+		_ = d.idle[0].Conn.Close()
+
+		_, code, _, err := fc.do()
+		assert.Nil(t, err)
+		assert.Equal(t, 204, code)
+	})
+
+	t.Run("debug", func(t *testing.T) {
+		raw := "GET /debug HTTP/1.1\r\nhost: x\r\n\r\n"
+		fc, err := newHttpClient(&Config{Url: "http://" + addr, Timeout: time.Second, Raw: writeScenario(t, "req.txt", raw), Debug: true})
+		assert.Nil(t, err)
+
+		var buf bytes.Buffer
+		fc.writeCloser = defaultWriteCloser{&buf}
+		assert.Nil(t, fc.doOnce())
+		assert.Contains(t, buf.String(), raw)
+		assert.True(t, strings.Contains(buf.String(), "204 No Content"))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		path := writeScenario(t, "req.txt", "GET / HTTP/1.1\r\n\r\n")
+		_, err := newHttpClient(&Config{Raw: path})
+		assert.NotNil(t, err)
+		_, err = newHttpClient(&Config{Url: "http://" + addr, Timeout: time.Second, Raw: path, Pipeline: true})
+		assert.NotNil(t, err)
+		_, err = newHttpClient(&Config{Url: "http://" + addr, Timeout: time.Second, Raw: "not-exist.txt"})
+		assert.NotNil(t, err)
+		assert.NotNil(t, New(Config{Raw: path, Scenario: path}).Run())
+
+		fc, err := newHttpClient(&Config{Url: "http://127.0.0.1:1", Timeout: time.Second, Raw: path})
+		assert.Nil(t, err)
+		_, _, _, err = fc.do()
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,413 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// reportVersion 为 JSON 结果的格式版本，字段发生不兼容变更时递增
+const reportVersion = 1
+
+type report struct {
+	Version    int                    `json:"version"`
+	Tool       string                 `json:"tool"`
+	Status     string                 `json:"status"`
+	Config     reportConfig           `json:"config"`
+	Stage      *reportStage           `json:"stage,omitempty"`
+	Totals     reportTotals           `json:"totals"`
+	Rps        reportRps              `json:"rps"`
+	Latency    reportLatency          `json:"latency"`
+	Corrected  *reportLatency         `json:"corrected_latency,omitempty"`
+	Codes      reportCodes            `json:"codes"`
+	Errors     map[string]int         `json:"errors"`
+	Throughput reportThroughput       `json:"throughput"`
+	Phases     map[string]reportPhase `json:"phases,omitempty"`
+	HTTP2      *reportHTTP2           `json:"http2,omitempty"`
+	HTTP3      *reportHTTP3           `json:"http3,omitempty"`
+	WebSocket  *reportWebSocket       `json:"websocket,omitempty"`
+	SSE        *reportSSE             `json:"sse,omitempty"`
+	GRPC       *reportGRPC            `json:"grpc,omitempty"`
+	Endpoints  []reportEndpoint       `json:"endpoints,omitempty"`
+	Assertions []reportAssertion      `json:"assertions,omitempty"`
+
+	latency   *histogram
+	corrected *histogram
+}
+
+type reportConfig struct {
+	Url            string              `json:"url"`
+	UnixSocket     string              `json:"unix_socket,omitempty"`
+	Scenario       string              `json:"scenario,omitempty"`
+	HttpFile       string              `json:"http_file,omitempty"`
+	Raw            string              `json:"raw,omitempty"`
+	Har            string              `json:"har,omitempty"`
+	HarMode        string              `json:"har_mode,omitempty"`
+	Speed          float64             `json:"speed,omitempty"`
+	Method         string              `json:"method"`
+	Connections    int                 `json:"connections"`
+	Requests       int                 `json:"requests"`
+	Qps            int                 `json:"qps"`
+	Rate           int                 `json:"rate,omitempty"`
+	MaxInFlight    int                 `json:"max_in_flight,omitempty"`
+	Stages         []reportStageConfig `json:"stages,omitempty"`
+	StageBy        string              `json:"stage_by,omitempty"`
+	DurationSec    float64             `json:"duration_sec"`
+	TimeoutSec     float64             `json:"timeout_sec"`
+	Pipeline       bool                `json:"pipeline"`
+	CompressBody   string              `json:"compress_body,omitempty"`
+	AcceptEncoding string              `json:"accept_encoding,omitempty"`
+}
+
+type reportStageConfig struct {
+	DurationSec float64 `json:"duration_sec"`
+	Target      int     `json:"target"`
+}
+
+// reportStage 表示结束时所处的阶段，Index 从 1 开始
+type reportStage struct {
+	Index   int     `json:"index"`
+	Count   int     `json:"count"`
+	Target  int     `json:"target"`
+	Current float64 `json:"current"`
+}
+
+type reportTotals struct {
+	Requests   int64   `json:"requests"`
+	Errors     int64   `json:"errors"`
+	Dropped    int64   `json:"dropped"`
+	ElapsedSec float64 `json:"elapsed_sec"`
+}
+
+type reportRps struct {
+	Avg    float64 `json:"avg"`
+	Stdev  float64 `json:"stdev"`
+	Max    float64 `json:"max"`
+	Series []int64 `json:"series"`
+}
+
+type reportLatency struct {
+	AvgMs         float64            `json:"avg_ms"`
+	StdevMs       float64            `json:"stdev_ms"`
+	MinMs         float64            `json:"min_ms"`
+	MaxMs         float64            `json:"max_ms"`
+	PercentilesMs map[string]float64 `json:"percentiles_ms"`
+}
+
+type reportPhase struct {
+	Count int64 `json:"count"`
+	reportLatency
+}
+
+type reportEndpoint struct {
+	Name     string        `json:"name"`
+	Requests int64         `json:"requests"`
+	Errors   int64         `json:"errors"`
+	Codes    reportCodes   `json:"codes"`
+	Latency  reportLatency `json:"latency"`
+}
+
+type reportCodes struct {
+	Code1xx    int64 `json:"1xx"`
+	Code2xx    int64 `json:"2xx"`
+	Code3xx    int64 `json:"3xx"`
+	Code4xx    int64 `json:"4xx"`
+	Code5xx    int64 `json:"5xx"`
+	CodeOthers int64 `json:"others"`
+}
+
+type reportAssertion struct {
+	Expr   string `json:"expr"`
+	Actual string `json:"actual"`
+	Passed bool   `json:"passed"`
+}
+
+// reportHTTP2 为 HTTP/2 的连接与流的统计，MaxConcurrentStreams 为同时活跃的流的最大值
+type reportHTTP2 struct {
+	StreamsPerConn       int   `json:"streams_per_conn"`
+	Connections          int64 `json:"connections"`
+	ConnectionErrors     int64 `json:"connection_errors"`
+	Streams              int64 `json:"streams"`
+	StreamErrors         int64 `json:"stream_errors"`
+	MaxConcurrentStreams int64 `json:"max_concurrent_streams"`
+}
+
+// reportHTTP3 为 HTTP/3 的连接与流的统计，ZeroRTT 为使用 0-RTT 恢复的连接数，
+// 握手耗时见 phases 中的 quic
+type reportHTTP3 struct {
+	StreamsPerConn   int   `json:"streams_per_conn"`
+	Connections      int64 `json:"connections"`
+	ConnectionErrors int64 `json:"connection_errors"`
+	ZeroRTT          int64 `json:"zero_rtt"`
+	Streams          int64 `json:"streams"`
+	StreamErrors     int64 `json:"stream_errors"`
+}
+
+// reportWebSocket 为 ws 模式下的连接与消息统计，Connect 为从拨号到收到 101 响应的耗时，
+// CloseCodes 为各关闭状态码的次数，消息的往返时间见 latency
+type reportWebSocket struct {
+	Connections int64            `json:"connections"`
+	Connect     reportPhase      `json:"connect"`
+	Sent        int64            `json:"sent"`
+	Received    int64            `json:"received"`
+	Unmatched   int64            `json:"unmatched"`
+	CloseCodes  map[string]int64 `json:"close_codes"`
+}
+
+// reportSSE 为 sse 模式下的流与事件统计，TTFE 为从发送请求到收到第一个事件的耗时，
+// Gap 为相邻事件的间隔，EventsPerSec 为每个连接在流保持打开期间平均每秒收到的事件数
+type reportSSE struct {
+	Streams      int64       `json:"streams"`
+	Events       int64       `json:"events"`
+	EventsPerSec float64     `json:"events_per_sec_per_conn"`
+	TTFE         reportPhase `json:"ttfe"`
+	Gap          reportPhase `json:"gap"`
+}
+
+// reportGRPC 为 grpc 模式下的连接与消息统计，Codes 为各 grpc-status 的次数，
+// 顶层 codes 中只有 others 有值，为未定义的状态码的次数
+type reportGRPC struct {
+	StreamsPerConn int              `json:"streams_per_conn"`
+	Connections    int              `json:"connections"`
+	Sent           int64            `json:"sent"`
+	Received       int64            `json:"received"`
+	Codes          map[string]int64 `json:"codes"`
+}
+
+type reportThroughput struct {
+	Bytes       int64   `json:"bytes"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	// BodyBytes 与 DecodedBodyBytes 为响应体解压前后的字节数，仅在指定 Accept-Encoding 时输出
+	BodyBytes        int64 `json:"body_bytes,omitempty"`
+	DecodedBodyBytes int64 `json:"decoded_body_bytes,omitempty"`
+}
+
+func (p *HttpGo) report() *report {
+	t := p.stat
+
+	r := &report{
+		Version: reportVersion,
+		Tool:    "httpgo/" + Version,
+		Status:  t.status(),
+		Config: reportConfig{
+			Url:            p.c.Url,
+			UnixSocket:     p.c.UnixSocket,
+			Scenario:       p.c.Scenario,
+			HttpFile:       p.c.HttpFile,
+			Raw:            p.c.Raw,
+			Har:            p.c.Har,
+			Method:         p.c.Method,
+			Connections:    p.c.Connections,
+			Requests:       p.c.Count,
+			Qps:            p.c.Qps,
+			Rate:           p.c.Rate,
+			MaxInFlight:    p.c.MaxInFlight,
+			DurationSec:    p.c.Duration.Seconds(),
+			TimeoutSec:     p.c.Timeout.Seconds(),
+			Pipeline:       p.c.Pipeline,
+			CompressBody:   p.c.CompressBody,
+			AcceptEncoding: p.c.AcceptEncoding,
+		},
+		Codes: reportCodes{
+			Code1xx:    atomic.LoadInt64(&t.code1xx),
+			Code2xx:    atomic.LoadInt64(&t.code2xx),
+			Code3xx:    atomic.LoadInt64(&t.code3xx),
+			Code4xx:    atomic.LoadInt64(&t.code4xx),
+			Code5xx:    atomic.LoadInt64(&t.code5xx),
+			CodeOthers: atomic.LoadInt64(&t.codeOthers),
+		},
+		Errors:    make(map[string]int),
+		latency:   t.latency,
+		corrected: t.corrected,
+	}
+
+	if p.c.Har != "" {
+		r.Config.HarMode = p.c.HarMode
+		if r.Config.HarMode == "" {
+			r.Config.HarMode = harPaced
+		}
+		if r.Config.HarMode == harPaced {
+			r.Config.Speed = p.c.Speed
+		}
+	}
+
+	if t.stages != nil {
+		for _, st := range t.stages.list {
+			r.Config.Stages = append(r.Config.Stages, reportStageConfig{DurationSec: st.duration.Seconds(), Target: st.target})
+		}
+		r.Config.StageBy = t.stages.by
+
+		i, v := t.stages.current()
+		r.Stage = &reportStage{Index: i + 1, Count: len(t.stages.list), Target: t.stages.list[i].target, Current: v}
+	}
+
+	elapsed := time.Duration(atomic.LoadInt64(&t.elapsed))
+	r.Totals.Requests = atomic.LoadInt64(&t.reqs)
+	r.Totals.Dropped = atomic.LoadInt64(&t.dropped)
+	r.Totals.ElapsedSec = elapsed.Seconds()
+
+	t.mut.Lock()
+	for err, n := range t.errs {
+		r.Errors[err] = n
+		r.Totals.Errors += int64(n)
+	}
+	t.mut.Unlock()
+
+	r.Rps.Avg, r.Rps.Stdev, r.Rps.Max = rpsResult(t.rps)
+	r.Rps.Series = append([]int64{}, t.seconds...)
+
+	r.Latency = latencyReport(t.latency)
+	if t.corrected.count() > 0 {
+		corrected := latencyReport(t.corrected)
+		r.Corrected = &corrected
+	}
+
+	if t.phases != nil && !t.phases.empty() {
+		r.Phases = make(map[string]reportPhase)
+		for _, ph := range t.phases.list() {
+			if n := ph.h.count(); n > 0 {
+				r.Phases[strings.ToLower(ph.name)] = reportPhase{Count: n, reportLatency: latencyReport(ph.h)}
+			}
+		}
+	}
+
+	for _, ep := range t.endpoints {
+		r.Endpoints = append(r.Endpoints, reportEndpoint{
+			Name:     ep.name,
+			Requests: atomic.LoadInt64(&ep.reqs),
+			Errors:   atomic.LoadInt64(&ep.errs),
+			Codes: reportCodes{
+				Code1xx:    atomic.LoadInt64(&ep.codes[0]),
+				Code2xx:    atomic.LoadInt64(&ep.codes[1]),
+				Code3xx:    atomic.LoadInt64(&ep.codes[2]),
+				Code4xx:    atomic.LoadInt64(&ep.codes[3]),
+				Code5xx:    atomic.LoadInt64(&ep.codes[4]),
+				CodeOthers: atomic.LoadInt64(&ep.codes[5]),
+			},
+			Latency: latencyReport(ep.latency),
+		})
+	}
+
+	if t.throughput != nil {
+		r.Throughput.Bytes = atomic.LoadInt64(t.throughput)
+	}
+	if t.h2 != nil {
+		r.HTTP2 = &reportHTTP2{
+			StreamsPerConn:       p.c.StreamsPerConn,
+			Connections:          atomic.LoadInt64(&t.h2.conns),
+			ConnectionErrors:     atomic.LoadInt64(&t.h2.connErrors),
+			Streams:              atomic.LoadInt64(&t.h2.streams),
+			StreamErrors:         atomic.LoadInt64(&t.h2.streamErrors),
+			MaxConcurrentStreams: atomic.LoadInt64(&t.h2.maxActive),
+		}
+	}
+	if t.h3 != nil {
+		r.HTTP3 = &reportHTTP3{
+			StreamsPerConn:   p.c.StreamsPerConn,
+			Connections:      atomic.LoadInt64(&t.h3.conns),
+			ConnectionErrors: atomic.LoadInt64(&t.h3.connErrors),
+			ZeroRTT:          atomic.LoadInt64(&t.h3.zeroRTT),
+			Streams:          atomic.LoadInt64(&t.h3.streams),
+			StreamErrors:     atomic.LoadInt64(&t.h3.streamErrors),
+		}
+	}
+	if t.ws != nil {
+		r.WebSocket = &reportWebSocket{
+			Connections: atomic.LoadInt64(&t.ws.conns),
+			Connect:     reportPhase{Count: t.ws.connect.count(), reportLatency: latencyReport(t.ws.connect)},
+			Sent:        atomic.LoadInt64(&t.ws.sent),
+			Received:    atomic.LoadInt64(&t.ws.received),
+			Unmatched:   atomic.LoadInt64(&t.ws.unmatched),
+			CloseCodes:  make(map[string]int64),
+		}
+		codes, counts := t.ws.codes()
+		for i, code := range codes {
+			r.WebSocket.CloseCodes[strconv.Itoa(code)] = counts[i]
+		}
+	}
+	if t.sse != nil {
+		r.SSE = &reportSSE{
+			Streams:      atomic.LoadInt64(&t.sse.streams),
+			Events:       atomic.LoadInt64(&t.sse.events),
+			EventsPerSec: t.sse.eventsPerSec(),
+			TTFE:         reportPhase{Count: t.sse.ttfe.count(), reportLatency: latencyReport(t.sse.ttfe)},
+			Gap:          reportPhase{Count: t.sse.gap.count(), reportLatency: latencyReport(t.sse.gap)},
+		}
+	}
+	if t.grpc != nil {
+		r.GRPC = &reportGRPC{
+			StreamsPerConn: p.c.StreamsPerConn,
+			Connections:    t.grpc.conns,
+			Sent:           atomic.LoadInt64(&t.grpc.sent),
+			Received:       atomic.LoadInt64(&t.grpc.received),
+			Codes:          make(map[string]int64),
+		}
+		for code := range t.grpc.codes {
+			if n := atomic.LoadInt64(&t.grpc.codes[code]); n > 0 {
+				r.GRPC.Codes[grpcCodeName(code)] = n
+			}
+		}
+	}
+	if t.bodies != nil {
+		r.Throughput.BodyBytes = atomic.LoadInt64(&t.bodies.wire)
+		r.Throughput.DecodedBodyBytes = atomic.LoadInt64(&t.bodies.decoded)
+	}
+	if seconds := elapsed.Seconds(); seconds != 0 {
+		r.Throughput.BytesPerSec = float64(r.Throughput.Bytes) / seconds
+	}
+
+	if len(p.asserts) > 0 {
+		r.Assertions = evaluateAssertions(p.asserts, r)
+	}
+
+	return r
+}
+
+// correctedLatency 返回修正协调遗漏后的延迟统计，未开启 --qps 时与原始延迟一致
+func (r *report) correctedLatency() (reportLatency, *histogram) {
+	if r.Corrected == nil {
+		return r.Latency, r.latency
+	}
+	return *r.Corrected, r.corrected
+}
+
+func latencyReport(h *histogram) reportLatency {
+	l := reportLatency{PercentilesMs: make(map[string]float64, len(latencyPercentiles))}
+	l.AvgMs, l.StdevMs, l.MaxMs = latencyResult(h)
+	l.MinMs = float64(h.minValue()) / 1000
+	for _, q := range latencyPercentiles {
+		l.PercentilesMs[percentileKey(q)] = float64(h.percentile(q)) / 1000
+	}
+	return l
+}
+
+func percentileKey(q float64) string {
+	return "p" + strconv.FormatFloat(q, 'f', -1, 64)
+}
+
+// writeReport 将 JSON 结果写入 OutputFile，未指定时写入标准输出
+func (p *HttpGo) writeReport() (err error) {
+	var w io.Writer = p.stat.w
+	if p.c.OutputFile != "" {
+		var f *os.File
+		if f, err = os.Create(filepath.Clean(p.c.OutputFile)); err != nil {
+			return
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(p.report())
+}
@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HttpGo_report(t *testing.T) {
+	t.Parallel()
+
+	var throughput int64 = 2048
+	p := New(Config{Url: "http://example.com", Method: "GET", Count: 3})
+	p.stat.throughput = &throughput
+	p.stat.done = true
+	p.beginTime = time.Now()
+	p.startTime = p.beginTime
+	p.statistic(200, time.Millisecond, nil)
+	p.statistic(503, time.Millisecond*3, nil)
+	p.statistic(0, 0, errors.New("custom-error"))
+	p.stat.elapsed = int64(time.Second)
+
+	r := p.report()
+	assert.Equal(t, reportVersion, r.Version)
+	assert.Equal(t, "done", r.Status)
+	assert.Equal(t, "http://example.com", r.Config.Url)
+	assert.Equal(t, 3, r.Config.Requests)
+	assert.Equal(t, int64(2), r.Totals.Requests)
+	assert.Equal(t, int64(1), r.Totals.Errors)
+	assert.Equal(t, 1, r.Errors["custom-error"])
+	assert.Equal(t, int64(1), r.Codes.Code2xx)
+	assert.Equal(t, int64(1), r.Codes.Code5xx)
+	assert.Equal(t, []int64{2}, r.Rps.Series)
+	assert.Equal(t, 3.0, r.Latency.MaxMs)
+	assert.Equal(t, 1.0, r.Latency.MinMs)
+	assert.Equal(t, 3.0, r.Latency.PercentilesMs["p99.9"])
+	assert.Nil(t, r.Phases)
+	assert.Nil(t, r.Corrected)
+	assert.Equal(t, int64(2048), r.Throughput.Bytes)
+	assert.Equal(t, 2048.0, r.Throughput.BytesPerSec)
+}
+
+func Test_HttpGo_report_endpoints(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url"})
+	ep := newEndpoint("login")
+	p.stat.endpoints = []*endpoint{ep}
+	p.startTime = time.Now()
+	p.record(time.Time{}, ep, 201, time.Millisecond, nil)
+	p.record(time.Time{}, ep, 0, 0, errors.New("error"))
+	p.record(time.Time{}, nil, 200, time.Millisecond, nil)
+
+	r := p.report()
+	assert.Len(t, r.Endpoints, 1)
+	assert.Equal(t, "login", r.Endpoints[0].Name)
+	assert.Equal(t, int64(1), r.Endpoints[0].Requests)
+	assert.Equal(t, int64(1), r.Endpoints[0].Errors)
+	assert.Equal(t, int64(1), r.Endpoints[0].Codes.Code2xx)
+	assert.Equal(t, 1.0, r.Endpoints[0].Latency.MaxMs)
+	assert.Equal(t, int64(2), r.Totals.Requests)
+}
+
+func Test_HttpGo_report_corrected(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url"})
+	p.stat.appendLatency(time.Millisecond)
+	p.stat.appendCorrectedLatency(time.Millisecond*7, time.Millisecond)
+
+	r := p.report()
+	assert.NotNil(t, r.Corrected)
+	assert.Equal(t, 7.0, r.Corrected.MaxMs)
+	assert.Equal(t, 1.0, r.Latency.MaxMs)
+}
+
+func Test_HttpGo_report_phases(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url"})
+	observe(p.c.phases.tls, time.Millisecond*8)
+	observe(p.c.phases.ttfb, time.Millisecond*2)
+
+	r := p.report()
+	assert.Len(t, r.Phases, 2)
+	assert.Equal(t, int64(1), r.Phases["tls"].Count)
+	assert.Equal(t, 8.0, r.Phases["tls"].MaxMs)
+	assert.Equal(t, 2.0, r.Phases["ttfb"].PercentilesMs["p50"])
+}
+
+func Test_HttpGo_writeReport(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stdout", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: "url"})
+		p.stat.w = &buf
+		assert.Nil(t, p.writeReport())
+
+		var r report
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &r))
+		assert.Equal(t, reportVersion, r.Version)
+		assert.Equal(t, "running", r.Status)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "result.json")
+		p := New(Config{Url: "url", OutputFile: file})
+		assert.Nil(t, p.writeReport())
+
+		b, err := os.ReadFile(file)
+		assert.Nil(t, err)
+		assert.Contains(t, string(b), `"version": 1`)
+	})
+
+	t.Run("file error", func(t *testing.T) {
+		p := New(Config{Url: "url", OutputFile: filepath.Join(t.TempDir(), "no", "such", "dir")})
+		assert.NotNil(t, p.writeReport())
+	})
+}
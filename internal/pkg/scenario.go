@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v3"
+)
+
+// scenario 表示场景文件中的一个请求
+type scenario struct {
+	Name    string   `yaml:"name"`
+	Method  string   `yaml:"method"`
+	Url     string   `yaml:"url"`
+	Headers []string `yaml:"headers"`
+	Body    string   `yaml:"body"`
+	File    string   `yaml:"file"`
+	Weight  *int     `yaml:"weight"`
+}
+
+// scenarioFile 为场景文件的格式，支持 YAML 与 JSON
+type scenarioFile struct {
+	Requests []scenario `yaml:"requests"`
+}
+
+// readScenarios 读取场景文件，权重未指定时为 1，权重为 0 的请求被忽略
+func readScenarios(path string) ([]scenario, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var f scenarioFile
+	if err = yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file %s: %w", path, err)
+	}
+
+	list := make([]scenario, 0, len(f.Requests))
+	names := make(map[string]bool, len(f.Requests))
+	for i, s := range f.Requests {
+		if s.Url == "" {
+			return nil, fmt.Errorf("missing url in scenario request #%d", i+1)
+		}
+		if s.Weight == nil {
+			one := 1
+			s.Weight = &one
+		}
+		if *s.Weight < 0 {
+			return nil, fmt.Errorf("negative weight in scenario request #%d", i+1)
+		}
+		if *s.Weight == 0 {
+			continue
+		}
+		if s.Name != "" {
+			if names[s.Name] {
+				return nil, fmt.Errorf("duplicate scenario request name %q", s.Name)
+			}
+			names[s.Name] = true
+		}
+		list = append(list, s)
+	}
+
+	if len(list) == 0 {
+		return nil, errors.New("no request with positive weight in scenario file")
+	}
+
+	return list, nil
+}
+
+// resolveUrl 以 / 开头的路径使用 base 的协议与主机，其余地址与命令行 URL 的简化格式一致
+func (s scenario) resolveUrl(base string) (string, error) {
+	if base == "" || !strings.HasPrefix(s.Url, "/") || strings.HasPrefix(s.Url, "//") {
+		return addMissingSchemaAndHost(s.Url), nil
+	}
+
+	// 直接拼接以保留路径中的模板变量
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	return b.Scheme + "://" + b.Host + s.Url, nil
+}
+
+// requestsFile 返回场景文件、HAR 文件或 .http 文件中指定的一个
+func (c *Config) requestsFile() string {
+	for _, f := range []string{c.Scenario, c.Har, c.HttpFile} {
+		if f != "" {
+			return f
+		}
+	}
+	return ""
+}
+
+// checkRequestsFile 检查是否同时指定了多个请求文件（包括原始请求文件）
+func (c *Config) checkRequestsFile() error {
+	var n int
+	for _, f := range []string{c.Scenario, c.Har, c.HttpFile, c.Raw} {
+		if f != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("only one of scenario, HAR, .http and raw request files can be used")
+	}
+	return nil
+}
+
+// scenarioTargets 为场景中的每个请求构建请求模板，相同地址的请求共用一个客户端，
+// 名称相同的请求共用一个端点
+func (c *Config) scenarioTargets(list []scenario) (targets []*target, weights []int, err error) {
+	doers := make(map[string]clientDoer)
+	endpoints := make(map[string]*endpoint)
+
+	var total int
+	var usesData bool
+	for _, s := range list {
+		sc := *c
+		sc.Args = nil
+		sc.body = nil
+		sc.Body, sc.File = s.Body, s.File
+		sc.Headers = append(append([]string{}, c.Headers...), s.Headers...)
+		if s.Method != "" {
+			sc.Method = strings.ToUpper(s.Method)
+		}
+		if sc.Url, err = s.resolveUrl(c.Url); err != nil {
+			return
+		}
+
+		t := &target{request: fasthttp.AcquireRequest()}
+		if err = sc.setReqBasic(t.request); err != nil {
+			return
+		}
+		if err = sc.setReqBody(t.request); err != nil {
+			return
+		}
+		t.body = sc.body
+		if err = sc.setReqHeader(t.request); err != nil {
+			return
+		}
+		// HAR 中记录的请求默认原样回放，指定 HarTemplate 时才渲染模板变量
+		if c.Har == "" || c.HarTemplate {
+			if t.tmpl, err = sc.requestTemplate(); err != nil {
+				return
+			}
+		}
+		usesData = usesData || t.tmpl.usesData()
+
+		name := s.Name
+		if name == "" {
+			name = string(t.request.Header.Method()) + " " + string(t.request.URI().Path())
+		}
+		if t.endpoint = endpoints[name]; t.endpoint == nil {
+			t.endpoint = newEndpoint(name)
+			endpoints[name] = t.endpoint
+		}
+
+		key := sc.addr
+		if sc.isTLS {
+			key = "https://" + key
+		}
+		if t.doer = doers[key]; t.doer == nil {
+			if t.doer, err = c.addrDoer(sc.addr, sc.isTLS); err != nil {
+				return
+			}
+			doers[key] = t.doer
+		}
+
+		total += *s.Weight
+		targets = append(targets, t)
+		weights = append(weights, total)
+	}
+
+	if c.feeder != nil && !usesData {
+		return nil, nil, errDataUnused
+	}
+
+	return
+}
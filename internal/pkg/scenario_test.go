@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeScenario(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_readScenarios(t *testing.T) {
+	t.Parallel()
+
+	t.Run("yaml", func(t *testing.T) {
+		path := writeScenario(t, "s.yaml", `
+requests:
+  - name: login
+    method: post
+    url: /login
+    headers: ["Content-Type: application/json"]
+    body: '{"user":"foo"}'
+    weight: 3
+  - url: /search?q=foo
+  - url: /disabled
+    weight: 0
+`)
+		list, err := readScenarios(path)
+		assert.Nil(t, err)
+		assert.Len(t, list, 2)
+		assert.Equal(t, "login", list[0].Name)
+		assert.Equal(t, 3, *list[0].Weight)
+		assert.Equal(t, 1, *list[1].Weight)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		path := writeScenario(t, "s.json", `{"requests": [{"url": ":3000/a", "weight": 2}]}`)
+		list, err := readScenarios(path)
+		assert.Nil(t, err)
+		assert.Len(t, list, 1)
+	})
+
+	for name, content := range map[string]string{
+		"invalid":         "requests: [",
+		"missing url":     "requests: [{name: a}]",
+		"negative weight": "requests: [{url: /a, weight: -1}]",
+		"duplicate name":  "requests: [{name: a, url: /a}, {name: a, url: /b}]",
+		"empty":           "requests: [{url: /a, weight: 0}]",
+	} {
+		name, content := name, content
+		t.Run(name, func(t *testing.T) {
+			_, err := readScenarios(writeScenario(t, "s.yaml", content))
+			assert.NotNil(t, err)
+		})
+	}
+
+	t.Run("not exist", func(t *testing.T) {
+		_, err := readScenarios("not-exist")
+		assert.NotNil(t, err)
+	})
+}
+
+func Test_scenario_resolveUrl(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		base, url, want string
+	}{
+		{"", "/a", "http://localhost/a"},
+		{"", ":3000/a", "http://localhost:3000/a"},
+		{"", "example.com/a", "http://example.com/a"},
+		{"https://example.com/api", "/users?id=1", "https://example.com/users?id=1"},
+		{"https://example.com", "http://other.com/a", "http://other.com/a"},
+		{"https://example.com", "//other.com/a", "//other.com/a"},
+	}
+	for _, tt := range tests {
+		u, err := scenario{Url: tt.url}.resolveUrl(tt.base)
+		assert.Nil(t, err)
+		assert.Equal(t, tt.want, u, tt.url)
+	}
+
+	_, err := scenario{Url: "/a"}.resolveUrl("http://[::1")
+	assert.NotNil(t, err)
+}
+
+func Test_Config_scenarioTargets(t *testing.T) {
+	t.Parallel()
+
+	two, three := 2, 3
+	c := &Config{Url: "http://example.com", Method: "GET", Headers: []string{"X-Common: 1"}, JSON: true}
+	targets, weights, err := c.scenarioTargets([]scenario{
+		{Name: "login", Method: "post", Url: "/login", Body: "{}", Weight: &two},
+		{Url: "/search", Headers: []string{"X-Search: 1"}, Weight: &three},
+		{Url: "https://other.com/a", Weight: &three},
+		{Url: "http://example.com/b", Weight: &two},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []int{2, 5, 8, 10}, weights)
+	assert.Len(t, targets, 4)
+
+	assert.Equal(t, "login", targets[0].name)
+	assert.Equal(t, "POST", string(targets[0].request.Header.Method()))
+	assert.Equal(t, "{}", string(targets[0].request.Body()))
+	assert.Equal(t, "1", string(targets[0].request.Header.Peek("X-Common")))
+	assert.Equal(t, mimeApplicationJSON, string(targets[0].request.Header.ContentType()))
+
+	assert.Equal(t, "GET /search", targets[1].name)
+	assert.Equal(t, "1", string(targets[1].request.Header.Peek("X-Search")))
+
+	assert.Same(t, targets[0].doer, targets[1].doer)
+	assert.Same(t, targets[0].doer, targets[3].doer)
+	assert.NotSame(t, targets[0].doer, targets[2].doer)
+
+	for _, list := range [][]scenario{
+		{{Url: "ftp://example.com", Weight: &two}},
+		{{Url: "/a", File: "not-exist", Weight: &two}},
+		{{Url: "/a", Headers: []string{"a"}, Weight: &two}},
+	} {
+		_, _, err = c.scenarioTargets(list)
+		assert.NotNil(t, err)
+	}
+}
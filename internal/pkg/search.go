@@ -0,0 +1,297 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// 容量搜索的默认值
+const (
+	defaultSearchErrorRate = "1%"
+	searchPrecisionSteps   = 50
+)
+
+// SearchConfig 保存容量搜索的配置，每轮压测的其余参数（连接数、持续时间、
+// 请求内容等）取自 Config
+type SearchConfig struct {
+	// By 表示搜索的对象，可选 qps（默认）、rate 与 connections
+	By string
+	// Min 表示搜索区间的下限
+	Min int
+	// Max 表示搜索区间的上限
+	Max int
+	// Precision 表示搜索精度，区间宽度不大于该值时结束搜索，
+	// 默认为区间宽度的 1/50
+	Precision int
+	// P99 表示每轮允许的 p99 延迟上限，为 0 时不校验延迟，
+	// qps 与 rate 模式下使用修正协调遗漏后的延迟
+	P99 time.Duration
+	// ErrorRate 表示每轮允许的错误率上限，例如 1% 或 0.01，默认为 1%，
+	// 丢弃的请求按错误计算
+	ErrorRate string
+}
+
+type searchStep struct {
+	target    int
+	requests  int64
+	rps       float64
+	p99       float64
+	errorRate float64
+	passed    bool
+}
+
+// Search 通过多轮短时压测二分搜索满足延迟与错误率要求的最大速率
+type Search struct {
+	c         Config
+	sc        SearchConfig
+	errorRate float64
+	w         io.Writer
+	ew        io.Writer
+	steps     []searchStep
+	round     func(target int) (searchStep, error)
+
+	mut     sync.Mutex
+	current *HttpGo
+	stopped bool
+}
+
+// NewSearch create a Search instance with specific Config and SearchConfig
+func NewSearch(c Config, sc SearchConfig) *Search {
+	s := &Search{
+		c:  c,
+		sc: sc,
+		w:  os.Stdout,
+		ew: os.Stderr,
+	}
+	s.round = s.runRound
+
+	if s.sc.By == "" {
+		s.sc.By = stageByQps
+	}
+
+	if s.sc.ErrorRate == "" {
+		s.sc.ErrorRate = defaultSearchErrorRate
+	}
+
+	if s.sc.Precision <= 0 {
+		if s.sc.Precision = (s.sc.Max - s.sc.Min) / searchPrecisionSteps; s.sc.Precision < 1 {
+			s.sc.Precision = 1
+		}
+	}
+
+	return s
+}
+
+// Run 执行容量搜索，输出每一轮的结果与拐点，区间内没有满足要求的速率时返回错误
+func (s *Search) Run() (err error) {
+	if err = s.init(); err != nil {
+		return
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+	go func() {
+		if _, ok := <-sig; ok {
+			s.stop()
+		}
+	}()
+
+	knee, ok, err := s.search()
+	if err != nil {
+		return
+	}
+
+	if err = s.writeTable(knee, ok); err != nil {
+		return
+	}
+
+	if !ok {
+		return fmt.Errorf("no sustainable %s found in [%d, %d]", s.sc.By, s.sc.Min, s.sc.Max)
+	}
+
+	return nil
+}
+
+func (s *Search) init() (err error) {
+	switch s.sc.By {
+	case stageByQps, stageByRate, stageByConnections:
+	default:
+		return fmt.Errorf("unsupported search target %q. qps, rate and connections are supported", s.sc.By)
+	}
+
+	if s.sc.Min <= 0 || s.sc.Max <= s.sc.Min {
+		return errors.New("search range must satisfy 0 < min < max")
+	}
+
+	if s.c.Stages != "" || s.c.Qps > 0 || s.c.Rate > 0 {
+		return errors.New("search cannot be used with --stages, --qps or --rate")
+	}
+
+	if s.errorRate, err = parseRatioValue(s.sc.ErrorRate); err != nil {
+		return fmt.Errorf("invalid error rate %q: %w", s.sc.ErrorRate, err)
+	}
+
+	return
+}
+
+// search 先校验区间两端，再二分搜索通过与未通过的分界点
+func (s *Search) search() (knee int, ok bool, err error) {
+	lo, hi := s.sc.Min, s.sc.Max
+
+	var step searchStep
+	if step, err = s.try(lo); err != nil || !step.passed {
+		return
+	}
+
+	if step, err = s.try(hi); err != nil || step.passed {
+		return hi, err == nil, err
+	}
+
+	for hi-lo > s.sc.Precision && !s.isStopped() {
+		mid := lo + (hi-lo)/2
+		if step, err = s.try(mid); err != nil {
+			return
+		}
+		if step.passed {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return lo, true, nil
+}
+
+func (s *Search) try(target int) (step searchStep, err error) {
+	if step, err = s.round(target); err != nil {
+		return
+	}
+	step.target = target
+	s.steps = append(s.steps, step)
+
+	result := "fail"
+	if step.passed {
+		result = "pass"
+	}
+	_, _ = fmt.Fprintf(s.ew, "[step %d] %s: %d, rps: %.2f, p99: %.2fms, error rate: %.3f%%, %s\n",
+		len(s.steps), s.sc.By, target, step.rps, step.p99, step.errorRate*100, result)
+
+	return
+}
+
+// runRound 以指定的目标值执行一轮压测
+func (s *Search) runRound(target int) (step searchStep, err error) {
+	c := s.c
+	c.Count = 0
+	c.Debug = false
+	c.Output = ""
+	c.OutputFile = ""
+	c.Asserts = nil
+	switch s.sc.By {
+	case stageByQps:
+		c.Qps = target
+	case stageByRate:
+		c.Rate = target
+	case stageByConnections:
+		c.Connections = target
+	}
+
+	p := New(c)
+	if err = p.init(); err != nil {
+		return
+	}
+
+	s.mut.Lock()
+	s.current = p
+	if s.stopped {
+		p.stop()
+	}
+	s.mut.Unlock()
+
+	p.run()
+	return s.evaluate(p.report()), nil
+}
+
+// evaluate 根据一轮压测的结果判断是否满足延迟与错误率要求
+func (s *Search) evaluate(r *report) (step searchStep) {
+	lat, h := r.correctedLatency()
+	if h != nil {
+		step.p99 = float64(h.percentile(99)) / 1000
+	} else {
+		step.p99 = lat.PercentilesMs[percentileKey(99)]
+	}
+
+	step.requests = r.Totals.Requests
+	if r.Totals.ElapsedSec != 0 {
+		step.rps = float64(r.Totals.Requests) / r.Totals.ElapsedSec
+	}
+
+	failed := r.Totals.Errors + r.Totals.Dropped
+	if total := r.Totals.Requests + failed; total > 0 {
+		step.errorRate = float64(failed) / float64(total)
+	}
+
+	step.passed = step.requests > 0 && step.errorRate <= s.errorRate
+	if s.sc.P99 > 0 && step.p99 > float64(s.sc.P99)/float64(time.Millisecond) {
+		step.passed = false
+	}
+
+	return
+}
+
+func (s *Search) writeTable(knee int, ok bool) error {
+	tw := tabwriter.NewWriter(s.w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Step\tTarget\tRequests\tRPS\tP99\tError Rate\tResult\n")
+	for i, step := range s.steps {
+		result := "fail"
+		if step.passed {
+			result = "pass"
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%d %s\t%d\t%.2f\t%.2fms\t%.3f%%\t%s\n",
+			i+1, step.target, s.sc.By, step.requests, step.rps, step.p99, step.errorRate*100, result)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	var err error
+	if ok {
+		_, err = fmt.Fprintf(s.w, "\nKnee point: %d %s (p99<=%s, error rate<=%s)\n",
+			knee, s.sc.By, s.p99Limit(), s.sc.ErrorRate)
+	} else {
+		_, err = fmt.Fprintf(s.w, "\nNo %s in [%d, %d] satisfies p99<=%s and error rate<=%s\n",
+			s.sc.By, s.sc.Min, s.sc.Max, s.p99Limit(), s.sc.ErrorRate)
+	}
+	return err
+}
+
+func (s *Search) p99Limit() string {
+	if s.sc.P99 <= 0 {
+		return "∞"
+	}
+	return s.sc.P99.String()
+}
+
+// stop 结束当前一轮压测并停止搜索
+func (s *Search) stop() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.stopped = true
+	if s.current != nil {
+		s.current.stop()
+	}
+}
+
+func (s *Search) isStopped() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.stopped
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSearch(sc SearchConfig, limit int) (*Search, *bytes.Buffer) {
+	var buf bytes.Buffer
+	s := NewSearch(Config{Url: "url"}, sc)
+	s.w = &buf
+	s.ew = &bytes.Buffer{}
+	s.round = func(target int) (searchStep, error) {
+		return searchStep{requests: 1, rps: float64(target), p99: 1, passed: target <= limit}, nil
+	}
+	return s, &buf
+}
+
+func Test_Search_Run(t *testing.T) {
+	t.Parallel()
+
+	t.Run("knee", func(t *testing.T) {
+		s, buf := newTestSearch(SearchConfig{Min: 100, Max: 1000, Precision: 10}, 550)
+		assert.Nil(t, s.Run())
+
+		knee := 0
+		for _, step := range s.steps {
+			if step.passed && step.target > knee {
+				knee = step.target
+			}
+		}
+		assert.True(t, knee <= 550 && knee > 540, knee)
+		assert.Contains(t, buf.String(), "Step")
+		assert.Contains(t, buf.String(), "Knee point: ")
+		assert.Equal(t, 100, s.steps[0].target)
+		assert.Equal(t, 1000, s.steps[1].target)
+	})
+
+	t.Run("max passes", func(t *testing.T) {
+		s, buf := newTestSearch(SearchConfig{Min: 1, Max: 10}, 100)
+		assert.Nil(t, s.Run())
+		assert.Len(t, s.steps, 2)
+		assert.Contains(t, buf.String(), "Knee point: 10 qps")
+	})
+
+	t.Run("min fails", func(t *testing.T) {
+		s, buf := newTestSearch(SearchConfig{Min: 10, Max: 100, By: stageByConnections}, 1)
+		assert.NotNil(t, s.Run())
+		assert.Len(t, s.steps, 1)
+		assert.Contains(t, buf.String(), "No connections in [10, 100]")
+	})
+
+	t.Run("round error", func(t *testing.T) {
+		s, _ := newTestSearch(SearchConfig{Min: 1, Max: 10}, 100)
+		s.round = func(int) (searchStep, error) {
+			return searchStep{}, errors.New("error")
+		}
+		assert.NotNil(t, s.Run())
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		s, _ := newTestSearch(SearchConfig{Min: 1, Max: 1000, Precision: 1}, 500)
+		s.stop()
+		assert.Nil(t, s.Run())
+		assert.Len(t, s.steps, 2)
+	})
+}
+
+func Test_Search_init(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		c  Config
+		sc SearchConfig
+	}{
+		{Config{}, SearchConfig{By: "foo", Min: 1, Max: 2}},
+		{Config{}, SearchConfig{Min: 0, Max: 2}},
+		{Config{}, SearchConfig{Min: 2, Max: 2}},
+		{Config{Qps: 1}, SearchConfig{Min: 1, Max: 2}},
+		{Config{Stages: "1s:1"}, SearchConfig{Min: 1, Max: 2}},
+		{Config{}, SearchConfig{Min: 1, Max: 2, ErrorRate: "x"}},
+	}
+	for _, tt := range tests {
+		assert.NotNil(t, NewSearch(tt.c, tt.sc).init(), tt.sc)
+	}
+
+	s := NewSearch(Config{}, SearchConfig{Min: 100, Max: 1100})
+	assert.Nil(t, s.init())
+	assert.Equal(t, stageByQps, s.sc.By)
+	assert.Equal(t, 20, s.sc.Precision)
+	assert.Equal(t, 0.01, s.errorRate)
+}
+
+func Test_Search_evaluate(t *testing.T) {
+	t.Parallel()
+
+	s := NewSearch(Config{}, SearchConfig{Min: 1, Max: 2, P99: time.Millisecond * 10, ErrorRate: "10%"})
+	assert.Nil(t, s.init())
+
+	h := newHistogram()
+	h.record(5000)
+	r := &report{latency: h, Totals: reportTotals{Requests: 9, Errors: 1, ElapsedSec: 1}}
+	step := s.evaluate(r)
+	assert.True(t, step.passed)
+	assert.Equal(t, 5.0, step.p99)
+	assert.Equal(t, 9.0, step.rps)
+	assert.Equal(t, 0.1, step.errorRate)
+
+	r.Totals.Dropped = 1
+	assert.False(t, s.evaluate(r).passed)
+
+	corrected := newHistogram()
+	corrected.record(20000)
+	r = &report{latency: h, corrected: corrected, Corrected: &reportLatency{}, Totals: reportTotals{Requests: 1}}
+	step = s.evaluate(r)
+	assert.False(t, step.passed)
+	assert.Equal(t, 20.0, step.p99)
+
+	assert.False(t, s.evaluate(&report{}).passed)
+}
+
+func Test_Search_runRound(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	for _, by := range []string{stageByQps, stageByRate, stageByConnections} {
+		s := NewSearch(Config{Url: srv.URL, Connections: 2, Duration: time.Millisecond * 100}, SearchConfig{By: by, Min: 10, Max: 100})
+		assert.Nil(t, s.init())
+
+		step, err := s.runRound(50)
+		assert.Nil(t, err, by)
+		assert.True(t, step.requests > 0, by)
+		assert.True(t, step.passed, by)
+	}
+
+	s := NewSearch(Config{Url: "ftp://url"}, SearchConfig{Min: 10, Max: 20})
+	_, err := s.runRound(10)
+	assert.NotNil(t, err)
+}
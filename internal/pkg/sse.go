@@ -0,0 +1,233 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// sseMaxLineSize 为事件流中单行的最大长度，超过时结束该流并计为错误
+const sseMaxLineSize = 1 << 20
+
+// errSseIdleTimeout 表示在超时时间内没有从事件流中读取到数据，连接随后被关闭并重新建立
+var errSseIdleTimeout = errors.New("sse stream idle timeout")
+
+// sseStats 统计 SSE 的流与事件，ttfe 为从发送请求到收到第一个事件的耗时，
+// gap 为同一个流上相邻两个事件的间隔，open 为各流保持打开的总时长（纳秒）
+type sseStats struct {
+	streams int64
+	events  int64
+	open    int64
+	ttfe    *histogram
+	gap     *histogram
+}
+
+func newSseStats() *sseStats {
+	return &sseStats{ttfe: newHistogram(), gap: newHistogram()}
+}
+
+// eventsPerSec 返回每个连接在流保持打开期间平均每秒收到的事件数
+func (s *sseStats) eventsPerSec() float64 {
+	open := time.Duration(atomic.LoadInt64(&s.open))
+	if open <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&s.events)) / open.Seconds()
+}
+
+// sseBench 以 Connections 个连接压测 SSE 服务，每个连接保持一个事件流，流结束后重新发送请求。
+// 每个事件计为一次请求，第一个事件的延迟为 TTFE，之后为与上一个事件的间隔
+type sseBench struct {
+	p     *HttpGo
+	c     *httpClient
+	stats *sseStats
+}
+
+// initSSE 检查 SSE 模式的配置，事件流需要逐个连接保持打开，不支持限流与多路复用
+func (p *HttpGo) initSSE() error {
+	switch {
+	case p.c.Debug:
+		return errors.New("sse mode cannot be used with --debug")
+	case p.c.Qps > 0 || p.c.Stages != "" || p.c.openModel():
+		return errors.New("sse mode cannot be used with --qps, --rate, --stages or paced HAR replay")
+	case p.c.HTTP2 || p.c.HTTP3 || p.c.Pipeline:
+		return errors.New("sse mode cannot be used with --http2, --http3 or --pipeline")
+	case p.c.Raw != "":
+		return errors.New("sse mode cannot be used with raw requests")
+	}
+	return nil
+}
+
+func newSseBench(p *HttpGo, c *httpClient) *sseBench {
+	b := &sseBench{p: p, c: c, stats: p.c.sse}
+	if b.stats == nil {
+		b.stats = newSseStats()
+	}
+	return b
+}
+
+// run 每个 worker 维持一个事件流，直到达到事件数或持续时间
+func (b *sseBench) run() {
+	// 事件流可能长时间没有事件，按时长结束压测
+	if b.p.c.Count <= 0 {
+		timer := time.AfterFunc(b.p.c.Duration, b.p.stop)
+		defer timer.Stop()
+	}
+
+	n := b.p.c.Connections
+	b.p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go b.worker()
+	}
+	b.p.wg.Wait()
+	b.p.stop()
+}
+
+func (b *sseBench) worker() {
+	defer b.p.wg.Done()
+
+	for {
+		select {
+		case <-b.p.doneChan:
+			return
+		default:
+		}
+
+		t := b.c.pick()
+		if err := b.c.doStream(t, func(resp *fasthttp.Response, start time.Time) error {
+			return b.stream(t.endpoint, resp, start)
+		}); err != nil {
+			b.p.record(time.Time{}, t.endpoint, 0, 0, err)
+		}
+	}
+}
+
+// stream 读取一个响应，非 2xx 响应与普通请求一样记录状态码与延迟，
+// 2xx 响应必须是事件流，读取到流结束、压测结束或读取超时
+func (b *sseBench) stream(ep *endpoint, resp *fasthttp.Response, start time.Time) (err error) {
+	code := resp.StatusCode()
+	body := resp.BodyStream()
+	if code < fasthttp.StatusOK || code >= fasthttp.StatusMultipleChoices {
+		if body != nil {
+			_, _ = io.Copy(io.Discard, body)
+		}
+		b.p.record(time.Time{}, ep, code, time.Since(start), nil)
+		return nil
+	}
+	if ct := resp.Header.ContentType(); !isEventStream(ct) {
+		return fmt.Errorf("unexpected content type %q, want %s", ct, mimeTextEventStream)
+	}
+	if body == nil {
+		return nil
+	}
+
+	atomic.AddInt64(&b.stats.streams, 1)
+	defer func() {
+		atomic.AddInt64(&b.stats.open, int64(time.Since(start)))
+	}()
+
+	err = b.events(ep, code, body, start)
+
+	select {
+	case <-b.p.doneChan:
+		return nil
+	default:
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return errSseIdleTimeout
+	}
+	return err
+}
+
+// events 逐行解析事件流，空行时分发包含 data 字段的事件，注释与其他字段不影响统计
+func (b *sseBench) events(ep *endpoint, code int, r io.Reader, start time.Time) error {
+	sc := newSseScanner(r)
+	last := start
+	data := false
+	for sc.Scan() {
+		if line := sc.Bytes(); len(line) > 0 {
+			if isDataField(line) {
+				data = true
+			}
+			continue
+		}
+		if !data {
+			continue
+		}
+		data = false
+
+		now := time.Now()
+		d := now.Sub(last)
+		if last == start {
+			observe(b.stats.ttfe, d)
+		} else {
+			observe(b.stats.gap, d)
+		}
+		last = now
+		atomic.AddInt64(&b.stats.events, 1)
+		b.p.record(time.Time{}, ep, code, d, nil)
+
+		select {
+		case <-b.p.doneChan:
+			return nil
+		default:
+		}
+	}
+
+	return sc.Err()
+}
+
+// newSseScanner 返回按行读取事件流的 Scanner，行以 \r\n、\n 或 \r 结尾，
+// 以 \r 结尾的行在读取到 \r 时立即返回，不等待可能紧随其后的 \n
+func newSseScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), sseMaxLineSize)
+
+	skipLF := false
+	sc.Split(func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		// 跳过的 \n 与下一行一起返回，Scanner 在没有返回行时会继续读取，不能单独跳过
+		start := 0
+		if skipLF && len(data) > 0 {
+			skipLF = false
+			if data[0] == '\n' {
+				start = 1
+			}
+		}
+		if i := bytes.IndexAny(data[start:], "\r\n"); i >= 0 {
+			skipLF = data[start+i] == '\r'
+			return start + i + 1, data[start : start+i], nil
+		}
+		if atEOF && len(data) > start {
+			return len(data), data[start:], nil
+		}
+		return start, nil, nil
+	})
+
+	return sc
+}
+
+// isDataField 判断一行是否为 data 字段，字段名后可以没有冒号
+func isDataField(line []byte) bool {
+	return bytes.Equal(line, strData) || bytes.HasPrefix(line, strDataColon)
+}
+
+// isEventStream 判断 Content-Type 是否为 text/event-stream，忽略参数与大小写
+func isEventStream(ct []byte) bool {
+	if i := bytes.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return bytes.EqualFold(bytes.TrimSpace(ct), []byte(mimeTextEventStream))
+}
+
+var (
+	strData      = []byte("data")
+	strDataColon = []byte("data:")
+)
@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sseServer 每 5ms 发送一个事件，记录请求的 Accept 与 Connection 头。
+// /end 发送 3 个事件后结束响应，/silent 只发送响应头，/plain 返回普通响应，/missing 返回 404
+type sseServer struct {
+	mut     sync.Mutex
+	accepts map[string]bool
+	conns   map[string]bool
+}
+
+func newSseServer() *sseServer {
+	return &sseServer{accepts: map[string]bool{}, conns: map[string]bool{}}
+}
+
+func (s *sseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/missing":
+		http.NotFound(w, r)
+		return
+	case "/plain":
+		_, _ = w.Write([]byte("data: hello\n\n"))
+		return
+	}
+
+	s.mut.Lock()
+	s.accepts[r.Header.Get("Accept")] = true
+	s.conns[r.Header.Get("Connection")] = true
+	s.mut.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	f := w.(http.Flusher)
+	f.Flush()
+	if r.URL.Path == "/silent" {
+		<-r.Context().Done()
+		return
+	}
+
+	_, _ = w.Write([]byte(": welcome\n\n"))
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for i := 0; r.URL.Path != "/end" || i < 3; i++ {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+		_, _ = fmt.Fprintf(w, "id: %d\r\nevent: tick\r\ndata: %d\r\ndata\r\n\r\n", i, i)
+		f.Flush()
+	}
+}
+
+func Test_HttpGo_Run_SSE(t *testing.T) {
+	t.Parallel()
+
+	s := newSseServer()
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	t.Run("events", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/events", SSE: true, Connections: 2, Count: 50, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, int64(50), r.Totals.Requests)
+		assert.Equal(t, int64(50), r.Codes.Code2xx)
+		assert.Equal(t, int64(2), r.SSE.Streams)
+		assert.GreaterOrEqual(t, r.SSE.Events, int64(50))
+		assert.Equal(t, int64(2), r.SSE.TTFE.Count)
+		assert.GreaterOrEqual(t, r.SSE.Gap.Count, int64(48))
+		assert.Greater(t, r.SSE.EventsPerSec, float64(0))
+		assert.Greater(t, r.Throughput.Bytes, int64(0))
+		assert.Contains(t, p.stat.output(), "SSE:")
+
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		assert.Equal(t, []string{"text/event-stream"}, keys(s.accepts))
+		assert.Equal(t, []string{"close"}, keys(s.conns))
+	})
+
+	// 流结束后重新发送请求
+	t.Run("stream end", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/end", SSE: true, Connections: 1, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Greater(t, r.SSE.Streams, int64(1))
+		assert.Greater(t, r.SSE.TTFE.Count, int64(1))
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/silent", SSE: true, Timeout: 50 * time.Millisecond, Connections: 1, Duration: 300 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Errors[errSseIdleTimeout.Error()], 0)
+		assert.Greater(t, r.SSE.Streams, int64(1))
+	})
+
+	t.Run("not event stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/plain", SSE: true, Connections: 1, Duration: 100 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Totals.Errors, int64(0))
+		for msg := range r.Errors {
+			assert.True(t, strings.HasPrefix(msg, "unexpected content type"), msg)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/missing", SSE: true, Connections: 1, Count: 5, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(5), r.Codes.Code4xx)
+		assert.Equal(t, int64(0), r.SSE.Streams)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NotNil(t, New(Config{Url: ts.URL, SSE: true, Debug: true}).Run())
+		assert.NotNil(t, New(Config{Url: ts.URL, SSE: true, Rate: 10}).Run())
+		assert.NotNil(t, New(Config{Url: ts.URL, SSE: true, HTTP2: true}).Run())
+	})
+}
+
+func Test_newSseScanner(t *testing.T) {
+	t.Parallel()
+
+	sc := newSseScanner(strings.NewReader("data: a\r\n\r\ndata: b\r\rdata: c\n\n: x\nlast"))
+	var lines []string
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	assert.Nil(t, sc.Err())
+	assert.Equal(t, []string{"data: a", "", "data: b", "", "data: c", "", ": x", "last"}, lines)
+}
+
+func Test_isEventStream(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, isEventStream([]byte("text/event-stream")))
+	assert.True(t, isEventStream([]byte("Text/Event-Stream; charset=utf-8")))
+	assert.False(t, isEventStream([]byte("text/plain")))
+	assert.False(t, isEventStream(nil))
+}
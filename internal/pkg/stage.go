@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 阶段目标值作用的对象
+const (
+	stageByQps         = "qps"
+	stageByRate        = "rate"
+	stageByConnections = "connections"
+)
+
+type stage struct {
+	duration time.Duration
+	target   int
+}
+
+// stages 表示分阶段的负载曲线，目标值在每个阶段内从上一阶段的目标值
+// 线性变化到本阶段的目标值，首个阶段从 0 开始
+type stages struct {
+	list  []stage
+	by    string
+	start int64
+}
+
+// parseStages 解析形如 30s:100,2m:1000,30s:0 的阶段配置
+func parseStages(s string) (list []stage, err error) {
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		i := strings.IndexByte(item, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid stage %q, format is duration:target", item)
+		}
+
+		var st stage
+		if st.duration, err = time.ParseDuration(strings.TrimSpace(item[:i])); err != nil || st.duration <= 0 {
+			return nil, fmt.Errorf("invalid duration in stage %q", item)
+		}
+		if st.target, err = strconv.Atoi(strings.TrimSpace(item[i+1:])); err != nil || st.target < 0 {
+			return nil, fmt.Errorf("invalid target in stage %q", item)
+		}
+		list = append(list, st)
+	}
+	return
+}
+
+func newStages(list []stage, by string) *stages {
+	if by == "" {
+		by = stageByQps
+	}
+	return &stages{list: list, by: by}
+}
+
+// begin 记录负载曲线的起始时间
+func (s *stages) begin(t time.Time) {
+	atomic.StoreInt64(&s.start, t.UnixNano())
+}
+
+func (s *stages) startTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.start))
+}
+
+func (s *stages) total() (d time.Duration) {
+	for _, st := range s.list {
+		d += st.duration
+	}
+	return
+}
+
+func (s *stages) max() (m int) {
+	for _, st := range s.list {
+		if st.target > m {
+			m = st.target
+		}
+	}
+	return
+}
+
+// at 返回经过 d 后所处阶段的下标与当前目标值，超出总时长时停留在最后一个阶段
+func (s *stages) at(d time.Duration) (index int, value float64) {
+	var from float64
+	for i, st := range s.list {
+		if d < st.duration {
+			to := float64(st.target)
+			return i, from + (to-from)*float64(d)/float64(st.duration)
+		}
+		d -= st.duration
+		from = float64(st.target)
+	}
+	return len(s.list) - 1, from
+}
+
+// current 返回当前所处阶段的下标与目标值
+func (s *stages) current() (int, float64) {
+	if atomic.LoadInt64(&s.start) == 0 {
+		return 0, 0
+	}
+	return s.at(time.Since(s.startTime()))
+}
+
+// offset 将目标值视为每秒请求数，返回第 n 个请求（从 0 开始）相对起始时间
+// 的计划发送时间，超出负载曲线时返回 false
+func (s *stages) offset(n float64) (time.Duration, bool) {
+	var from, elapsed float64
+	for _, st := range s.list {
+		to, d := float64(st.target), st.duration.Seconds()
+		sum := (from + to) / 2 * d
+		if n < sum {
+			// 求解 from*t + (to-from)/(2d)*t² = n
+			a := (to - from) / (2 * d)
+			var t float64
+			if denom := from + math.Sqrt(from*from+4*a*n); denom > 0 {
+				t = 2 * n / denom
+			}
+			return time.Duration((elapsed + t) * float64(time.Second)), true
+		}
+		n -= sum
+		elapsed += d
+		from = to
+	}
+	return 0, false
+}
+
+// active 判断第 i 个 worker 在当前阶段是否应当发送请求
+func (s *stages) active(i int) bool {
+	_, v := s.current()
+	return float64(i) < math.Ceil(v)
+}
+
+func (s *stages) unit() string {
+	switch s.by {
+	case stageByRate:
+		return "req/s"
+	case stageByConnections:
+		return "connections"
+	default:
+		return "qps"
+	}
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseStages(t *testing.T) {
+	t.Parallel()
+
+	list, err := parseStages("30s:100, 2m:1000,30s:0")
+	assert.Nil(t, err)
+	assert.Equal(t, []stage{
+		{duration: time.Second * 30, target: 100},
+		{duration: time.Minute * 2, target: 1000},
+		{duration: time.Second * 30, target: 0},
+	}, list)
+
+	for _, s := range []string{"", "30s", ":100", "x:100", "0s:100", "30s:x", "30s:-1", "30s:100,"} {
+		_, err := parseStages(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func Test_stages_at(t *testing.T) {
+	t.Parallel()
+
+	list, _ := parseStages("10s:100,10s:100,10s:0")
+	s := newStages(list, "")
+	assert.Equal(t, stageByQps, s.by)
+	assert.Equal(t, time.Second*30, s.total())
+	assert.Equal(t, 100, s.max())
+
+	tests := []struct {
+		d     time.Duration
+		index int
+		value float64
+	}{
+		{0, 0, 0},
+		{time.Second * 5, 0, 50},
+		{time.Second * 15, 1, 100},
+		{time.Second * 25, 2, 50},
+		{time.Minute, 2, 0},
+	}
+	for _, tt := range tests {
+		i, v := s.at(tt.d)
+		assert.Equal(t, tt.index, i, tt.d)
+		assert.InDelta(t, tt.value, v, 1e-9, tt.d)
+	}
+
+	i, v := s.current()
+	assert.Equal(t, 0, i)
+	assert.Equal(t, 0.0, v)
+}
+
+func Test_stages_offset(t *testing.T) {
+	t.Parallel()
+
+	// 0 -> 100 over 10s sends 500 requests, then 100/s for 10s
+	list, _ := parseStages("10s:100,10s:100")
+	s := newStages(list, stageByRate)
+
+	tests := []struct {
+		n float64
+		d time.Duration
+	}{
+		{0, 0},
+		{125, time.Second * 5},
+		{500, time.Second * 10},
+		{600, time.Second * 11},
+	}
+	for _, tt := range tests {
+		d, ok := s.offset(tt.n)
+		assert.True(t, ok)
+		assert.InDelta(t, float64(tt.d), float64(d), float64(time.Microsecond), tt.n)
+	}
+
+	_, ok := s.offset(1500)
+	assert.False(t, ok)
+
+	// ramp down
+	list, _ = parseStages("10s:100,10s:0")
+	s = newStages(list, stageByRate)
+	d, ok := s.offset(500 + 375)
+	assert.True(t, ok)
+	assert.InDelta(t, float64(time.Second*15), float64(d), float64(time.Microsecond))
+}
+
+func Test_stages_active(t *testing.T) {
+	t.Parallel()
+
+	list, _ := parseStages("1h:10")
+	s := newStages(list, stageByConnections)
+	s.begin(time.Now().Add(-time.Minute * 29))
+
+	assert.True(t, s.active(0))
+	assert.True(t, s.active(4))
+	assert.False(t, s.active(5))
+	assert.Equal(t, "connections", s.unit())
+}
+
+func Test_HttpGo_initStages(t *testing.T) {
+	t.Parallel()
+
+	p := New(Config{Url: "url", Stages: "1s:10,2s:20"})
+	assert.Nil(t, p.init())
+	assert.IsType(t, &stageLimiter{}, p.limiter)
+	assert.Equal(t, time.Second*3, p.c.Duration)
+	assert.Equal(t, time.Second*3, p.stat.duration)
+
+	p = New(Config{Url: "url", Stages: "1s:10,2s:20", StageBy: stageByConnections})
+	assert.Nil(t, p.init())
+	assert.Equal(t, 20, p.c.Connections)
+
+	p = New(Config{Url: "url", Stages: "1s:10", StageBy: stageByRate})
+	assert.Nil(t, p.init())
+	assert.True(t, p.c.openModel())
+	assert.Equal(t, defaultMaxInFlight, p.c.maxConns())
+
+	for _, c := range []Config{
+		{Url: "url", Stages: "1s:10", Qps: 1},
+		{Url: "url", Stages: "1s:10", Rate: 1},
+		{Url: "url", Stages: "1s"},
+		{Url: "url", Stages: "1s:10", StageBy: "foo"},
+		{Url: "url", Stages: "1s:0", StageBy: stageByConnections},
+	} {
+		assert.NotNil(t, New(c).init(), c.Stages)
+	}
+}
+
+func Test_HttpGo_run_stages(t *testing.T) {
+	t.Parallel()
+
+	for _, by := range []string{stageByQps, stageByRate, stageByConnections} {
+		by := by
+		t.Run(by, func(t *testing.T) {
+			t.Parallel()
+
+			p := New(Config{Url: "url", Stages: "100ms:200,100ms:0", StageBy: by})
+			assert.Nil(t, p.init())
+			fc := newFakeClient()
+			p.client = fc
+
+			begin := time.Now()
+			assert.Equal(t, done, p.run().(int))
+			assert.Less(t, time.Since(begin), time.Second)
+			assert.True(t, atomic.LoadInt64(&fc.count) > 0)
+
+			r := p.report()
+			assert.Equal(t, by, r.Config.StageBy)
+			assert.Len(t, r.Config.Stages, 2)
+			assert.Equal(t, 2, r.Stage.Index)
+			assert.Equal(t, 2, r.Stage.Count)
+		})
+	}
+}
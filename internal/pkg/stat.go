@@ -5,6 +5,7 @@ import (
     "math"
     "os"
     "strconv"
+    "strings"
     "sync"
     "sync/atomic"
     "time"
@@ -12,25 +13,38 @@ import (
     "github.com/charmbracelet/bubbles/progress"
     tea "github.com/charmbracelet/bubbletea"
     "github.com/charmbracelet/lipgloss"
+    "github.com/muesli/termenv"
     "github.com/valyala/bytebufferpool"
     "github.com/valyala/fasthttp"
 )
 
 const (
-    done         = 1
-    fieldWidth   = 18
-    defaultFps   = time.Duration(40)
-    padding      = 2
-    maxWidth     = 66
-    processColor = "#444"
+    done            = 1
+    fieldWidth      = 18
+    percentileWidth = 21
+    phaseWidth      = 13
+    endpointWidth   = 12
+    nameWidth       = 18
+    defaultFps      = time.Duration(40)
+    padding         = 2
+    maxWidth        = 66
+    processColor    = "#444"
 )
 
 type stat struct {
-    r io.Reader
-    w io.Writer
+    r  io.Reader
+    w  io.Writer
+    ew io.Writer
 
     throughput *int64
+    bodies     *bodyCounter
+    h2         *http2Stats
+    h3         *http3Stats
+    ws         *wsStats
+    sse        *sseStats
+    grpc       *grpcStats
     reqs       int64
+    dropped    int64
     elapsed    int64
     code1xx    int64
     code2xx    int64
@@ -38,8 +52,13 @@ type stat struct {
     code4xx    int64
     code5xx    int64
     codeOthers int64
-    latencies  []int64
+    latency    *histogram
+    corrected  *histogram
+    phases     *phases
+    stages     *stages
+    endpoints  []*endpoint
     rps        []float64
+    seconds    []int64
     mut        sync.Mutex
     errs       map[string]int
     buf        *bytebufferpool.ByteBuffer
@@ -48,8 +67,12 @@ type stat struct {
     count       int
     duration    time.Duration
     connections int
+    rate        int
+    maxInFlight int
     initCmd     tea.Cmd
     progressBar progress.Model
+    renderer    *lipgloss.Renderer
+    plain       bool
     quitting    bool
     done        bool
 }
@@ -60,7 +83,10 @@ func newStat() *stat {
     return &stat{
         r:           os.Stdin,
         w:           os.Stdout,
+        ew:          os.Stderr,
         errs:        make(map[string]int),
+        latency:     newHistogram(),
+        corrected:   newHistogram(),
         buf:         bytebufferpool.Get(),
         progressBar: progressBar,
     }
@@ -115,6 +141,14 @@ func (t *stat) View() string {
 }
 
 func (t *stat) appendCode(code int) {
+    // gRPC 模式下按 grpc-status 统计，未定义的状态码计入 Others
+    if t.grpc != nil {
+        if !t.grpc.add(code) {
+            t.codeOthers++
+        }
+        return
+    }
+
     switch code / 100 {
     case 1:
         t.code1xx++
@@ -135,8 +169,31 @@ func (t *stat) appendRps(rps float64) {
     t.rps = append(t.rps, rps)
 }
 
+// appendSecond 按秒统计完成的请求数，at 为自压测开始经过的时间
+func (t *stat) appendSecond(at time.Duration) {
+    i := int(at / time.Second)
+    for len(t.seconds) <= i {
+        t.seconds = append(t.seconds, 0)
+    }
+    t.seconds[i]++
+}
+
 func (t *stat) appendLatency(latency time.Duration) {
-    t.latencies = append(t.latencies, latency.Microseconds())
+    t.latency.record(latency.Microseconds())
+}
+
+// appendCorrectedLatency 记录从计划发送时间起算的延迟，
+// 计划时间晚于实际发送时间时以实际延迟为准
+func (t *stat) appendCorrectedLatency(sinceIntended, latency time.Duration) {
+    if sinceIntended < latency {
+        sinceIntended = latency
+    }
+    t.corrected.record(sinceIntended.Microseconds())
+}
+
+// appendDropped 记录一次因 in-flight 请求达到上限而被丢弃的请求
+func (t *stat) appendDropped() {
+    atomic.AddInt64(&t.dropped, 1)
 }
 
 func (t *stat) appendError(err error) {
@@ -149,11 +206,23 @@ func (t *stat) output() string {
     t.buf.Reset()
 
     t.writeTitle()
-    t.writeProcessBar()
+    if !t.plain {
+        t.writeProcessBar()
+    }
+    t.writeStage()
     t.writeTotalRequest()
     t.writeElapsed()
     t.writeThroughput()
+    t.writeBodies()
+    t.writeHTTP2()
+    t.writeHTTP3()
+    t.writeWebSocket()
+    t.writeSSE()
+    t.writeGRPC()
     t.writeStatistics()
+    t.writePercentiles()
+    t.writePhases()
+    t.writeEndpoints()
     t.writeCodes()
     t.writeErrors()
     t.writeHint()
@@ -164,6 +233,14 @@ func (t *stat) output() string {
 func (t *stat) writeTitle() {
     _, _ = t.buf.WriteString("Benchmarking ")
     _, _ = t.buf.WriteString(t.url)
+    if t.rate > 0 {
+        _, _ = t.buf.WriteString(" at ")
+        t.writeInt(t.rate)
+        _, _ = t.buf.WriteString(" req/s (max ")
+        t.writeInt(t.maxInFlight)
+        _, _ = t.buf.WriteString(" in-flight)\n")
+        return
+    }
     _, _ = t.buf.WriteString(" with ")
     t.writeInt(t.connections)
     _, _ = t.buf.WriteString(" connections\n")
@@ -185,6 +262,26 @@ func (t *stat) writeProcessBar() {
     _ = t.buf.WriteByte('\n')
 }
 
+// writeStage 输出当前所处的阶段与目标值
+func (t *stat) writeStage() {
+    if t.stages == nil {
+        return
+    }
+
+    i, v := t.stages.current()
+    _, _ = t.buf.WriteString("Stage:  ")
+    t.writeInt(i + 1)
+    _ = t.buf.WriteByte('/')
+    t.writeInt(len(t.stages.list))
+    _, _ = t.buf.WriteString("  Target:  ")
+    t.writeInt(t.stages.list[i].target)
+    _, _ = t.buf.WriteString("  Current:  ")
+    t.writeInt(int(math.Round(v)))
+    _ = t.buf.WriteByte(' ')
+    _, _ = t.buf.WriteString(t.stages.unit())
+    _ = t.buf.WriteByte('\n')
+}
+
 func (t *stat) writeTotalRequest() {
     _, _ = t.buf.WriteString("Requests:  ")
     t.writeInt(int(atomic.LoadInt64(&t.reqs)))
@@ -193,6 +290,11 @@ func (t *stat) writeTotalRequest() {
         t.writeInt(t.count)
     }
     _, _ = t.buf.WriteString("  ")
+    if t.rate > 0 {
+        _, _ = t.buf.WriteString("Dropped:  ")
+        t.writeInt(int(atomic.LoadInt64(&t.dropped)))
+        _, _ = t.buf.WriteString("  ")
+    }
 }
 
 func (t *stat) writeElapsed() {
@@ -223,41 +325,285 @@ func (t *stat) writeThroughput() {
     _ = t.buf.WriteByte('\n')
 }
 
+// writeBodies 输出响应体压缩与解压后的字节数，仅在指定 Accept-Encoding 时展示
+func (t *stat) writeBodies() {
+    if t.bodies == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("Body:  ")
+    wire, unit := formatBytes(float64(atomic.LoadInt64(&t.bodies.wire)))
+    t.writeFloat(wire)
+    _, _ = t.buf.WriteString(" " + unit + " wire, ")
+    decoded, unit := formatBytes(float64(atomic.LoadInt64(&t.bodies.decoded)))
+    t.writeFloat(decoded)
+    _, _ = t.buf.WriteString(" " + unit + " decoded (")
+    t.writeFloat(t.bodies.ratio())
+    _, _ = t.buf.WriteString("x)\n")
+}
+
+// writeHTTP2 输出 HTTP/2 的连接数与流数，仅在使用 --http2 时展示
+func (t *stat) writeHTTP2() {
+    if t.h2 == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("HTTP/2:  ")
+    t.writeInt(int(atomic.LoadInt64(&t.h2.conns)))
+    _, _ = t.buf.WriteString(" conns, ")
+    t.writeInt(int(atomic.LoadInt64(&t.h2.streams)))
+    _, _ = t.buf.WriteString(" streams (max ")
+    t.writeInt(int(atomic.LoadInt64(&t.h2.maxActive)))
+    _, _ = t.buf.WriteString(" concurrent), errors: ")
+    t.writeInt(int(atomic.LoadInt64(&t.h2.streamErrors)))
+    _, _ = t.buf.WriteString(" stream, ")
+    t.writeInt(int(atomic.LoadInt64(&t.h2.connErrors)))
+    _, _ = t.buf.WriteString(" conn\n")
+}
+
+// writeHTTP3 输出 HTTP/3 的连接数、0-RTT 连接数与流数，仅在使用 --http3 时展示
+func (t *stat) writeHTTP3() {
+    if t.h3 == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("HTTP/3:  ")
+    t.writeInt(int(atomic.LoadInt64(&t.h3.conns)))
+    _, _ = t.buf.WriteString(" conns (")
+    t.writeInt(int(atomic.LoadInt64(&t.h3.zeroRTT)))
+    _, _ = t.buf.WriteString(" 0-RTT), ")
+    t.writeInt(int(atomic.LoadInt64(&t.h3.streams)))
+    _, _ = t.buf.WriteString(" streams, errors: ")
+    t.writeInt(int(atomic.LoadInt64(&t.h3.streamErrors)))
+    _, _ = t.buf.WriteString(" stream, ")
+    t.writeInt(int(atomic.LoadInt64(&t.h3.connErrors)))
+    _, _ = t.buf.WriteString(" conn\n")
+}
+
+// writeWebSocket 输出 WebSocket 的连接数、建立连接的耗时、消息数与关闭状态码，
+// 仅在 ws 模式下展示，消息的往返时间见延迟统计
+func (t *stat) writeWebSocket() {
+    if t.ws == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("WebSocket:  ")
+    t.writeInt(int(atomic.LoadInt64(&t.ws.conns)))
+    _, _ = t.buf.WriteString(" conns (connect avg ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(t.ws.connect.mean()/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms, p99 ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(float64(t.ws.connect.percentile(99))/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms), messages: ")
+    t.writeInt(int(atomic.LoadInt64(&t.ws.sent)))
+    _, _ = t.buf.WriteString(" sent, ")
+    t.writeInt(int(atomic.LoadInt64(&t.ws.received)))
+    _, _ = t.buf.WriteString(" received, ")
+    t.writeInt(int(atomic.LoadInt64(&t.ws.unmatched)))
+    _, _ = t.buf.WriteString(" unmatched\n")
+
+    codes, counts := t.ws.codes()
+    if len(codes) == 0 {
+        return
+    }
+    _, _ = t.buf.WriteString("Close codes:\n  ")
+    for i, code := range codes {
+        if i > 0 {
+            _, _ = t.buf.WriteString(", ")
+        }
+        _, _ = t.buf.WriteString(strconv.Itoa(code) + " - ")
+        t.writeInt(int(counts[i]))
+    }
+    _ = t.buf.WriteByte('\n')
+}
+
+// writeSSE 输出 SSE 的流数、事件数、每个连接每秒的事件数以及 TTFE 与事件间隔，
+// 仅在 sse 模式下展示
+func (t *stat) writeSSE() {
+    if t.sse == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("SSE:  ")
+    t.writeInt(int(atomic.LoadInt64(&t.sse.streams)))
+    _, _ = t.buf.WriteString(" streams, ")
+    t.writeInt(int(atomic.LoadInt64(&t.sse.events)))
+    _, _ = t.buf.WriteString(" events (")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(t.sse.eventsPerSec(), 'f', 2, 64))
+    _, _ = t.buf.WriteString("/sec per conn), TTFE avg ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(t.sse.ttfe.mean()/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms, p99 ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(float64(t.sse.ttfe.percentile(99))/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms, gap avg ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(t.sse.gap.mean()/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms, p99 ")
+    _, _ = t.buf.WriteString(strconv.FormatFloat(float64(t.sse.gap.percentile(99))/1000, 'f', 2, 64))
+    _, _ = t.buf.WriteString("ms\n")
+}
+
+// writeGRPC 输出 gRPC 的连接数与调用中收发的消息数，仅在 grpc 模式下展示
+func (t *stat) writeGRPC() {
+    if t.grpc == nil {
+        return
+    }
+
+    _, _ = t.buf.WriteString("gRPC:  ")
+    t.writeInt(t.grpc.conns)
+    _, _ = t.buf.WriteString(" conns, messages: ")
+    t.writeInt(int(atomic.LoadInt64(&t.grpc.sent)))
+    _, _ = t.buf.WriteString(" sent, ")
+    t.writeInt(int(atomic.LoadInt64(&t.grpc.received)))
+    _, _ = t.buf.WriteString(" received\n")
+}
+
 func (t *stat) writeStatistics() {
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(12).Align(lipgloss.Center).Render("Statistics  "))
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Statistics  "))
 
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(fieldWidth).Align(lipgloss.Center).Render("Avg"))
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(fieldWidth).Align(lipgloss.Center).Render("Stdev"))
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(fieldWidth).Align(lipgloss.Center).Render("Max"))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render("Avg"))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render("Stdev"))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render("Max"))
     _ = t.buf.WriteByte('\n')
 
     rpsAvg, rpsStdev, rpsMax := rpsResult(t.rps)
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(12).Align(lipgloss.Center).Render("Reqs/sec  "))
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Reqs/sec  "))
 
     t.writeRps(rpsAvg)
     t.writeRps(rpsStdev)
     t.writeRps(rpsMax)
     _ = t.buf.WriteByte('\n')
 
-    latencyAvg, latencyStdev, latencyMax := latencyResult(t.latencies)
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(12).Align(lipgloss.Center).Render("Latency  "))
+    latencyAvg, latencyStdev, latencyMax := latencyResult(t.latency)
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Latency  "))
     t.writeLatency(latencyAvg)
     t.writeLatency(latencyStdev)
     t.writeLatency(latencyMax)
     _ = t.buf.WriteByte('\n')
+
+    if t.corrected.count() == 0 {
+        return
+    }
+
+    correctedAvg, correctedStdev, correctedMax := latencyResult(t.corrected)
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Corrected  "))
+    t.writeLatency(correctedAvg)
+    t.writeLatency(correctedStdev)
+    t.writeLatency(correctedMax)
+    _ = t.buf.WriteByte('\n')
+}
+
+// latencyPercentiles 为报告中展示的延迟百分位
+var latencyPercentiles = []float64{50, 75, 90, 99, 99.9, 99.99}
+
+func (t *stat) writePercentiles() {
+    if t.corrected.count() > 0 {
+        t.writeCorrectedPercentiles()
+        return
+    }
+
+    _, _ = t.buf.WriteString("Latency Distribution:\n")
+    for i, q := range latencyPercentiles {
+        if i%3 == 0 {
+            _, _ = t.buf.WriteString("  ")
+        }
+        s := "P" + strconv.FormatFloat(q, 'f', -1, 64) + ": " +
+            strconv.FormatFloat(float64(t.latency.percentile(q))/1000, 'f', 2, 64) + "ms"
+        _, _ = t.buf.WriteString(t.style().Width(percentileWidth).Render(s))
+        if i%3 == 2 || i == len(latencyPercentiles)-1 {
+            _ = t.buf.WriteByte('\n')
+        }
+    }
+}
+
+// writeCorrectedPercentiles 将原始延迟与修正协调遗漏后的延迟分布并列输出
+func (t *stat) writeCorrectedPercentiles() {
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Percentile  "))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render("Latency"))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render("Corrected"))
+    _ = t.buf.WriteByte('\n')
+
+    for _, q := range latencyPercentiles {
+        _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("P" + strconv.FormatFloat(q, 'f', -1, 64) + "  "))
+        t.writeLatency(float64(t.latency.percentile(q)) / 1000)
+        t.writeLatency(float64(t.corrected.percentile(q)) / 1000)
+        _ = t.buf.WriteByte('\n')
+    }
+}
+
+// writePhases 输出各阶段耗时分布，未记录的阶段不展示
+func (t *stat) writePhases() {
+    if t.phases == nil || t.phases.empty() {
+        return
+    }
+
+    _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render("Phases  "))
+    for _, title := range []string{"Avg", "P50", "P99", "Max"} {
+        _, _ = t.buf.WriteString(t.style().Width(phaseWidth).Align(lipgloss.Center).Render(title))
+    }
+    _ = t.buf.WriteByte('\n')
+
+    for _, ph := range t.phases.list() {
+        if ph.h.count() == 0 {
+            continue
+        }
+        _, _ = t.buf.WriteString(t.style().Width(12).Align(lipgloss.Center).Render(ph.name + "  "))
+        for _, v := range []float64{
+            ph.h.mean(),
+            float64(ph.h.percentile(50)),
+            float64(ph.h.percentile(99)),
+            float64(ph.h.maxValue()),
+        } {
+            s := strconv.FormatFloat(v/1000, 'f', 2, 64) + "ms"
+            _, _ = t.buf.WriteString(t.style().Width(phaseWidth).Align(lipgloss.Center).Render(s))
+        }
+        _ = t.buf.WriteByte('\n')
+    }
+}
+
+// writeEndpoints 按端点输出请求数、错误数与延迟，仅在使用场景文件时展示
+func (t *stat) writeEndpoints() {
+    if len(t.endpoints) == 0 {
+        return
+    }
+
+    _, _ = t.buf.WriteString(t.style().Width(nameWidth).Render("Endpoints"))
+    for _, title := range []string{"Reqs", "Errors", "Avg", "P99"} {
+        _, _ = t.buf.WriteString(t.style().Width(endpointWidth).Align(lipgloss.Center).Render(title))
+    }
+    _ = t.buf.WriteByte('\n')
+
+    for _, ep := range t.endpoints {
+        name := ep.name
+        if r := []rune(name); len(r) > nameWidth-3 {
+            name = string(r[:nameWidth-4]) + "…"
+        }
+        _, _ = t.buf.WriteString(t.style().Width(nameWidth).Render("  " + name))
+        for _, s := range []string{
+            strconv.FormatInt(atomic.LoadInt64(&ep.reqs), 10),
+            strconv.FormatInt(atomic.LoadInt64(&ep.errs), 10),
+            strconv.FormatFloat(ep.latency.mean()/1000, 'f', 2, 64) + "ms",
+            strconv.FormatFloat(float64(ep.latency.percentile(99))/1000, 'f', 2, 64) + "ms",
+        } {
+            _, _ = t.buf.WriteString(t.style().Width(endpointWidth).Align(lipgloss.Center).Render(s))
+        }
+        _ = t.buf.WriteByte('\n')
+    }
 }
 
 func (t *stat) writeRps(rps float64) {
     s := strconv.FormatFloat(rps, 'f', 2, 64)
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(fieldWidth).Align(lipgloss.Center).Render(s))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render(s))
 }
 
 func (t *stat) writeLatency(latency float64) {
     s := strconv.FormatFloat(latency, 'f', 2, 64)
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Width(fieldWidth).Align(lipgloss.Center).Render(s + "ms"))
+    _, _ = t.buf.WriteString(t.style().Width(fieldWidth).Align(lipgloss.Center).Render(s + "ms"))
 }
 
 func (t *stat) writeCodes() {
+    if t.grpc != nil {
+        t.writeGrpcCodes()
+        return
+    }
+
     _, _ = t.buf.WriteString("HTTP codes:\n  ")
 
     _, _ = t.buf.WriteString("1xx - ")
@@ -285,6 +631,34 @@ func (t *stat) writeCodes() {
     _, _ = t.buf.WriteString("\n")
 }
 
+// writeGrpcCodes 输出各 grpc-status 的次数，只展示出现过的状态码
+func (t *stat) writeGrpcCodes() {
+    _, _ = t.buf.WriteString("gRPC codes:\n  ")
+
+    n := 0
+    for code := range t.grpc.codes {
+        count := atomic.LoadInt64(&t.grpc.codes[code])
+        if count == 0 && code != 0 {
+            continue
+        }
+        if n > 0 {
+            _, _ = t.buf.WriteString(", ")
+        }
+        n++
+        color := "#ff8700"
+        if code == 0 {
+            color = "#00ff00"
+        }
+        _, _ = t.buf.WriteString(grpcCodeName(code) + " - ")
+        t.writeInt(int(count), color)
+    }
+    _, _ = t.buf.WriteString("\n  ")
+
+    _, _ = t.buf.WriteString("Others - ")
+    t.writeInt(int(atomic.LoadInt64(&t.codeOthers)), "#444")
+    _, _ = t.buf.WriteString("\n")
+}
+
 func (t *stat) writeErrors() {
     t.mut.Lock()
     defer t.mut.Unlock()
@@ -295,7 +669,7 @@ func (t *stat) writeErrors() {
     _, _ = t.buf.WriteString("Errors:\n")
     for err, count := range t.errs {
         _, _ = t.buf.WriteString("  ")
-        _, _ = t.buf.WriteString(lipgloss.NewStyle().Underline(true).Render(err))
+        _, _ = t.buf.WriteString(t.style().Underline(true).Render(err))
         _, _ = t.buf.WriteString(": ")
         t.writeInt(count)
         _ = t.buf.WriteByte('\n')
@@ -304,11 +678,102 @@ func (t *stat) writeErrors() {
 
 func (t *stat) writeHint() {
     if t.done {
-        _, _ = t.buf.WriteString(lipgloss.NewStyle().Background(lipgloss.Color("#008700")).Render(" Done! \n"))
+        _, _ = t.buf.WriteString(t.style().Background(lipgloss.Color("#008700")).Render(" Done! \n"))
     } else if t.quitting {
-        _, _ = t.buf.WriteString(lipgloss.NewStyle().Background(lipgloss.Color("#870000")).Render(" Terminated! \n"))
-    } else {
-        _, _ = t.buf.WriteString(lipgloss.NewStyle().Background(lipgloss.Color("#444")).Render(" press q/esc/ctrl+c to quit "))
+        _, _ = t.buf.WriteString(t.style().Background(lipgloss.Color("#870000")).Render(" Terminated! \n"))
+    } else if !t.plain {
+        _, _ = t.buf.WriteString(t.style().Background(lipgloss.Color("#444")).Render(" press q/esc/ctrl+c to quit "))
+    }
+}
+
+// style 返回当前渲染器的样式，纯文本模式下不输出任何 ANSI 控制符
+func (t *stat) style() lipgloss.Style {
+    if t.renderer == nil {
+        return lipgloss.NewStyle()
+    }
+    return t.renderer.NewStyle()
+}
+
+// writePlain 向标准输出写入不含 ANSI 控制符的最终结果
+func (t *stat) writePlain() error {
+    t.plain = true
+    t.renderer = lipgloss.NewRenderer(io.Discard)
+    t.renderer.SetColorProfile(termenv.Ascii)
+
+    _, err := io.WriteString(t.w, t.output())
+    return err
+}
+
+// writeProgress 向标准错误写入一行纯文本进度
+func (t *stat) writeProgress() {
+    elapsed := time.Duration(atomic.LoadInt64(&t.elapsed))
+    reqs := atomic.LoadInt64(&t.reqs)
+
+    var errs int
+    t.mut.Lock()
+    for _, n := range t.errs {
+        errs += n
+    }
+    t.mut.Unlock()
+
+    b := bytebufferpool.Get()
+    defer bytebufferpool.Put(b)
+
+    _ = b.WriteByte('[')
+    b.B = strconv.AppendFloat(b.B, elapsed.Seconds(), 'f', 2, 64)
+    _, _ = b.WriteString("s] requests: ")
+    b.B = strconv.AppendInt(b.B, reqs, 10)
+    if t.count != 0 {
+        _ = b.WriteByte('/')
+        b.B = strconv.AppendInt(b.B, int64(t.count), 10)
+    }
+    _, _ = b.WriteString(", errors: ")
+    b.B = strconv.AppendInt(b.B, int64(errs), 10)
+    if t.rate > 0 {
+        _, _ = b.WriteString(", dropped: ")
+        b.B = strconv.AppendInt(b.B, atomic.LoadInt64(&t.dropped), 10)
+    }
+    if t.stages != nil {
+        i, v := t.stages.current()
+        _, _ = b.WriteString(", stage: ")
+        b.B = strconv.AppendInt(b.B, int64(i+1), 10)
+        _ = b.WriteByte('/')
+        b.B = strconv.AppendInt(b.B, int64(len(t.stages.list)), 10)
+        _, _ = b.WriteString(" (")
+        b.B = strconv.AppendFloat(b.B, v, 'f', 0, 64)
+        _ = b.WriteByte(' ')
+        _, _ = b.WriteString(t.stages.unit())
+        _ = b.WriteByte(')')
+    }
+    _, _ = b.WriteString(", rps: ")
+    var rps, throughput float64
+    if seconds := elapsed.Seconds(); seconds != 0 {
+        rps = float64(reqs) / seconds
+        if t.throughput != nil {
+            throughput = float64(atomic.LoadInt64(t.throughput)) / seconds
+        }
+    }
+    b.B = strconv.AppendFloat(b.B, rps, 'f', 2, 64)
+    _, _ = b.WriteString(", p99: ")
+    b.B = strconv.AppendFloat(b.B, float64(t.latency.percentile(99))/1000, 'f', 2, 64)
+    _, _ = b.WriteString("ms, throughput: ")
+    throughput, unit := formatThroughput(throughput)
+    b.B = strconv.AppendFloat(b.B, throughput, 'f', 2, 64)
+    _ = b.WriteByte(' ')
+    _, _ = b.WriteString(unit)
+    _ = b.WriteByte('\n')
+
+    _, _ = t.ew.Write(b.B)
+}
+
+func (t *stat) status() string {
+    switch {
+    case t.done:
+        return "done"
+    case t.quitting:
+        return "terminated"
+    default:
+        return "running"
     }
 }
 
@@ -318,7 +783,7 @@ func (t *stat) writeInt(i int, colorStr ...string) {
         return
     }
 
-    _, _ = t.buf.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(colorStr[0])).Render(strconv.Itoa(i)))
+    _, _ = t.buf.WriteString(t.style().Foreground(lipgloss.Color(colorStr[0])).Render(strconv.Itoa(i)))
 }
 
 func (t *stat) writeFloat(f float64) {
@@ -340,6 +805,9 @@ func rpsResult(rps []float64) (avg float64, stdev float64, max float64) {
     }
 
     avg = sum / float64(l)
+    if l == 1 {
+        return
+    }
 
     var diff float64
     for _, r := range rps {
@@ -352,35 +820,23 @@ func rpsResult(rps []float64) (avg float64, stdev float64, max float64) {
     return
 }
 
-func latencyResult(latencies []int64) (avg float64, stdev float64, max float64) {
-    l := len(latencies)
-    if l == 0 {
+func latencyResult(h *histogram) (avg float64, stdev float64, max float64) {
+    if h.count() == 0 {
         return
     }
 
-    var sum float64
-    for _, latency := range latencies {
-        r := float64(latency) / 1000
-        sum += r
-        if r > max {
-            max = r
-        }
-    }
-
-    avg = sum / float64(l)
-
-    var diff, sum2 float64
-    for _, latency := range latencies {
-        r := float64(latency) / 1000
-        diff = avg - r
-        sum2 += diff * diff
-    }
-
-    stdev = math.Sqrt(sum2 / float64(l-1))
+    avg = h.mean() / 1000
+    stdev = h.stdev() / 1000
+    max = float64(h.maxValue()) / 1000
 
     return
 }
 
+func formatBytes(n float64) (float64, string) {
+    v, unit := formatThroughput(n)
+    return v, strings.TrimSuffix(unit, "/s")
+}
+
 func formatThroughput(throughput float64) (float64, string) {
     switch {
     case throughput < 1e3:
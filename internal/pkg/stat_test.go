@@ -1,6 +1,8 @@
 package pkg
 
 import (
+    "bytes"
+    "errors"
     "io"
     "os"
     "testing"
@@ -71,6 +73,127 @@ func Test_stat_writeThroughput(t *testing.T) {
     assert.Contains(t, tt.buf.String(), "1.00 KB/s")
 }
 
+func Test_stat_writePercentiles(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    for i := 1; i <= 100; i++ {
+        tt.appendLatency(time.Duration(i) * time.Millisecond)
+    }
+    tt.writePercentiles()
+    assert.Contains(t, tt.buf.String(), "P50: 50.")
+    assert.Contains(t, tt.buf.String(), "P99.99: 100.00ms")
+}
+
+func Test_stat_rate(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    tt.url = "url"
+    tt.rate = 100
+    tt.maxInFlight = 8
+    tt.appendDropped()
+    tt.appendDropped()
+
+    tt.writeTitle()
+    tt.writeTotalRequest()
+    assert.Contains(t, tt.buf.String(), "at 100 req/s (max 8 in-flight)")
+    assert.Contains(t, tt.buf.String(), "Dropped:  2")
+
+    ew := &bytes.Buffer{}
+    tt.ew = ew
+    tt.writeProgress()
+    assert.Contains(t, ew.String(), "dropped: 2")
+}
+
+func Test_stat_writeStage(t *testing.T) {
+    t.Parallel()
+
+    list, _ := parseStages("1h:100,1h:1000")
+    tt := newStat()
+    tt.stages = newStages(list, stageByQps)
+    tt.stages.begin(time.Now().Add(-time.Minute * 30))
+
+    tt.writeStage()
+    assert.Equal(t, "Stage:  1/2  Target:  100  Current:  50 qps\n", tt.buf.String())
+
+    ew := &bytes.Buffer{}
+    tt.ew = ew
+    tt.writeProgress()
+    assert.Contains(t, ew.String(), "stage: 1/2 (50 qps)")
+}
+
+func Test_stat_writeEndpoints(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    tt.writeEndpoints()
+    assert.Equal(t, "", tt.buf.String())
+
+    login := newEndpoint("login")
+    login.record(200, time.Millisecond*2, nil)
+    long := newEndpoint("GET /a/very/long/path/name")
+    long.record(0, 0, errors.New("error"))
+    tt.endpoints = []*endpoint{login, long}
+
+    tt.writeEndpoints()
+    out := tt.buf.String()
+    assert.Contains(t, out, "Endpoints")
+    assert.Contains(t, out, "login")
+    assert.Contains(t, out, "2.00ms")
+    assert.Contains(t, out, "GET /a/very/lo…")
+}
+
+func Test_stat_appendCorrectedLatency(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    tt.appendCorrectedLatency(time.Millisecond*5, time.Millisecond*2)
+    tt.appendCorrectedLatency(time.Millisecond, time.Millisecond*3)
+    assert.Equal(t, int64(2), tt.corrected.count())
+    assert.Equal(t, int64(3000), tt.corrected.minValue())
+    assert.Equal(t, int64(5000), tt.corrected.maxValue())
+}
+
+func Test_stat_writeCorrected(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    tt.appendLatency(time.Millisecond)
+    tt.appendCorrectedLatency(time.Millisecond*40, time.Millisecond)
+    tt.writeStatistics()
+    tt.writePercentiles()
+
+    out := tt.buf.String()
+    assert.Contains(t, out, "Corrected")
+    assert.Contains(t, out, "P99.9")
+    assert.Contains(t, out, "40.00ms")
+    assert.NotContains(t, out, "Latency Distribution")
+}
+
+func Test_stat_writePhases(t *testing.T) {
+    t.Parallel()
+
+    tt := newStat()
+    tt.writePhases()
+    assert.Empty(t, tt.buf.String())
+
+    tt.phases = newPhases()
+    tt.writePhases()
+    assert.Empty(t, tt.buf.String())
+
+    observe(tt.phases.connect, time.Millisecond*2)
+    observe(tt.phases.ttfb, time.Millisecond*4)
+    tt.writePhases()
+    out := tt.buf.String()
+    assert.Contains(t, out, "Phases")
+    assert.Contains(t, out, "Connect")
+    assert.Contains(t, out, "TTFB")
+    assert.Contains(t, out, "4.00ms")
+    assert.NotContains(t, out, "DNS")
+    assert.NotContains(t, out, "TLS")
+}
+
 func Test_stat_writeErrors(t *testing.T) {
     t.Parallel()
 
@@ -99,6 +222,46 @@ func Test_stat_writeHint(t *testing.T) {
     })
 }
 
+func Test_stat_writeProgress(t *testing.T) {
+    t.Parallel()
+
+    var buf bytes.Buffer
+    var throughput int64 = 2002
+    tt := newStat()
+    tt.ew = &buf
+    tt.count = 10
+    tt.reqs = 4
+    tt.throughput = &throughput
+    tt.elapsed = int64(time.Second * 2)
+    tt.errs["custom-error"] = 2
+    tt.appendLatency(time.Millisecond * 3)
+    tt.writeProgress()
+    assert.Equal(t, "[2.00s] requests: 4/10, errors: 2, rps: 2.00, p99: 3.00ms, throughput: 1.00 KB/s\n", buf.String())
+}
+
+func Test_stat_writePlain(t *testing.T) {
+    t.Parallel()
+
+    var buf bytes.Buffer
+    var throughput int64
+    tt := newStat()
+    tt.w = &buf
+    tt.throughput = &throughput
+    tt.url = "http://example.com"
+    tt.done = true
+    tt.errs["custom-error"] = 1
+    tt.appendCode(200)
+    assert.Nil(t, tt.writePlain())
+
+    out := buf.String()
+    assert.Contains(t, out, "Benchmarking http://example.com")
+    assert.Contains(t, out, "Latency Distribution:")
+    assert.Contains(t, out, "custom-error: 1")
+    assert.Contains(t, out, "Done!")
+    assert.NotContains(t, out, "\x1b[")
+    assert.NotContains(t, out, "press q")
+}
+
 func Test_stat_writeInt(t *testing.T) {
     t.Parallel()
 
@@ -114,15 +277,30 @@ func Test_rpsResult(t *testing.T) {
     assert.Equal(t, 6.0, avg)
     assert.Equal(t, 2.8284271247461903, stdev)
     assert.Equal(t, 9.0, max)
+
+    avg, stdev, max = rpsResult([]float64{3})
+    assert.Equal(t, 3.0, avg)
+    assert.Zero(t, stdev)
+    assert.Equal(t, 3.0, max)
 }
 
 func Test_latencyResult(t *testing.T) {
     t.Parallel()
 
-    avg, stdev, max := latencyResult([]int64{1e6, 6e6, 5e6, 7e6, 9e6, 8e6})
+    h := newHistogram()
+    for _, v := range []int64{1e6, 6e6, 5e6, 7e6, 9e6, 8e6} {
+        h.record(v)
+    }
+
+    avg, stdev, max := latencyResult(h)
     assert.Equal(t, 6.0e3, avg)
-    assert.Equal(t, 2828.42712474619, stdev)
+    assert.InDelta(t, 2828.42712474619, stdev, 1e-6)
     assert.Equal(t, 9.0e3, max)
+
+    avg, stdev, max = latencyResult(newHistogram())
+    assert.Zero(t, avg)
+    assert.Zero(t, stdev)
+    assert.Zero(t, max)
 }
 
 func Test_formatThroughput(t *testing.T) {
@@ -0,0 +1,352 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasthttp"
+)
+
+// renderContext 为一次请求渲染时的上下文，seq 为请求序号，row 为数据文件中取出的行
+type renderContext struct {
+	seq uint64
+	row []string
+}
+
+// templateFunc 将变量的值追加到 dst
+type templateFunc func(dst []byte, ctx renderContext) []byte
+
+type templatePart struct {
+	lit  string
+	fn   templateFunc
+	data bool
+}
+
+// template 是编译后的模板，由字面量与变量交替组成，
+// 渲染时只向目标缓冲区追加内容，不产生额外的内存分配
+type template struct {
+	parts []templatePart
+}
+
+// compileTemplate 编译包含 {{变量}} 的字符串，不包含可识别的变量时返回 nil，
+// data.列名 变量引用 f 中的列。未知的变量（例如 Mustache 模板中的 {{name}}）与未闭合的 {{
+// 按原样保留，只有可识别的变量参数错误时返回错误
+func compileTemplate(s string, f *feeder) (*template, error) {
+	if !strings.Contains(s, "{{") {
+		return nil, nil
+	}
+
+	var (
+		t   = &template{}
+		lit strings.Builder
+	)
+	for {
+		i := strings.Index(s, "{{")
+		if i == -1 {
+			break
+		}
+		j := strings.Index(s[i+2:], "}}")
+		if j == -1 {
+			break
+		}
+		end := i + 2 + j + 2
+
+		expr := strings.TrimSpace(s[i+2 : i+2+j])
+		fn, err := compileTemplateFunc(expr, f)
+		if err != nil {
+			return nil, err
+		}
+		if fn == nil {
+			lit.WriteString(s[:end])
+		} else {
+			lit.WriteString(s[:i])
+			t.parts = append(t.parts, templatePart{lit: lit.String(), fn: fn, data: strings.HasPrefix(expr, dataPrefix)})
+			lit.Reset()
+		}
+		s = s[end:]
+	}
+
+	if len(t.parts) == 0 {
+		return nil, nil
+	}
+	if lit.WriteString(s); lit.Len() > 0 {
+		t.parts = append(t.parts, templatePart{lit: lit.String()})
+	}
+
+	return t, nil
+}
+
+// templateNames 为支持的模板变量，data.列名 之外的其他变量不作为模板处理
+var templateNames = map[string]bool{
+	"uuid":          true,
+	"seq":           true,
+	"randInt":       true,
+	"randString":    true,
+	"now":           true,
+	"now.unix":      true,
+	"now.unixMilli": true,
+	"now.unixNano":  true,
+}
+
+// compileTemplateFunc 编译一个变量，expr 不是支持的变量时返回 nil
+func compileTemplateFunc(expr string, f *feeder) (templateFunc, error) {
+	fields := strings.Fields(expr)
+	if len(fields) == 0 || (!templateNames[fields[0]] && !strings.HasPrefix(fields[0], dataPrefix)) {
+		return nil, nil
+	}
+
+	if col, ok := strings.CutPrefix(fields[0], dataPrefix); ok && len(fields) == 1 {
+		if f == nil {
+			return nil, fmt.Errorf("template variable {{%s}} needs a data file", expr)
+		}
+		i, ok := f.columns[col]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q in data file", col)
+		}
+		return func(dst []byte, ctx renderContext) []byte {
+			return append(dst, ctx.row[i]...)
+		}, nil
+	}
+
+	args, err := templateArgs(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name := fields[0]; {
+	case name == "uuid" && len(args) == 0:
+		return appendUUID, nil
+	case name == "seq" && len(args) == 0:
+		return func(dst []byte, ctx renderContext) []byte {
+			return strconv.AppendUint(dst, ctx.seq, 10)
+		}, nil
+	case name == "randInt" && len(args) == 2 && args[0] <= args[1]:
+		// 区间宽度按无符号数计算，宽度加 1 超出 int64 时 rand.Int64N 会 panic
+		width := uint64(args[1]) - uint64(args[0])
+		if width >= math.MaxInt64 {
+			return nil, fmt.Errorf("range of template variable {{%s}} is too wide", expr)
+		}
+		min, n := args[0], int64(width)+1
+		return func(dst []byte, _ renderContext) []byte {
+			return strconv.AppendInt(dst, min+rand.Int64N(n), 10)
+		}, nil
+	case name == "randString" && len(args) == 1 && args[0] > 0:
+		n := int(args[0])
+		return func(dst []byte, _ renderContext) []byte {
+			for i := 0; i < n; i++ {
+				dst = append(dst, randStringChars[rand.IntN(len(randStringChars))])
+			}
+			return dst
+		}, nil
+	case name == "now" && len(args) == 0:
+		return func(dst []byte, _ renderContext) []byte {
+			return time.Now().AppendFormat(dst, time.RFC3339)
+		}, nil
+	case name == "now.unix" && len(args) == 0:
+		return func(dst []byte, _ renderContext) []byte {
+			return strconv.AppendInt(dst, time.Now().Unix(), 10)
+		}, nil
+	case name == "now.unixMilli" && len(args) == 0:
+		return func(dst []byte, _ renderContext) []byte {
+			return strconv.AppendInt(dst, time.Now().UnixMilli(), 10)
+		}, nil
+	case name == "now.unixNano" && len(args) == 0:
+		return func(dst []byte, _ renderContext) []byte {
+			return strconv.AppendInt(dst, time.Now().UnixNano(), 10)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported template variable {{%s}}", expr)
+}
+
+func templateArgs(fields []string) ([]int64, error) {
+	args := make([]int64, 0, len(fields)-1)
+	for _, f := range fields[1:] {
+		v, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q of template variable %s", f, fields[0])
+		}
+		args = append(args, v)
+	}
+	return args, nil
+}
+
+const (
+	randStringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	hexChars        = "0123456789abcdef"
+)
+
+// appendUUID 追加一个随机的 UUID v4
+func appendUUID(dst []byte, _ renderContext) []byte {
+	var b [16]byte
+	hi, lo := rand.Uint64(), rand.Uint64()
+	for i := 0; i < 8; i++ {
+		b[i], b[8+i] = byte(hi>>(8*i)), byte(lo>>(8*i))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	for i, v := range b {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			dst = append(dst, '-')
+		}
+		dst = append(dst, hexChars[v>>4], hexChars[v&0x0f])
+	}
+	return dst
+}
+
+func (t *template) appendTo(dst []byte, ctx renderContext) []byte {
+	for _, p := range t.parts {
+		dst = append(dst, p.lit...)
+		if p.fn != nil {
+			dst = p.fn(dst, ctx)
+		}
+	}
+	return dst
+}
+
+// usesData 判断模板是否引用了数据文件中的列
+func (t *template) usesData() bool {
+	if t == nil {
+		return false
+	}
+	for _, p := range t.parts {
+		if p.data {
+			return true
+		}
+	}
+	return false
+}
+
+type headerTemplate struct {
+	key   string
+	value *template
+}
+
+// errDataUnused 表示指定了数据文件但没有模板引用数据列，数据文件中的行不会被使用
+var errDataUnused = errors.New("--data is given but no template references a data column like {{data.name}}")
+
+// requestTemplate 保存请求中包含模板变量的部分，每次请求前重新渲染，
+// 同一请求中的 {{seq}} 取值相同，引用的数据来自同一行
+type requestTemplate struct {
+	path    *template
+	query   *template
+	headers []headerTemplate
+	body    *template
+	seq     uint64
+	feeder  *feeder
+}
+
+// requestTemplate 编译 URL 路径与查询参数、请求头的值以及请求体中的模板变量，
+// 均不包含变量时返回 nil，使用流式请求体时不渲染请求体
+func (c *Config) requestTemplate() (rt *requestTemplate, err error) {
+	rt = &requestTemplate{}
+
+	path, query := splitPathQuery(c.Url)
+	if rt.path, err = compileTemplate(path, c.feeder); err != nil {
+		return
+	}
+	if rt.query, err = compileTemplate(query, c.feeder); err != nil {
+		return
+	}
+
+	kvs, err := headers(c.Headers).kvs()
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(kvs); i += 2 {
+		var value *template
+		if value, err = compileTemplate(kvs[i+1], c.feeder); err != nil {
+			return
+		}
+		if value != nil {
+			rt.headers = append(rt.headers, headerTemplate{key: kvs[i], value: value})
+		}
+	}
+
+	// 流式请求体通常是大文件，压缩的请求体为二进制数据，都不做渲染
+	if !c.Stream && c.CompressBody == "" {
+		if rt.body, err = compileTemplate(string(c.body), c.feeder); err != nil {
+			return
+		}
+	}
+
+	if rt.path == nil && rt.query == nil && len(rt.headers) == 0 && rt.body == nil {
+		return nil, nil
+	}
+
+	// 只有引用了数据列时才从数据文件中取行
+	usesData := rt.path.usesData() || rt.query.usesData() || rt.body.usesData()
+	for _, h := range rt.headers {
+		usesData = usesData || h.value.usesData()
+	}
+	if usesData {
+		rt.feeder = c.feeder
+	}
+
+	return
+}
+
+// usesData 判断请求模板是否从数据文件中取行
+func (rt *requestTemplate) usesData() bool {
+	return rt != nil && rt.feeder != nil
+}
+
+// splitPathQuery 从 URL 中取出原始的路径与查询参数
+func splitPathQuery(u string) (path, query string) {
+	if i := strings.Index(u, "://"); i != -1 {
+		u = u[i+3:]
+		if i = strings.IndexAny(u, "/?"); i == -1 {
+			return "", ""
+		}
+		u = u[i:]
+	}
+	if i := strings.IndexByte(u, '#'); i != -1 {
+		u = u[:i]
+	}
+	if i := strings.IndexByte(u, '?'); i != -1 {
+		return u[:i], u[i+1:]
+	}
+	return u, ""
+}
+
+// render 将模板渲染到 req 中，渲染使用池化的缓冲区，
+// 数据文件按顺序读取完毕时返回 errDataExhausted
+func (rt *requestTemplate) render(req *fasthttp.Request) error {
+	ctx := renderContext{seq: atomic.AddUint64(&rt.seq, 1)}
+	if rt.feeder != nil {
+		var ok bool
+		if ctx.row, ok = rt.feeder.next(); !ok {
+			return errDataExhausted
+		}
+	}
+
+	b := bytebufferpool.Get()
+	defer bytebufferpool.Put(b)
+
+	if rt.path != nil {
+		b.B = rt.path.appendTo(b.B[:0], ctx)
+		req.URI().SetPathBytes(b.B)
+	}
+	if rt.query != nil {
+		b.B = rt.query.appendTo(b.B[:0], ctx)
+		req.URI().SetQueryStringBytes(b.B)
+	}
+	for _, h := range rt.headers {
+		b.B = h.value.appendTo(b.B[:0], ctx)
+		req.Header.SetBytesV(h.key, b.B)
+	}
+	if rt.body != nil {
+		b.B = rt.body.appendTo(b.B[:0], ctx)
+		req.SetBody(b.B)
+	}
+
+	return nil
+}
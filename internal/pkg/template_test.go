@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func Test_compileTemplate(t *testing.T) {
+	t.Parallel()
+
+	tt, err := compileTemplate("no variables", nil)
+	assert.Nil(t, err)
+	assert.Nil(t, tt)
+
+	tt, err = compileTemplate("/users/{{seq}}?id={{ randInt 5 5 }}&s={{randString 8}}", nil)
+	assert.Nil(t, err)
+	out := string(tt.appendTo(nil, renderContext{seq: 42}))
+	assert.Regexp(t, `^/users/42\?id=5&s=[a-zA-Z0-9]{8}$`, out)
+
+	// 未知的变量与未闭合的 {{ 按原样保留
+	for _, s := range []string{"{{seq", "{{}}", "{{foo}}", "{{#each items}}{{name}}{{/each}}", "{{ data}}"} {
+		tt, err = compileTemplate(s, nil)
+		assert.Nil(t, err, s)
+		assert.Nil(t, tt, s)
+	}
+
+	tt, err = compileTemplate("{{foo}}/{{seq}}/{{ bar }}/{{seq", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "{{foo}}/7/{{ bar }}/{{seq", string(tt.appendTo(nil, renderContext{seq: 7})))
+
+	for _, s := range []string{
+		"{{seq 1}}", "{{uuid 1}}", "{{randInt 1}}", "{{randInt 2 1}}",
+		"{{randInt a 1}}", "{{randString 0}}", "{{randString}}", "{{now 1}}",
+		"{{randInt 0 9223372036854775807}}", "{{randInt -1 9223372036854775807}}", "{{randInt -9223372036854775808 0}}",
+	} {
+		_, err = compileTemplate(s, nil)
+		assert.NotNil(t, err, s)
+	}
+
+	// 区间内的整数个数恰好为 math.MaxInt64 时仍然可用
+	for _, s := range []string{"{{randInt 1 9223372036854775807}}", "{{randInt -9223372036854775808 -2}}"} {
+		tt, err = compileTemplate(s, nil)
+		assert.Nil(t, err, s)
+		_, err = strconv.ParseInt(string(tt.appendTo(nil, renderContext{})), 10, 64)
+		assert.Nil(t, err, s)
+	}
+}
+
+func Test_template_vars(t *testing.T) {
+	t.Parallel()
+
+	render := func(s string) string {
+		tt, err := compileTemplate(s, nil)
+		assert.Nil(t, err)
+		return string(tt.appendTo(nil, renderContext{seq: 1}))
+	}
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	a, b := render("{{uuid}}"), render("{{uuid}}")
+	assert.Regexp(t, uuid, a)
+	assert.NotEqual(t, a, b)
+
+	for i := 0; i < 100; i++ {
+		n, err := strconv.Atoi(render("{{randInt -3 3}}"))
+		assert.Nil(t, err)
+		assert.True(t, n >= -3 && n <= 3, n)
+	}
+
+	now := time.Now()
+	unix, _ := strconv.ParseInt(render("{{now.unix}}"), 10, 64)
+	assert.InDelta(t, now.Unix(), unix, 1)
+	milli, _ := strconv.ParseInt(render("{{now.unixMilli}}"), 10, 64)
+	assert.InDelta(t, now.UnixMilli(), milli, 1000)
+	nano, _ := strconv.ParseInt(render("{{now.unixNano}}"), 10, 64)
+	assert.InDelta(t, now.UnixNano(), nano, float64(time.Second))
+	_, err := time.Parse(time.RFC3339, render("{{now}}"))
+	assert.Nil(t, err)
+}
+
+func Test_compileTemplate_data(t *testing.T) {
+	t.Parallel()
+
+	f := &feeder{columns: map[string]int{"id": 0, "name": 1}}
+	tt, err := compileTemplate("/users/{{data.id}}?name={{ data.name }}&seq={{seq}}", f)
+	assert.Nil(t, err)
+	assert.True(t, tt.usesData())
+	assert.Equal(t, "/users/7?name=bob&seq=3", string(tt.appendTo(nil, renderContext{seq: 3, row: []string{"7", "bob"}})))
+
+	tt, err = compileTemplate("{{seq}}", f)
+	assert.Nil(t, err)
+	assert.False(t, tt.usesData())
+
+	_, err = compileTemplate("{{data.id}}", nil)
+	assert.NotNil(t, err)
+	_, err = compileTemplate("{{data.missing}}", f)
+	assert.NotNil(t, err)
+}
+
+func Test_splitPathQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{ url, path, query string }{
+		{"http://example.com", "", ""},
+		{"http://example.com/a/{{seq}}", "/a/{{seq}}", ""},
+		{"http://example.com?a={{seq}}#f", "", "a={{seq}}"},
+		{"https://example.com:8443/a?b=1&c=2", "/a", "b=1&c=2"},
+		{"/a?b", "/a", "b"},
+	}
+	for _, tt := range tests {
+		path, query := splitPathQuery(tt.url)
+		assert.Equal(t, tt.path, path, tt.url)
+		assert.Equal(t, tt.query, query, tt.url)
+	}
+}
+
+func Test_Config_requestTemplate(t *testing.T) {
+	t.Parallel()
+
+	rt, err := (&Config{Url: "http://example.com/a", Headers: []string{"a: b"}, body: []byte("{}")}).requestTemplate()
+	assert.Nil(t, err)
+	assert.Nil(t, rt)
+
+	rt, err = (&Config{Url: "http://example.com/a", Stream: true, body: []byte("{{seq}}")}).requestTemplate()
+	assert.Nil(t, err)
+	assert.Nil(t, rt)
+
+	rt, err = (&Config{Url: "http://example.com/{{id}}", Headers: []string{"a: {{b}}"}, body: []byte("{{foo}}")}).requestTemplate()
+	assert.Nil(t, err)
+	assert.Nil(t, rt)
+
+	for _, c := range []*Config{
+		{Url: "http://example.com/{{seq 1}}"},
+		{Url: "http://example.com/?a={{randInt 2 1}}"},
+		{Url: "http://example.com", Headers: []string{"a"}},
+		{Url: "http://example.com", Headers: []string{"a: {{uuid 1}}"}},
+		{Url: "http://example.com", body: []byte("{{randString 0}}")},
+	} {
+		_, err = c.requestTemplate()
+		assert.NotNil(t, err, c)
+	}
+}
+
+func Test_requestTemplate_render(t *testing.T) {
+	c := &Config{
+		Url:     "http://example.com/users/{{seq}}?token={{seq}}",
+		Headers: []string{"X-Request-Id: req-{{seq}}", "X-Static: 1"},
+		Body:    `{"id":{{seq}}}`,
+	}
+	req := fasthttp.AcquireRequest()
+	assert.Nil(t, c.setReqBasic(req))
+	assert.Nil(t, c.setReqBody(req))
+	assert.Nil(t, c.setReqHeader(req))
+
+	rt, err := c.requestTemplate()
+	assert.Nil(t, err)
+	assert.Len(t, rt.headers, 1)
+
+	for i := 1; i <= 2; i++ {
+		assert.Nil(t, rt.render(req))
+		n := strconv.Itoa(i)
+		assert.Equal(t, "http://example.com/users/"+n+"?token="+n, req.URI().String())
+		assert.Equal(t, "req-"+n, string(req.Header.Peek("X-Request-Id")))
+		assert.Equal(t, "1", string(req.Header.Peek("X-Static")))
+		assert.Equal(t, `{"id":`+n+`}`, string(req.Body()))
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = rt.render(req)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+func Test_requestTemplate_render_data(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		Url:    "http://example.com/users/{{data.id}}",
+		feeder: &feeder{columns: map[string]int{"id": 0}, rows: [][]string{{"1"}, {"2"}}, mode: dataSequential},
+	}
+	req := fasthttp.AcquireRequest()
+	assert.Nil(t, c.setReqBasic(req))
+
+	rt, err := c.requestTemplate()
+	assert.Nil(t, err)
+	assert.NotNil(t, rt.feeder)
+
+	for _, id := range []string{"1", "2"} {
+		assert.Nil(t, rt.render(req))
+		assert.Equal(t, "/users/"+id, string(req.URI().Path()))
+	}
+	assert.ErrorIs(t, rt.render(req), errDataExhausted)
+
+	// 未引用数据列时不消耗数据行
+	c.Url = "http://example.com/users/{{seq}}"
+	rt, err = c.requestTemplate()
+	assert.Nil(t, err)
+	assert.Nil(t, rt.feeder)
+}
@@ -0,0 +1,375 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/valyala/fasthttp"
+)
+
+// defaultWsMessage 为未指定消息内容与请求体时发送的消息
+const defaultWsMessage = "ping"
+
+// errWsReplyTimeout 表示消息在超时时间内没有收到回复，连接随后被关闭并重新建立
+var errWsReplyTimeout = errors.New("websocket reply timeout")
+
+// wsStats 统计 WebSocket 的连接与消息，消息的往返时间记录在延迟中
+type wsStats struct {
+	conns     int64
+	sent      int64
+	received  int64
+	unmatched int64
+	connect   *histogram
+
+	mut        sync.Mutex
+	closeCodes map[int]int64
+}
+
+func newWsStats() *wsStats {
+	return &wsStats{connect: newHistogram(), closeCodes: make(map[int]int64)}
+}
+
+// closed 记录一次连接关闭，code 为收到的关闭帧中的状态码，未收到关闭帧时为 1006
+func (s *wsStats) closed(code int) {
+	s.mut.Lock()
+	s.closeCodes[code]++
+	s.mut.Unlock()
+}
+
+// codes 返回按状态码排序的关闭次数
+func (s *wsStats) codes() (codes []int, counts []int64) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for code := range s.closeCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		counts = append(counts, s.closeCodes[code])
+	}
+	return
+}
+
+// wsBench 以 Connections 个连接压测 WebSocket 服务，每个连接按固定速率发送消息，
+// 回复按发送顺序与消息匹配，速率为 0 时收到回复后立即发送下一条消息
+type wsBench struct {
+	p        *HttpGo
+	url      string
+	header   http.Header
+	message  []byte
+	interval time.Duration
+	timeout  time.Duration
+	dialer   *websocket.Dialer
+	stats    *wsStats
+}
+
+// initWebSocket 初始化 WebSocket 模式，连接使用与 HTTP 相同的拨号器（代理、TLS 与吞吐量统计）
+func (p *HttpGo) initWebSocket() (err error) {
+	switch {
+	case p.c.Debug:
+		return errors.New("ws mode cannot be used with --debug")
+	case p.c.Qps > 0 || p.c.Rate > 0 || p.c.Stages != "":
+		return errors.New("ws mode cannot be used with --qps, --rate or --stages, use --msg-rate instead")
+	case p.c.HTTP2 || p.c.HTTP3 || p.c.Pipeline:
+		return errors.New("ws mode cannot be used with --http2, --http3 or --pipeline")
+	}
+
+	if p.ws, err = newWsBench(p); err != nil {
+		return
+	}
+	p.stat.url = p.ws.url
+
+	return
+}
+
+func newWsBench(p *HttpGo) (b *wsBench, err error) {
+	c := p.c
+	b = &wsBench{
+		p:       p,
+		header:  http.Header{},
+		message: []byte(c.WsMessage),
+		timeout: c.Timeout,
+		stats:   c.ws,
+	}
+	if len(b.message) == 0 {
+		b.message = []byte(c.Body)
+	}
+	if len(b.message) == 0 {
+		b.message = []byte(defaultWsMessage)
+	}
+	if c.WsRate > 0 {
+		b.interval = time.Duration(float64(time.Second) / c.WsRate)
+	}
+	if b.stats == nil {
+		b.stats = newWsStats()
+	}
+
+	var u *url.URL
+	if u, err = url.Parse(c.Url); err != nil {
+		return
+	}
+	switch u.Scheme {
+	case "ws", "http":
+		u.Scheme = "ws"
+	case "wss", "https":
+		u.Scheme = "wss"
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q. ws and wss are supported", u.Scheme)
+	}
+	b.url = u.String()
+
+	kvs, err := headers(c.Headers).kvs()
+	if err != nil {
+		return
+	}
+	for i := 0; i < len(kvs); i += 2 {
+		b.header.Add(kvs[i], kvs[i+1])
+	}
+	if c.Host != "" {
+		b.header.Set(fasthttp.HeaderHost, c.Host)
+	}
+	if b.header.Get(fasthttp.HeaderUserAgent) == "" {
+		b.header.Set(fasthttp.HeaderUserAgent, "httpgo/"+Version)
+	}
+
+	if c.tlsConf, err = c.getTlsConfig(); err != nil {
+		return
+	}
+	// 消息的收发不是请求与响应，不记录 TTFB 与 Transfer
+	dial := c.getConnDialer()
+	b.dialer = &websocket.Dialer{
+		NetDialContext:    contextDialer(dial),
+		NetDialTLSContext: contextDialer(tlsDialer(dial, c.tlsConf, c.Timeout, c.phases)),
+		HandshakeTimeout:  c.Timeout,
+	}
+
+	return
+}
+
+func contextDialer(dial fasthttp.DialFunc) func(context.Context, string, string) (net.Conn, error) {
+	return func(_ context.Context, _, addr string) (net.Conn, error) {
+		return dial(addr)
+	}
+}
+
+// run 每个 worker 维持一个连接，连接断开后重新建立，直到达到消息数或持续时间
+func (b *wsBench) run() {
+	// 连接被服务端关闭时没有消息往返，也没有错误，按时长结束压测
+	if b.p.c.Count <= 0 {
+		timer := time.AfterFunc(b.p.c.Duration, b.p.stop)
+		defer timer.Stop()
+	}
+
+	n := b.p.c.Connections
+	b.p.wg.Add(n)
+	for i := 0; i < n; i++ {
+		go b.worker()
+	}
+	b.p.wg.Wait()
+	b.p.stop()
+}
+
+func (b *wsBench) worker() {
+	defer b.p.wg.Done()
+
+	for {
+		select {
+		case <-b.p.doneChan:
+			return
+		default:
+		}
+
+		conn, err := b.connect()
+		if err != nil {
+			b.p.statistic(0, 0, err)
+			continue
+		}
+		newWsSession(b, conn).run()
+	}
+}
+
+// connect 建立连接并记录从拨号到收到 101 响应的耗时
+func (b *wsBench) connect() (*websocket.Conn, error) {
+	start := time.Now()
+	conn, resp, err := b.dialer.Dial(b.url, b.header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			return nil, fmt.Errorf("websocket handshake failed with status %d", resp.StatusCode)
+		}
+		return nil, err
+	}
+	observe(b.stats.connect, time.Since(start))
+	atomic.AddInt64(&b.stats.conns, 1)
+
+	return conn, nil
+}
+
+// wsSession 为一个连接上的消息收发，pending 为等待回复的消息的发送时间，
+// 读超时设置为最早的未回复消息的发送时间加上超时时间
+type wsSession struct {
+	b     *wsBench
+	conn  *websocket.Conn
+	ready chan struct{}
+	done  chan struct{}
+
+	mut     sync.Mutex
+	pending []time.Time
+	closing bool
+}
+
+func newWsSession(b *wsBench, conn *websocket.Conn) *wsSession {
+	return &wsSession{
+		b:     b,
+		conn:  conn,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// run 发送消息直到压测结束或连接断开，压测结束时发送关闭帧并等待服务端的关闭帧。
+// 已发送的消息设置了读超时，读取方在超时时间内结束并记录连接的关闭状态
+func (s *wsSession) run() {
+	go s.read()
+
+	if s.write() {
+		s.close()
+	}
+	<-s.done
+	_ = s.conn.Close()
+}
+
+// write 按速率发送消息，压测结束时返回 true，连接断开时返回 false
+func (s *wsSession) write() bool {
+	var tick <-chan time.Time
+	if s.b.interval > 0 {
+		ticker := time.NewTicker(s.b.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for first := true; ; first = false {
+		if !first {
+			wait := s.ready
+			if tick != nil {
+				wait = nil
+			}
+			select {
+			case <-s.b.p.doneChan:
+				return true
+			case <-s.done:
+				return false
+			case <-tick:
+			case <-wait:
+			}
+		}
+
+		// 发送失败说明连接已断开，由读取方记录关闭状态或错误
+		if err := s.send(); err != nil {
+			return false
+		}
+	}
+}
+
+func (s *wsSession) send() error {
+	now := time.Now()
+	s.mut.Lock()
+	s.pending = append(s.pending, now)
+	if len(s.pending) == 1 {
+		_ = s.conn.SetReadDeadline(now.Add(s.b.timeout))
+	}
+	s.mut.Unlock()
+
+	_ = s.conn.SetWriteDeadline(now.Add(s.b.timeout))
+	if err := s.conn.WriteMessage(websocket.TextMessage, s.b.message); err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.b.stats.sent, 1)
+
+	return nil
+}
+
+// read 读取回复并与最早的未回复消息匹配，没有未回复的消息时计为未匹配
+func (s *wsSession) read() {
+	defer close(s.done)
+
+	for {
+		_, r, err := s.conn.NextReader()
+		if err == nil {
+			_, err = io.Copy(io.Discard, r)
+		}
+		if err != nil {
+			s.failed(err)
+			return
+		}
+		atomic.AddInt64(&s.b.stats.received, 1)
+
+		s.mut.Lock()
+		if len(s.pending) == 0 {
+			s.mut.Unlock()
+			atomic.AddInt64(&s.b.stats.unmatched, 1)
+			continue
+		}
+		sent := s.pending[0]
+		s.pending = s.pending[1:]
+		if !s.closing {
+			deadline := time.Time{}
+			if len(s.pending) > 0 {
+				deadline = s.pending[0].Add(s.b.timeout)
+			}
+			_ = s.conn.SetReadDeadline(deadline)
+		}
+		s.mut.Unlock()
+
+		s.b.p.record(time.Time{}, nil, fasthttp.StatusSwitchingProtocols, time.Since(sent), nil)
+		select {
+		case s.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// failed 记录连接关闭的状态码，回复超时与连接异常断开计为错误
+func (s *wsSession) failed(err error) {
+	s.mut.Lock()
+	closing := s.closing
+	s.mut.Unlock()
+
+	var (
+		ce *websocket.CloseError
+		ne net.Error
+	)
+	switch {
+	case errors.As(err, &ce):
+		s.b.stats.closed(ce.Code)
+	case closing:
+		s.b.stats.closed(websocket.CloseAbnormalClosure)
+	case errors.As(err, &ne) && ne.Timeout():
+		s.b.p.statistic(0, 0, errWsReplyTimeout)
+	default:
+		s.b.stats.closed(websocket.CloseAbnormalClosure)
+		s.b.p.statistic(0, 0, err)
+	}
+}
+
+// close 发送正常关闭的关闭帧，服务端应在超时时间内回应关闭帧
+func (s *wsSession) close() {
+	deadline := time.Now().Add(s.b.timeout)
+
+	s.mut.Lock()
+	s.closing = true
+	_ = s.conn.SetReadDeadline(deadline)
+	s.mut.Unlock()
+
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = s.conn.WriteControl(websocket.CloseMessage, msg, deadline)
+}
@@ -0,0 +1,197 @@
+package pkg
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsEcho 原样回复收到的消息，记录握手请求的 Host 与请求头，
+// 收到 close 消息时以 4001 关闭连接，收到 silent 消息时不回复
+type wsEcho struct {
+	mut      sync.Mutex
+	hosts    map[string]bool
+	tokens   map[string]bool
+	messages []string
+}
+
+func newWsEcho() *wsEcho {
+	return &wsEcho{hosts: map[string]bool{}, tokens: map[string]bool{}}
+}
+
+func (e *wsEcho) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/missing" {
+		http.NotFound(w, r)
+		return
+	}
+
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	e.mut.Lock()
+	e.hosts[r.Host] = true
+	e.tokens[r.Header.Get("X-Token")] = true
+	e.mut.Unlock()
+
+	for {
+		mt, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		e.mut.Lock()
+		e.messages = append(e.messages, string(msg))
+		e.mut.Unlock()
+
+		switch string(msg) {
+		case "close":
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4001, "bye"))
+			return
+		case "silent":
+			continue
+		}
+		if err = conn.WriteMessage(mt, msg); err != nil {
+			return
+		}
+	}
+}
+
+func Test_HttpGo_Run_WebSocket(t *testing.T) {
+	t.Parallel()
+
+	t.Run("echo", func(t *testing.T) {
+		e := newWsEcho()
+		ts := httptest.NewServer(e)
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{
+			Url:         ts.URL + "/echo",
+			WebSocket:   true,
+			WsMessage:   `{"op":"ping"}`,
+			WsRate:      100,
+			Headers:     []string{"X-Token: abc"},
+			Host:        "chat.example.com",
+			Connections: 4,
+			Count:       40,
+			Output:      outputJSON,
+		})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Equal(t, int64(40), r.Totals.Requests)
+		assert.Equal(t, int64(40), r.Codes.Code1xx)
+		assert.Equal(t, int64(4), r.WebSocket.Connections)
+		assert.Equal(t, int64(4), r.WebSocket.Connect.Count)
+		assert.GreaterOrEqual(t, r.WebSocket.Sent, int64(40))
+		assert.GreaterOrEqual(t, r.WebSocket.Received, int64(40))
+		assert.Equal(t, int64(0), r.WebSocket.Unmatched)
+		assert.Equal(t, map[string]int64{"1000": 4}, r.WebSocket.CloseCodes)
+		assert.Greater(t, r.Throughput.Bytes, int64(0))
+		assert.True(t, strings.HasPrefix(p.stat.url, "ws://"))
+
+		output := p.stat.output()
+		assert.Contains(t, output, "WebSocket:")
+		assert.Contains(t, output, "1000 - ")
+
+		e.mut.Lock()
+		defer e.mut.Unlock()
+		assert.Equal(t, []string{"chat.example.com"}, keys(e.hosts))
+		assert.Equal(t, []string{"abc"}, keys(e.tokens))
+		for _, msg := range e.messages {
+			assert.Equal(t, `{"op":"ping"}`, msg)
+		}
+	})
+
+	t.Run("closed loop over tls", func(t *testing.T) {
+		ts := httptest.NewTLSServer(newWsEcho())
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL, WebSocket: true, Insecure: true, Connections: 2, Count: 200, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.GreaterOrEqual(t, r.Totals.Requests, int64(200))
+		assert.Equal(t, int64(2), r.WebSocket.Connections)
+		assert.Contains(t, r.Phases, "tls")
+	})
+
+	// 服务端关闭的连接重新建立，全部消息没有回复，按时长结束
+	t.Run("server close", func(t *testing.T) {
+		ts := httptest.NewServer(newWsEcho())
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL, WebSocket: true, WsMessage: "close", WsRate: 100, Connections: 1, Duration: 200 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Equal(t, int64(0), r.Totals.Errors)
+		assert.Greater(t, r.WebSocket.Connections, int64(1))
+		assert.Greater(t, r.WebSocket.CloseCodes["4001"], int64(1))
+	})
+
+	t.Run("reply timeout", func(t *testing.T) {
+		ts := httptest.NewServer(newWsEcho())
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL, WebSocket: true, WsMessage: "silent", Timeout: 50 * time.Millisecond, Connections: 1, Duration: 300 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.Totals.Requests)
+		assert.Greater(t, r.Errors[errWsReplyTimeout.Error()], 0)
+		assert.Greater(t, r.WebSocket.Connections, int64(1))
+	})
+
+	t.Run("bad handshake", func(t *testing.T) {
+		ts := httptest.NewServer(newWsEcho())
+		t.Cleanup(ts.Close)
+
+		var buf bytes.Buffer
+		p := New(Config{Url: ts.URL + "/missing", WebSocket: true, Connections: 1, Duration: 100 * time.Millisecond, Output: outputJSON})
+		p.stat.w = &buf
+		assert.Nil(t, p.Run())
+
+		r := p.report()
+		assert.Equal(t, int64(0), r.WebSocket.Connections)
+		assert.Greater(t, r.Errors["websocket handshake failed with status 404"], 0)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert.NotNil(t, New(Config{Url: "ftp://127.0.0.1:1", WebSocket: true}).Run())
+		assert.NotNil(t, New(Config{Url: "ws://127.0.0.1:1", WebSocket: true, Rate: 10}).Run())
+		assert.NotNil(t, New(Config{Url: "ws://127.0.0.1:1", WebSocket: true, HTTP2: true}).Run())
+	})
+}
+
+func Test_wsStats_codes(t *testing.T) {
+	t.Parallel()
+
+	s := newWsStats()
+	s.closed(1006)
+	s.closed(1000)
+	s.closed(1006)
+	codes, counts := s.codes()
+	assert.Equal(t, []int{1000, 1006}, codes)
+	assert.Equal(t, []int64{1, 2}, counts)
+}
@@ -7,42 +7,74 @@ import (
 	"time"
 
 	"github.com/itnxs/httpgo/internal/pkg"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 )
 
+// 进程退出码
+const (
+	exitFailure        = 1
+	exitAssertionError = 2
+)
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitFailure)
 	}
+	os.Exit(exitCode)
 }
 
-var config pkg.Config
+var (
+	config   pkg.Config
+	exitCode int
+)
 
 func init() {
-	rootCmd.Flags().SortFlags = false
-	rootCmd.Flags().IntVarP(&config.Connections, "connections", "c", 128, "最大并发连接数")
-	rootCmd.Flags().IntVarP(&config.Count, "requests", "n", 0, "请求总数（如果指定，则忽略 --duration 参数）")
-	rootCmd.Flags().IntVar(&config.Qps, "qps", 0, "固定基准测试的最大 QPS 值（如果指定，则忽略 -n|--requests 参数）")
-	rootCmd.Flags().DurationVarP(&config.Duration, "duration", "d", time.Second*10, "测试持续时间")
-	rootCmd.Flags().DurationVarP(&config.Timeout, "timeout", "t", time.Second*3, "请求超时时间")
-	rootCmd.Flags().StringVarP(&config.Method, "method", "X", "GET", "HTTP 请求方法")
-	rootCmd.Flags().StringSliceVarP(&config.Headers, "header", "H", nil, headersUsage)
-	rootCmd.Flags().StringVar(&config.Host, "host", "", "覆盖请求主机名")
-	rootCmd.Flags().BoolVarP(&config.DisableKeepAlives, "disableKeepAlives", "a", false, "禁用 HTTP keep-alive，如果为 true，将设置 Connection: close 头")
-	rootCmd.Flags().StringVarP(&config.Body, "body", "b", "", "HTTP 请求体字符串")
-	rootCmd.Flags().StringVarP(&config.File, "file", "f", "", "从文件路径读取 HTTP 请求体")
-	rootCmd.Flags().BoolVarP(&config.Stream, "stream", "s", false, "使用流式请求体以减少内存使用")
-	rootCmd.Flags().BoolVarP(&config.JSON, "json", "J", false, "发送 JSON 请求，自动设置 Content-Type 为 application/json")
-	rootCmd.Flags().BoolVarP(&config.Form, "form", "F", false, "发送表单请求，自动设置 Content-Type 为 application/x-www-form-urlencoded")
-	rootCmd.Flags().BoolVarP(&config.Insecure, "insecure", "k", false, "控制客户端是否验证服务器的证书链和主机名")
-	rootCmd.Flags().StringVar(&config.Cert, "cert", "", "客户端 TLS 证书路径")
-	rootCmd.Flags().StringVar(&config.Key, "key", "", "客户端 TLS 证书私钥路径")
-	rootCmd.Flags().StringVar(&config.HttpProxy, "httpProxy", "", "HTTP 代理地址")
-	rootCmd.Flags().StringVar(&config.SocksProxy, "socksProxy", "", "SOCKS 代理地址")
-	rootCmd.Flags().BoolVarP(&config.Pipeline, "pipeline", "p", false, "使用 fasthttp 管道客户端")
-	rootCmd.Flags().BoolVar(&config.Follow, "follow", false, "在调试模式下跟随 30x 重定向")
-	rootCmd.Flags().IntVar(&config.MaxRedirects, "maxRedirects", 0, "跟随 30x 重定向的最大次数，默认为 30（配合 --follow 使用）")
-	rootCmd.Flags().BoolVarP(&config.Debug, "debug", "D", false, "只发送一次请求并显示请求和响应详情")
+	rootCmd.PersistentFlags().SortFlags = false
+	rootCmd.PersistentFlags().IntVarP(&config.Connections, "connections", "c", 128, "最大并发连接数")
+	rootCmd.PersistentFlags().IntVarP(&config.Count, "requests", "n", 0, "请求总数（如果指定，则忽略 --duration 参数）")
+	rootCmd.PersistentFlags().IntVar(&config.Qps, "qps", 0, "固定基准测试的最大 QPS 值（如果指定，则忽略 -n|--requests 参数）")
+	rootCmd.PersistentFlags().IntVar(&config.Rate, "rate", 0, "开放模型下每秒发起的请求数，按固定到达速率发送，不受响应快慢和连接数影响（不能与 --qps 同时使用）")
+	rootCmd.PersistentFlags().IntVar(&config.MaxInFlight, "maxInFlight", 0, "开放模型下同时进行的请求上限，超出时丢弃并计数，默认为 1024（配合 --rate 使用）")
+	rootCmd.PersistentFlags().StringVar(&config.Stages, "stages", "", stagesUsage)
+	rootCmd.PersistentFlags().StringVar(&config.StageBy, "stageBy", "qps", "阶段目标值作用的对象，可选 qps|rate|connections（配合 --stages 使用）")
+	rootCmd.PersistentFlags().DurationVarP(&config.Duration, "duration", "d", time.Second*10, "测试持续时间")
+	rootCmd.PersistentFlags().DurationVarP(&config.Timeout, "timeout", "t", time.Second*3, "请求超时时间")
+	rootCmd.PersistentFlags().StringVarP(&config.Method, "method", "X", "GET", "HTTP 请求方法")
+	rootCmd.PersistentFlags().StringSliceVarP(&config.Headers, "header", "H", nil, headersUsage)
+	rootCmd.PersistentFlags().StringVar(&config.Host, "host", "", "覆盖请求主机名")
+	rootCmd.PersistentFlags().BoolVarP(&config.DisableKeepAlives, "disableKeepAlives", "a", false, "禁用 HTTP keep-alive，如果为 true，将设置 Connection: close 头")
+	rootCmd.PersistentFlags().StringVarP(&config.Body, "body", "b", "", "HTTP 请求体字符串")
+	rootCmd.PersistentFlags().StringVarP(&config.File, "file", "f", "", "从文件路径读取 HTTP 请求体")
+	rootCmd.PersistentFlags().StringVar(&config.Scenario, "scenario", "", "场景文件路径（YAML 或 JSON），按权重混合多个请求并按端点统计，指定时 URL 参数可省略")
+	rootCmd.PersistentFlags().StringVarP(&config.HttpFile, "httpFile", "r", "", "JetBrains / VS Code REST Client 格式的 .http 文件路径，指定时 URL 参数可省略")
+	rootCmd.PersistentFlags().StringSliceVar(&config.Names, "name", nil, "按名称选择 .http 文件中的请求，格式为 名称[:权重]，可重复使用，默认使用所有请求")
+	rootCmd.PersistentFlags().StringVar(&config.Raw, "raw", "", "原始 HTTP/1.1 请求文件路径，文件内容原样发送，未指定 URL 时目标地址取自 Host 头")
+	rootCmd.PersistentFlags().StringVar(&config.Data, "data", "", "数据文件路径（CSV 或 JSONL），每次请求取出一行，模板中以 {{data.列名}} 引用")
+	rootCmd.PersistentFlags().StringVar(&config.DataMode, "dataMode", "circular", "数据文件的读取方式：circular、sequential（读完后结束压测）或 random")
+	rootCmd.PersistentFlags().BoolVarP(&config.Stream, "stream", "s", false, "使用流式请求体以减少内存使用")
+	rootCmd.PersistentFlags().BoolVarP(&config.JSON, "json", "J", false, "发送 JSON 请求，自动设置 Content-Type 为 application/json")
+	rootCmd.PersistentFlags().BoolVarP(&config.Form, "form", "F", false, "发送表单请求，自动设置 Content-Type 为 application/x-www-form-urlencoded，包含 k=@file 文件字段时为 multipart/form-data")
+	rootCmd.PersistentFlags().StringVar(&config.CompressBody, "compress-body", "", "压缩请求体并设置 Content-Encoding，可选 gzip|br|zstd，请求体只在启动时压缩一次")
+	rootCmd.PersistentFlags().StringVar(&config.AcceptEncoding, "accept-encoding", "", "设置 Accept-Encoding 请求压缩的响应，并统计响应体解压前后的字节数，例如 gzip,br,zstd。每个响应在记录延迟后解压一次以计算字节数，会占用压测端的 CPU")
+	rootCmd.PersistentFlags().BoolVarP(&config.Insecure, "insecure", "k", false, "控制客户端是否验证服务器的证书链和主机名")
+	rootCmd.PersistentFlags().StringVar(&config.Cert, "cert", "", "客户端 TLS 证书路径")
+	rootCmd.PersistentFlags().StringVar(&config.Key, "key", "", "客户端 TLS 证书私钥路径")
+	rootCmd.PersistentFlags().StringVar(&config.HttpProxy, "httpProxy", "", "HTTP 代理地址")
+	rootCmd.PersistentFlags().StringVar(&config.SocksProxy, "socksProxy", "", "SOCKS 代理地址")
+	rootCmd.PersistentFlags().StringVar(&config.UnixSocket, "unix-socket", "", "通过 Unix 域套接字连接，例如 /run/app.sock，Url 中的主机只用于 Host 头，也可以使用 http+unix://%2Frun%2Fapp.sock/path 形式的 Url")
+	rootCmd.PersistentFlags().BoolVarP(&config.Pipeline, "pipeline", "p", false, "使用 fasthttp 管道客户端")
+	rootCmd.PersistentFlags().BoolVar(&config.HTTP2, "http2", false, "使用 HTTP/2 发送请求，https 通过 ALPN 协商 h2，http 使用 h2c（prior knowledge）")
+	rootCmd.PersistentFlags().BoolVar(&config.HTTP3, "http3", false, "通过 QUIC 使用 HTTP/3 发送请求，仅支持 https")
+	rootCmd.PersistentFlags().IntVar(&config.StreamsPerConn, "streams-per-conn", 1, "HTTP/2 与 HTTP/3 每个连接上的并发流数，连接数为并发数除以该值")
+	rootCmd.PersistentFlags().BoolVar(&config.Follow, "follow", false, "在调试模式下跟随 30x 重定向")
+	rootCmd.PersistentFlags().IntVar(&config.MaxRedirects, "maxRedirects", 0, "跟随 30x 重定向的最大次数，默认为 30（配合 --follow 使用）")
+	rootCmd.PersistentFlags().BoolVarP(&config.Debug, "debug", "D", false, "只发送一次请求并显示请求和响应详情")
+	rootCmd.PersistentFlags().StringVarP(&config.Output, "output", "o", "", "结果输出格式，可选 tui|json，json 模式不启动交互界面")
+	rootCmd.PersistentFlags().StringVar(&config.OutputFile, "outputFile", "", "JSON 结果写入的文件路径，默认输出到标准输出")
+	rootCmd.PersistentFlags().StringArrayVar(&config.Asserts, "assert", nil, assertUsage)
+	rootCmd.PersistentFlags().BoolVar(&config.NoTUI, "no-tui", false, "不启动交互界面，进度输出到标准错误，结果以纯文本输出到标准输出（非终端环境下自动启用）")
 }
 
 var rootCmd = &cobra.Command{
@@ -56,27 +88,73 @@ var rootCmd = &cobra.Command{
 }
 
 func rootArgs(_ *cobra.Command, args []string) error {
-	if len(args) == 0 {
+	if len(args) == 0 && config.Scenario == "" && config.HttpFile == "" && config.Raw == "" {
 		return errors.New("缺少 URL 参数")
 	}
 	return nil
 }
 
+// setUrlArgs 从命令行参数中获取 URL 与额外参数，使用场景文件或 .http 文件时 URL 可省略
+func setUrlArgs(args []string) {
+	if len(args) > 0 {
+		config.Url = args[0]
+		config.Args = args[1:]
+	}
+}
+
 func rootRun(cmd *cobra.Command, args []string) {
-	config.Url = args[0]
-	config.Args = args[1:]
+	setUrlArgs(args)
+	runBenchmark(cmd)
+}
+
+// runBenchmark 按 config 执行压测，失败时设置进程退出码
+func runBenchmark(cmd *cobra.Command) {
+	if !isTerminal() {
+		config.NoTUI = true
+	}
 	if err := pkg.New(config).Run(); err != nil {
 		cmd.PrintErrln(err)
+		exitCode = errorExitCode(err)
 	}
 }
 
+// errorExitCode 断言失败时返回 2，其他错误返回 1
+func errorExitCode(err error) int {
+	var ae *pkg.AssertionError
+	if errors.As(err, &ae) {
+		return exitAssertionError
+	}
+	return exitFailure
+}
+
+// isTerminal 判断标准输入和标准输出是否均为终端
+func isTerminal() bool {
+	return isTTY(os.Stdin) && isTTY(os.Stdout)
+}
+
+func isTTY(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
 const (
-	usage   = `httpgo [url|:port|/path] [k:v|k:=v ...]`
+	usage   = `httpgo [url|:port|/path] [k==v|Header:v|k=v|k:=v|k=@file|k:=@file ...]`
 	example = `	httpgo https://www.google.com -c1 -n5   =>   httpgo -X GET https://www.google.com -c1 -n5
 	httpgo :3000 -c1 -n5                    =>   httpgo -X GET http://localhost:3000 -c1 -n5
 	httpgo /foo -c1 -n5                     =>   httpgo -X GET http://localhost/foo -c1 -n5
-	httpgo :3000 -c1 -n5 foo:=bar           =>   httpgo -X GET http://localhost:3000 -c1 -n5 -H "Content-Type: application/json" -b='{"foo":"bar"}'
-	httpgo :3000 -c1 -n5 foo=bar            =>   httpgo -X POST http://localhost:3000 -c1 -n5 -H "Content-Type: application/x-www-form-urlencoded" -b="foo=bar"`
+	httpgo :3000 -c1 -n5 q==go X-Id:1       =>   httpgo -X GET "http://localhost:3000?q=go" -c1 -n5 -H "X-Id: 1"
+	httpgo :3000 -c1 -n5 foo=bar n:=1       =>   httpgo -X GET http://localhost:3000 -c1 -n5 -H "Content-Type: application/json" -b='{"foo":"bar","n":1}'
+	httpgo :3000 -c1 -n5 user[tags][]=a     =>   httpgo -X GET http://localhost:3000 -c1 -n5 -H "Content-Type: application/json" -b='{"user":{"tags":["a"]}}'
+	httpgo :3000 -c1 -n5 -F foo=bar         =>   httpgo -X POST http://localhost:3000 -c1 -n5 -H "Content-Type: application/x-www-form-urlencoded" -b="foo=bar"
+	httpgo :3000 -c1 -n5 -F f=@a.png        =>   httpgo -X POST http://localhost:3000 -c1 -n5 -H "Content-Type: multipart/form-data; boundary=..." --stream`
+	assertUsage = `压测结束后校验的断言，可重复使用，任一失败时进程以退出码 2 结束
+支持的指标: avg|stdev|min|max|p50|p99|p99.9 等延迟（默认单位 ms），rps|requests|errors|dropped，
+error_rate|1xx_ratio ~ 5xx_ratio|ok_ratio（支持百分号，ok_ratio 仅用于 grpc 模式），运算符: < <= > >= == !=
+示例:
+	--assert "p99<200ms" --assert "error_rate<0.1%" --assert "rps>5000" --assert "2xx_ratio>=99.9%"`
+	stagesUsage = `分阶段的负载曲线，格式为 "时长:目标值"，以逗号分隔，指定时忽略 --duration
+目标值在阶段内从上一阶段的目标值线性变化，首个阶段从 0 开始
+示例:
+	--stages 30s:100,2m:1000,30s:0`
 	headersUsage = `格式为 "K: V" 的 HTTP 请求头，可重复使用
 示例:
 	-H "k1: v1" -H k2:v2
@@ -2,14 +2,37 @@ package main
 
 import (
     "bytes"
+    "errors"
+    "os"
     "testing"
 
+    "github.com/itnxs/httpgo/internal/pkg"
+    "github.com/spf13/cobra"
     "github.com/stretchr/testify/assert"
 )
 
 func Test_RootArgs(t *testing.T) {
     assert.NotNil(t, rootArgs(rootCmd, nil))
     assert.Nil(t, rootArgs(rootCmd, []string{"url"}))
+
+    config.Scenario = "scenario.yaml"
+    defer func() { config.Scenario = "" }()
+    assert.Nil(t, rootArgs(rootCmd, nil))
+
+    config.Scenario, config.HttpFile = "", "api.http"
+    defer func() { config.HttpFile = "" }()
+    assert.Nil(t, rootArgs(rootCmd, nil))
+}
+
+func Test_setUrlArgs(t *testing.T) {
+    defer func() { config.Url, config.Args = "", nil }()
+
+    setUrlArgs(nil)
+    assert.Equal(t, "", config.Url)
+
+    setUrlArgs([]string{"url", "a=1"})
+    assert.Equal(t, "url", config.Url)
+    assert.Equal(t, []string{"a=1"}, config.Args)
 }
 
 func Test_RootRun(t *testing.T) {
@@ -19,4 +42,113 @@ func Test_RootRun(t *testing.T) {
 
     rootRun(rootCmd, []string{"ftp://url"})
     assert.Equal(t, "unsupported protocol \"ftp\". http and https are supported\n", buf.String())
+    assert.Equal(t, exitFailure, exitCode)
+}
+
+// Test_subcommandRun 检查子命令写入的配置，以及压测失败时的错误输出与退出码，
+// 每个用例从标志的默认值开始，结束后恢复配置与搜索配置
+func Test_subcommandRun(t *testing.T) {
+    saved, savedSearch := config, searchConfig
+    defer func() { config, searchConfig = saved, savedSearch }()
+
+    cases := []struct {
+        name  string
+        cmd   *cobra.Command
+        run   func(*cobra.Command, []string)
+        args  []string
+        setup func()
+        check func(t *testing.T)
+        want  string
+    }{
+        {
+            name:  "har",
+            cmd:   harCmd,
+            run:   harRun,
+            args:  []string{"not-exist.har"},
+            check: func(t *testing.T) { assert.Equal(t, "not-exist.har", config.Har) },
+            want:  "not-exist.har",
+        },
+        {
+            name:  "ws",
+            cmd:   wsCmd,
+            run:   wsRun,
+            args:  []string{"ws://127.0.0.1:1"},
+            setup: func() { config.Debug = true },
+            check: func(t *testing.T) {
+                assert.True(t, config.WebSocket)
+                assert.Equal(t, "ws://127.0.0.1:1", config.Url)
+            },
+            want: "--debug",
+        },
+        {
+            name:  "sse",
+            cmd:   sseCmd,
+            run:   sseRun,
+            args:  []string{"http://127.0.0.1:1/events"},
+            setup: func() { config.Debug = true },
+            check: func(t *testing.T) {
+                assert.True(t, config.SSE)
+                assert.Equal(t, "http://127.0.0.1:1/events", config.Url)
+            },
+            want: "--debug",
+        },
+        {
+            name:  "grpc",
+            cmd:   grpcCmd,
+            run:   grpcRun,
+            args:  []string{"127.0.0.1:1", "bench.Echo/Unary"},
+            setup: func() { config.HTTP2 = true },
+            check: func(t *testing.T) {
+                assert.True(t, config.GRPC)
+                assert.Equal(t, "127.0.0.1:1", config.Url)
+                assert.Equal(t, "bench.Echo/Unary", config.GrpcMethod)
+            },
+            want: "--http2",
+        },
+        {
+            name:  "search",
+            cmd:   searchCmd,
+            run:   searchRun,
+            args:  []string{"url"},
+            setup: func() { searchConfig.Min = 0 },
+            check: func(t *testing.T) { assert.Equal(t, "url", config.Url) },
+            want:  "search range must satisfy 0 < min < max",
+        },
+    }
+
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            config, searchConfig = saved, savedSearch
+            if c.setup != nil {
+                c.setup()
+            }
+
+            var buf bytes.Buffer
+            c.cmd.SetOut(&buf)
+            c.cmd.SetErr(&buf)
+
+            exitCode = 0
+            c.run(c.cmd, c.args)
+            c.check(t)
+            assert.Contains(t, buf.String(), c.want)
+            assert.Equal(t, exitFailure, exitCode)
+        })
+    }
+}
+
+func Test_errorExitCode(t *testing.T) {
+    assert.Equal(t, exitFailure, errorExitCode(errors.New("error")))
+    assert.Equal(t, exitAssertionError, errorExitCode(&pkg.AssertionError{Failed: []string{"p99<1ms"}}))
+}
+
+func Test_isTTY(t *testing.T) {
+    r, w, err := os.Pipe()
+    assert.Nil(t, err)
+    defer func() {
+        _ = r.Close()
+        _ = w.Close()
+    }()
+
+    assert.False(t, isTTY(r))
+    assert.False(t, isTTY(w))
 }
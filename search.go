@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/itnxs/httpgo/internal/pkg"
+	"github.com/spf13/cobra"
+)
+
+var searchConfig pkg.SearchConfig
+
+func init() {
+	searchCmd.Flags().SortFlags = false
+	searchCmd.Flags().StringVar(&searchConfig.By, "by", "qps", "搜索的对象，可选 qps|rate|connections")
+	searchCmd.Flags().IntVar(&searchConfig.Min, "min", 10, "搜索区间的下限")
+	searchCmd.Flags().IntVar(&searchConfig.Max, "max", 10000, "搜索区间的上限")
+	searchCmd.Flags().IntVar(&searchConfig.Precision, "precision", 0, "搜索精度，区间宽度不大于该值时结束搜索，默认为区间宽度的 1/50")
+	searchCmd.Flags().DurationVar(&searchConfig.P99, "p99", 0, "每轮允许的 p99 延迟上限，qps 与 rate 模式下使用修正后的延迟")
+	searchCmd.Flags().StringVar(&searchConfig.ErrorRate, "errorRate", "1%", "每轮允许的错误率上限，例如 1% 或 0.01")
+	rootCmd.AddCommand(searchCmd)
+}
+
+var searchCmd = &cobra.Command{
+	Use:     "search [url|:port|/path] [k:v|k:=v ...]",
+	Example: searchExample,
+	Short:   "多轮短时压测，二分搜索满足 p99 与错误率要求的最大速率",
+	Long: `多轮短时压测，二分搜索满足 p99 与错误率要求的最大速率。
+每轮压测的持续时间由 --duration 指定，其余参数与根命令一致，结束后输出每一轮的结果与拐点。`,
+	Args: rootArgs,
+	Run:  searchRun,
+}
+
+func searchRun(cmd *cobra.Command, args []string) {
+	setUrlArgs(args)
+	if err := pkg.NewSearch(config, searchConfig).Run(); err != nil {
+		cmd.PrintErrln(err)
+		exitCode = exitFailure
+	}
+}
+
+const searchExample = `	httpgo search :3000 --p99 200ms --errorRate 0.1% --min 100 --max 20000 -d 10s
+	httpgo search :3000 --by connections --min 1 --max 512 --p99 50ms`
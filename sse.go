@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(sseCmd)
+}
+
+var sseCmd = &cobra.Command{
+	Use:     "sse <url>",
+	Example: sseExample,
+	Short:   "压测 Server-Sent Events 服务，统计首个事件的耗时、事件间隔与每个连接每秒的事件数",
+	Long: `以 --connections 个连接压测 SSE 服务，每个连接保持一个 text/event-stream 响应，
+逐行解析事件，流结束后重新发送请求。每个事件计为一次请求，第一个事件的延迟为 TTFE，
+之后为与上一个事件的间隔；--requests 表示收到的事件总数，--timeout 为两次读取之间的最长等待时间，
+压测结束时没有新事件的流最多等待 --timeout 后关闭。`,
+	Args: cobra.ExactArgs(1),
+	Run:  sseRun,
+}
+
+func sseRun(cmd *cobra.Command, args []string) {
+	config.SSE = true
+	config.Url = args[0]
+	runBenchmark(cmd)
+}
+
+const sseExample = `	httpgo sse http://localhost:8080/events -c 100 -d 30s
+	httpgo sse https://example.com/stream -H "Authorization: Bearer token" -n 10000`
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	wsCmd.Flags().SortFlags = false
+	wsCmd.Flags().StringVar(&config.WsMessage, "message", "", "发送的消息内容，为空时使用 --body，均为空时发送 ping")
+	wsCmd.Flags().Float64Var(&config.WsRate, "msg-rate", 1, "每个连接每秒发送的消息数，为 0 时收到回复后立即发送下一条")
+	rootCmd.AddCommand(wsCmd)
+}
+
+var wsCmd = &cobra.Command{
+	Use:     "ws <url>",
+	Example: wsExample,
+	Short:   "压测 WebSocket 服务，统计建立连接的耗时、消息往返时间与关闭状态码",
+	Long: `以 --connections 个 WebSocket 连接压测服务，每个连接按 --msg-rate 发送消息，
+回复按发送顺序与消息匹配，往返时间计入延迟统计，每秒完成的往返次数即消息速率。
+连接使用与 HTTP 相同的代理、TLS 配置与吞吐量统计，断开后自动重新建立；
+--requests 表示完成往返的消息总数，--timeout 同时作为握手与等待回复的超时时间。`,
+	Args: cobra.ExactArgs(1),
+	Run:  wsRun,
+}
+
+func wsRun(cmd *cobra.Command, args []string) {
+	config.WebSocket = true
+	config.Url = args[0]
+	runBenchmark(cmd)
+}
+
+const wsExample = `	httpgo ws ws://localhost:8080/echo -c 100 -d 30s
+	httpgo ws wss://example.com/socket --message '{"op":"ping"}' --msg-rate 10 -H "Authorization: Bearer token"
+	httpgo ws ws://localhost:8080/echo -c 10 -n 10000 --msg-rate 0`